@@ -0,0 +1,222 @@
+package ackqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrClosed — ошибка работы с закрытой очередью
+var ErrClosed = errors.New("очередь закрыта")
+
+// ErrStaleMessage — ошибка подтверждения сообщения, которое уже было
+// подтверждено, отклонено или повторно доставлено другому получателю
+var ErrStaleMessage = errors.New("сообщение уже не принадлежит получателю")
+
+// Message — доставленное получателю сообщение
+// Получатель обязан вызвать Ack после успешной обработки или Nack при ошибке.
+// Если он не сделает ни того, ни другого в течение таймаута видимости,
+// сообщение будет доставлено повторно
+type Message[T any] struct {
+	// Идентификатор сообщения (не меняется при повторных доставках)
+	ID uint64
+	// Содержимое сообщения
+	Body T
+	// Номер доставки, начиная с 1
+	Attempt int
+
+	queue *Queue[T]
+}
+
+// Ack — метод подтверждения успешной обработки сообщения
+func (m *Message[T]) Ack() error {
+	return m.queue.settle(m, false)
+}
+
+// Nack — метод отказа от обработки сообщения
+// Сообщение сразу возвращается в очередь для повторной доставки
+func (m *Message[T]) Nack() error {
+	return m.queue.settle(m, true)
+}
+
+// entry — внутреннее представление сообщения
+type entry[T any] struct {
+	id      uint64
+	body    T
+	attempt int
+	// Момент, после которого неподтвержденное сообщение доставляется повторно
+	deadline time.Time
+}
+
+// Queue — очередь работ с семантикой доставки «хотя бы один раз»
+type Queue[T any] struct {
+	// Время, в течение которого доставленное сообщение невидимо для других получателей
+	visibility time.Duration
+
+	// Защита состояния очереди
+	mutex sync.Mutex
+	// Сообщения, готовые к доставке
+	ready []*entry[T]
+	// Доставленные, но еще не подтвержденные сообщения
+	inflight map[uint64]*entry[T]
+	// Закрывается и заменяется новым при появлении готовых сообщений,
+	// чтобы разбудить ожидающих получателей
+	available chan struct{}
+	// Идентификатор следующего сообщения
+	nextID uint64
+	// Закрыта ли очередь
+	closed bool
+
+	// Остановка фоновой горутины повторной доставки
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewQueue — функция создания очереди
+// visibility — таймаут видимости: через это время неподтвержденное
+// сообщение снова становится доступным для получения
+func NewQueue[T any](visibility time.Duration) (*Queue[T], error) {
+	if visibility <= 0 {
+		return nil, fmt.Errorf("таймаут видимости должен быть положительным: %v", visibility)
+	}
+	q := &Queue[T]{
+		visibility: visibility,
+		inflight:   make(map[uint64]*entry[T]),
+		available:  make(chan struct{}),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go q.redeliverLoop()
+	return q, nil
+}
+
+// Publish — метод добавления сообщения в очередь
+func (q *Queue[T]) Publish(body T) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed {
+		return ErrClosed
+	}
+	q.nextID++
+	q.ready = append(q.ready, &entry[T]{id: q.nextID, body: body})
+	q.notifyLocked()
+	return nil
+}
+
+// Receive — метод получения очередного сообщения
+// Блокируется, пока в очереди нет готовых сообщений или пока не отменен ctx
+func (q *Queue[T]) Receive(ctx context.Context) (*Message[T], error) {
+	for {
+		q.mutex.Lock()
+		if q.closed {
+			q.mutex.Unlock()
+			return nil, ErrClosed
+		}
+		if len(q.ready) > 0 {
+			e := q.ready[0]
+			q.ready[0] = nil
+			q.ready = q.ready[1:]
+
+			e.attempt++
+			e.deadline = time.Now().Add(q.visibility)
+			q.inflight[e.id] = e
+			q.mutex.Unlock()
+
+			return &Message[T]{ID: e.id, Body: e.body, Attempt: e.attempt, queue: q}, nil
+		}
+		available := q.available
+		q.mutex.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-available:
+		}
+	}
+}
+
+// settle — метод завершения обработки сообщения (Ack или Nack)
+func (q *Queue[T]) settle(m *Message[T], requeue bool) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	e, ok := q.inflight[m.ID]
+	// Если номер доставки не совпадает, сообщение уже было доставлено
+	// повторно и принадлежит другому получателю
+	if !ok || e.attempt != m.Attempt {
+		return ErrStaleMessage
+	}
+	delete(q.inflight, m.ID)
+
+	if requeue && !q.closed {
+		q.ready = append(q.ready, e)
+		q.notifyLocked()
+	}
+	return nil
+}
+
+// redeliverLoop — фоновый цикл возврата просроченных сообщений в очередь
+func (q *Queue[T]) redeliverLoop() {
+	defer close(q.done)
+
+	// Проверяем просроченные сообщения несколько раз за таймаут видимости
+	// (при очень малом таймауте интервал не может опуститься до нуля)
+	interval := q.visibility / 4
+	if interval <= 0 {
+		interval = 1
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		case now := <-ticker.C:
+			q.mutex.Lock()
+			for id, e := range q.inflight {
+				if now.After(e.deadline) {
+					delete(q.inflight, id)
+					q.ready = append(q.ready, e)
+				}
+			}
+			if len(q.ready) > 0 {
+				q.notifyLocked()
+			}
+			q.mutex.Unlock()
+		}
+	}
+}
+
+// notifyLocked — метод пробуждения ожидающих получателей
+// Вызывается при захваченном мьютексе
+func (q *Queue[T]) notifyLocked() {
+	close(q.available)
+	q.available = make(chan struct{})
+}
+
+// Len — метод получения количества готовых и неподтвержденных сообщений
+func (q *Queue[T]) Len() (ready, inflight int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.ready), len(q.inflight)
+}
+
+// Close — метод закрытия очереди
+// Ожидающие получатели получают ErrClosed, оставшиеся сообщения отбрасываются
+func (q *Queue[T]) Close() {
+	q.mutex.Lock()
+	if q.closed {
+		q.mutex.Unlock()
+		return
+	}
+	q.closed = true
+	q.notifyLocked()
+	q.mutex.Unlock()
+
+	close(q.stop)
+	<-q.done
+}
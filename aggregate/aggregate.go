@@ -0,0 +1,161 @@
+package aggregate
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// Item — значение с оценкой
+type Item[T any] struct {
+	Value T
+	// Оценка, по которой выбираются лучшие значения и считаются квантили
+	Score float64
+}
+
+// minHeap — куча с наименьшей оценкой в корне для отбора K лучших
+type minHeap[T any] []Item[T]
+
+func (h minHeap[T]) Len() int            { return len(h) }
+func (h minHeap[T]) Less(i, j int) bool  { return h[i].Score < h[j].Score }
+func (h minHeap[T]) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *minHeap[T]) Push(x interface{}) { *h = append(*h, x.(Item[T])) }
+func (h *minHeap[T]) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// Aggregator — агрегатор результатов конкурентных производителей
+// Множество горутин добавляют значения через Add, а читатель в любой момент
+// получает K лучших по оценке, равномерную выборку фиксированного размера
+// (reservoir sampling) и оценки квантилей по этой выборке. Память
+// ограничена k + sampleSize элементами независимо от количества значений —
+// для сводок по результатам параллельного сканирования с большой
+// кардинальностью
+type Aggregator[T any] struct {
+	k          int
+	sampleSize int
+
+	// Защита полей ниже
+	mutex  sync.Mutex
+	top    minHeap[T]
+	sample []Item[T]
+	count  uint64
+	rand   *rand.Rand
+}
+
+// New — функция создания агрегатора
+// k — количество хранимых лучших значений, sampleSize — размер выборки
+func New[T any](k, sampleSize int) (*Aggregator[T], error) {
+	if k < 0 || sampleSize < 0 {
+		return nil, fmt.Errorf("размеры не могут быть отрицательными: k=%d, sampleSize=%d", k, sampleSize)
+	}
+	if k == 0 && sampleSize == 0 {
+		return nil, fmt.Errorf("нужно хранить хотя бы лучшие значения или выборку")
+	}
+	return &Aggregator[T]{
+		k:          k,
+		sampleSize: sampleSize,
+		top:        make(minHeap[T], 0, k),
+		sample:     make([]Item[T], 0, sampleSize),
+		rand:       rand.New(rand.NewSource(rand.Int63())),
+	}, nil
+}
+
+// Add — метод добавления значения с оценкой
+func (a *Aggregator[T]) Add(value T, score float64) {
+	item := Item[T]{Value: value, Score: score}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.count++
+
+	if a.k > 0 {
+		if len(a.top) < a.k {
+			heap.Push(&a.top, item)
+		} else if score > a.top[0].Score {
+			a.top[0] = item
+			heap.Fix(&a.top, 0)
+		}
+	}
+
+	if a.sampleSize > 0 {
+		// Алгоритм R: n-е значение попадает в выборку с вероятностью size/n
+		if len(a.sample) < a.sampleSize {
+			a.sample = append(a.sample, item)
+		} else if j := a.rand.Int63n(int64(a.count)); j < int64(a.sampleSize) {
+			a.sample[j] = item
+		}
+	}
+}
+
+// Count — метод получения количества добавленных значений
+func (a *Aggregator[T]) Count() uint64 {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.count
+}
+
+// TopK — метод получения лучших значений по убыванию оценки
+func (a *Aggregator[T]) TopK() []Item[T] {
+	a.mutex.Lock()
+	out := make([]Item[T], len(a.top))
+	copy(out, a.top)
+	a.mutex.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out
+}
+
+// Sample — метод получения копии равномерной выборки значений
+func (a *Aggregator[T]) Sample() []Item[T] {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	out := make([]Item[T], len(a.sample))
+	copy(out, a.sample)
+	return out
+}
+
+// Quantiles — метод оценки квантилей оценок по выборке
+// qs — квантили от 0 до 1. Пока значений не больше размера выборки,
+// результат точный. Для пустого агрегатора возвращаются NaN
+func (a *Aggregator[T]) Quantiles(qs ...float64) []float64 {
+	a.mutex.Lock()
+	scores := make([]float64, len(a.sample))
+	for i, item := range a.sample {
+		scores[i] = item.Score
+	}
+	a.mutex.Unlock()
+
+	sort.Float64s(scores)
+	out := make([]float64, len(qs))
+	for i, q := range qs {
+		if len(scores) == 0 {
+			out[i] = math.NaN()
+			continue
+		}
+		switch {
+		case q <= 0:
+			out[i] = scores[0]
+		case q >= 1:
+			out[i] = scores[len(scores)-1]
+		default:
+			out[i] = scores[int(math.Ceil(q*float64(len(scores))))-1]
+		}
+	}
+	return out
+}
+
+// Reset — метод сброса накопленных значений
+func (a *Aggregator[T]) Reset() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.top = a.top[:0]
+	a.sample = a.sample[:0]
+	a.count = 0
+}
@@ -0,0 +1,219 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"goroutines-example/clock"
+	"goroutines-example/combinator"
+	"goroutines-example/progress"
+	"goroutines-example/semaphore"
+)
+
+// Result — результат обработки одного файла
+type Result[R any] struct {
+	// Путь к файлу (как передан в ProcessFiles)
+	Path string
+	// Значение, возвращенное функцией обработки
+	Value R
+	// Ошибка последней попытки (nil при успехе)
+	Err error
+	// Количество выполненных попыток (0, если файл не обрабатывался)
+	Attempts int
+	// Время от первой попытки до завершения, включая паузы между попытками
+	Duration time.Duration
+}
+
+// Summary — итоги пакетной обработки
+type Summary struct {
+	Total     int
+	Succeeded int
+	Failed    int
+	// Количество повторных попыток по всем файлам
+	Retries  int
+	Duration time.Duration
+}
+
+// Option — функция настройки пакетной обработки
+type Option func(*config)
+
+type config struct {
+	// Количество одновременно обрабатываемых файлов
+	concurrency int
+	// Общий семафор, разрешение которого берется на каждую попытку
+	sem semaphore.Semaphore
+	// Количество повторных попыток после первой неудачной
+	retries int
+	// Пауза перед первой повторной попыткой (дальше удваивается)
+	backoff time.Duration
+	// Верхняя граница паузы между попытками
+	maxBackoff time.Duration
+	// Предикат повторяемости ошибки (nil — повторяются все ошибки)
+	retryable func(error) bool
+	// Функция чтения файла
+	read func(path string) ([]byte, error)
+	// Задание для учета прогресса (nil — без учета)
+	job *progress.Job
+	// Функция, вызываемая после каждого файла (последовательно)
+	onResult func(combinator.Progress)
+	clock    clock.Clock
+}
+
+// WithConcurrency — опция количества одновременно обрабатываемых файлов
+// По умолчанию — runtime.NumCPU()
+func WithConcurrency(n int) Option {
+	return func(c *config) { c.concurrency = n }
+}
+
+// WithSemaphore — опция общего семафора
+// Разрешение берется на каждую попытку, поэтому несколько пакетов (или
+// пакет и другие части сервиса) делят один бюджет конкурентности
+func WithSemaphore(sem semaphore.Semaphore) Option {
+	return func(c *config) { c.sem = sem }
+}
+
+// WithRetries — опция повторных попыток
+// n — количество повторов после первой неудачи, backoff — пауза перед
+// первым повтором, удваиваемая с каждым следующим до maxBackoff
+func WithRetries(n int, backoff, maxBackoff time.Duration) Option {
+	return func(c *config) {
+		c.retries = n
+		c.backoff = backoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// WithRetryIf — опция предиката повторяемости ошибок
+// Ошибки, для которых fn возвращает false, не повторяются
+func WithRetryIf(fn func(error) bool) Option {
+	return func(c *config) { c.retryable = fn }
+}
+
+// WithReader — опция функции чтения файла (по умолчанию os.ReadFile)
+// Позволяет читать из архива, объектного хранилища или потоково
+func WithReader(read func(path string) ([]byte, error)) Option {
+	return func(c *config) { c.read = read }
+}
+
+// WithProgress — опция учета прогресса в задании трекера
+// На каждый обработанный файл (успешно или нет) учитывается одна единица
+func WithProgress(job *progress.Job) Option {
+	return func(c *config) { c.job = job }
+}
+
+// WithOnResult — опция функции, вызываемой после каждого файла
+// Вызовы последовательны, поэтому fn не нуждается в синхронизации
+func WithOnResult(fn func(combinator.Progress)) Option {
+	return func(c *config) { c.onResult = fn }
+}
+
+// WithClock — опция источника времени для пауз между попытками
+func WithClock(c clock.Clock) Option {
+	return func(cfg *config) { cfg.clock = c }
+}
+
+// ProcessFiles — функция пакетной обработки файлов
+// Каждый файл читается и передается fn не более чем по concurrency
+// одновременно; неудачные попытки (чтения или fn) повторяются согласно
+// WithRetries. Неудача одного файла не прерывает остальные. Результаты
+// возвращаются в порядке paths вместе с итогами; если хотя бы один файл
+// не обработан, возвращается *combinator.FailuresError с индексами
+// неудачных файлов. При отмене ctx еще не начатые файлы получают ошибку
+// ctx, и она же возвращается после завершения начатых
+func ProcessFiles[R any](ctx context.Context, paths []string, fn func(ctx context.Context, path string, data []byte) (R, error), opts ...Option) ([]Result[R], Summary, error) {
+	cfg := config{
+		concurrency: runtime.NumCPU(),
+		read:        os.ReadFile,
+		clock:       clock.Real(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.retries < 0 {
+		return nil, Summary{}, fmt.Errorf("количество повторов не может быть отрицательным: %d", cfg.retries)
+	}
+
+	results := make([]Result[R], len(paths))
+	tasks := make([]func(ctx context.Context) error, len(paths))
+	for i, path := range paths {
+		i, path := i, path
+		tasks[i] = func(ctx context.Context) error {
+			r := process(ctx, &cfg, path, fn)
+			results[i] = r
+			if cfg.job != nil {
+				cfg.job.Add(1)
+			}
+			return r.Err
+		}
+	}
+
+	start := cfg.clock.Now()
+	taskResults, err := combinator.RunAll(ctx, tasks, cfg.concurrency, cfg.onResult)
+	summary := Summary{Total: len(paths), Duration: cfg.clock.Since(start)}
+	for i, tr := range taskResults {
+		// Незапущенные из-за отмены файлы получают ошибку только в RunAll
+		results[i].Path = paths[i]
+		if results[i].Err == nil {
+			results[i].Err = tr.Err
+		}
+		if results[i].Attempts > 1 {
+			summary.Retries += results[i].Attempts - 1
+		}
+		if results[i].Err != nil {
+			summary.Failed++
+		} else {
+			summary.Succeeded++
+		}
+	}
+	return results, summary, err
+}
+
+// process — функция обработки одного файла с повторами
+// Результат именованный, чтобы отложенная запись Duration попала в него
+func process[R any](ctx context.Context, cfg *config, path string, fn func(ctx context.Context, path string, data []byte) (R, error)) (r Result[R]) {
+	r = Result[R]{Path: path}
+	start := cfg.clock.Now()
+	defer func() { r.Duration = cfg.clock.Since(start) }()
+
+	backoff := cfg.backoff
+	for {
+		r.Attempts++
+		r.Value, r.Err = attempt(ctx, cfg, path, fn)
+		if r.Err == nil || r.Attempts > cfg.retries || ctx.Err() != nil {
+			return r
+		}
+		if cfg.retryable != nil && !cfg.retryable(r.Err) {
+			return r
+		}
+		if err := clock.Sleep(ctx, cfg.clock, backoff); err != nil {
+			return r
+		}
+		backoff *= 2
+		if cfg.maxBackoff > 0 && backoff > cfg.maxBackoff {
+			backoff = cfg.maxBackoff
+		}
+	}
+}
+
+// attempt — функция одной попытки: разрешение семафора, чтение и обработка
+func attempt[R any](ctx context.Context, cfg *config, path string, fn func(ctx context.Context, path string, data []byte) (R, error)) (R, error) {
+	var zero R
+	if cfg.sem != nil {
+		if err := cfg.sem.AcquireContext(ctx); err != nil {
+			return zero, err
+		}
+		defer cfg.sem.Release()
+	}
+	data, err := cfg.read(path)
+	if err != nil {
+		return zero, fmt.Errorf("чтение %s: %w", path, err)
+	}
+	v, err := fn(ctx, path, data)
+	if err != nil {
+		return zero, fmt.Errorf("обработка %s: %w", path, err)
+	}
+	return v, nil
+}
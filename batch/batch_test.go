@@ -0,0 +1,34 @@
+package batch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"goroutines-example/clock"
+)
+
+// TestProcessFilesDuration — время обработки файла попадает в результат
+func TestProcessFilesDuration(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	read := func(path string) ([]byte, error) { return []byte(path), nil }
+	fn := func(ctx context.Context, path string, data []byte) (int, error) {
+		fc.Advance(20 * time.Millisecond)
+		return len(data), nil
+	}
+
+	results, summary, err := ProcessFiles(context.Background(), []string{"a.txt"}, fn,
+		WithReader(read), WithClock(fc), WithConcurrency(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Succeeded != 1 {
+		t.Fatalf("успешно обработано %d файлов, ожидался 1", summary.Succeeded)
+	}
+	if got := results[0].Duration; got != 20*time.Millisecond {
+		t.Fatalf("длительность обработки %v, ожидалось 20ms", got)
+	}
+	if results[0].Value != 5 || results[0].Attempts != 1 {
+		t.Fatalf("неожиданный результат: %+v", results[0])
+	}
+}
@@ -0,0 +1,197 @@
+package bulkhead
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"goroutines-example/semaphore"
+)
+
+// ErrBulkheadFull — ошибка переполнения очереди ожидания отсека
+var ErrBulkheadFull = errors.New("отсек переполнен: очередь ожидания заполнена")
+
+// Bulkhead — изолированный отсек (паттерн Bulkhead)
+// Ограничивает количество одновременных вызовов зависимости и длину очереди
+// ожидающих вызовов, чтобы проблемы одной зависимости не исчерпали
+// ресурсы всего сервиса
+type Bulkhead struct {
+	// Имя отсека (обычно имя защищаемой зависимости)
+	name string
+	// Семафор, ограничивающий количество одновременных вызовов
+	sem *semaphore.CountingSemaphore
+	// Максимальное количество одновременных вызовов
+	maxConcurrent int
+	// Максимальное количество вызовов, ожидающих разрешения
+	maxWaiting int64
+
+	// Текущее количество ожидающих вызовов
+	waiting atomic.Int64
+	// Текущее количество выполняющихся вызовов
+	active atomic.Int64
+	// Счетчики для метрик
+	accepted atomic.Uint64
+	rejected atomic.Uint64
+	timeouts atomic.Uint64
+}
+
+// Metrics — снимок метрик отсека
+type Metrics struct {
+	Name          string
+	MaxConcurrent int
+	MaxWaiting    int
+	Active        int64
+	Waiting       int64
+	Accepted      uint64
+	Rejected      uint64
+	Timeouts      uint64
+}
+
+// NewBulkhead — функция создания отсека
+// maxConcurrent — максимальное количество одновременных вызовов
+// maxWaiting — максимальная длина очереди ожидания (0 — без ожидания)
+// timeout — максимальное время ожидания разрешения
+func NewBulkhead(name string, maxConcurrent, maxWaiting int, timeout time.Duration) (*Bulkhead, error) {
+	if maxConcurrent <= 0 {
+		return nil, fmt.Errorf("отсек %q: количество одновременных вызовов должно быть положительным: %d", name, maxConcurrent)
+	}
+	if maxWaiting < 0 {
+		return nil, fmt.Errorf("отсек %q: длина очереди ожидания не может быть отрицательной: %d", name, maxWaiting)
+	}
+	return &Bulkhead{
+		name:          name,
+		sem:           semaphore.NewCountingSemaphore(maxConcurrent, timeout),
+		maxConcurrent: maxConcurrent,
+		maxWaiting:    int64(maxWaiting),
+	}, nil
+}
+
+// Name — метод получения имени отсека
+func (b *Bulkhead) Name() string {
+	return b.name
+}
+
+// Execute — метод выполнения fn внутри отсека
+// Возвращает ErrBulkheadFull, если нет свободных разрешений и очередь
+// ожидания заполнена, или ошибку семафора при истечении таймаута ожидания
+func (b *Bulkhead) Execute(fn func() error) error {
+	release, err := b.Acquire(context.Background())
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn()
+}
+
+// Acquire — метод входа в отсек с отменой через ctx (реализует limiter.Limiter)
+// Ошибки те же, что у Execute; ожидание дополнительно прерывается отменой ctx.
+// Возвращенную функцию нужно вызвать ровно один раз при выходе из отсека
+func (b *Bulkhead) Acquire(ctx context.Context) (release func(), err error) {
+	if !b.sem.TryAcquire() {
+		if b.waiting.Add(1) > b.maxWaiting {
+			b.waiting.Add(-1)
+			b.rejected.Add(1)
+			return nil, ErrBulkheadFull
+		}
+		err := b.sem.AcquireContext(ctx)
+		b.waiting.Add(-1)
+		if err != nil {
+			if ctx.Err() == nil {
+				b.timeouts.Add(1)
+			}
+			return nil, fmt.Errorf("отсек %q: %w", b.name, err)
+		}
+	}
+
+	b.accepted.Add(1)
+	b.active.Add(1)
+	return func() {
+		b.active.Add(-1)
+		b.sem.Release()
+	}, nil
+}
+
+// Metrics — метод получения снимка метрик отсека
+func (b *Bulkhead) Metrics() Metrics {
+	return Metrics{
+		Name:          b.name,
+		MaxConcurrent: b.maxConcurrent,
+		MaxWaiting:    int(b.maxWaiting),
+		Active:        b.active.Load(),
+		Waiting:       b.waiting.Load(),
+		Accepted:      b.accepted.Load(),
+		Rejected:      b.rejected.Load(),
+		Timeouts:      b.timeouts.Load(),
+	}
+}
+
+// Registry — реестр отсеков
+// Каждая зависимость получает собственный изолированный отсек,
+// создаваемый при первом обращении с параметрами по умолчанию
+type Registry struct {
+	// Защита карты отсеков
+	mutex sync.Mutex
+	// Отсеки по именам зависимостей
+	bulkheads map[string]*Bulkhead
+	// Параметры отсеков, создаваемых по умолчанию
+	maxConcurrent int
+	maxWaiting    int
+	timeout       time.Duration
+}
+
+// NewRegistry — функция создания реестра отсеков
+// Параметры используются для отсеков, создаваемых методом Get
+func NewRegistry(maxConcurrent, maxWaiting int, timeout time.Duration) *Registry {
+	return &Registry{
+		bulkheads:     make(map[string]*Bulkhead),
+		maxConcurrent: maxConcurrent,
+		maxWaiting:    maxWaiting,
+		timeout:       timeout,
+	}
+}
+
+// Register — метод добавления в реестр отсека с индивидуальными параметрами
+func (r *Registry) Register(b *Bulkhead) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.bulkheads[b.name]; ok {
+		return fmt.Errorf("отсек %q уже зарегистрирован", b.name)
+	}
+	r.bulkheads[b.name] = b
+	return nil
+}
+
+// Get — метод получения отсека по имени
+// Если отсек еще не существует, он создается с параметрами по умолчанию
+func (r *Registry) Get(name string) (*Bulkhead, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if b, ok := r.bulkheads[name]; ok {
+		return b, nil
+	}
+	b, err := NewBulkhead(name, r.maxConcurrent, r.maxWaiting, r.timeout)
+	if err != nil {
+		return nil, err
+	}
+	r.bulkheads[name] = b
+	return b, nil
+}
+
+// Metrics — метод получения метрик всех отсеков, упорядоченных по имени
+func (r *Registry) Metrics() []Metrics {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	result := make([]Metrics, 0, len(r.bulkheads))
+	for _, b := range r.bulkheads {
+		result = append(result, b.Metrics())
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
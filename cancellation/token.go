@@ -0,0 +1,127 @@
+package cancellation
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"goroutines-example/cause"
+)
+
+// ErrCanceled — причина отмены контекста, связанного с токеном
+var ErrCanceled = errors.New("токен отмены отменен")
+
+// Token — токен кооперативной отмены для кода без context.Context
+// Старый код на обратных вызовах может проверять IsCanceled, ждать Done
+// или подписаться через Register, а с кодом пакета токен связывается
+// через FromContext и Context. Нулевое значение не готово к использованию —
+// создавайте токен через New
+type Token struct {
+	done chan struct{}
+	once sync.Once
+
+	// Защита callbacks и next
+	mutex     sync.Mutex
+	callbacks map[int]func()
+	next      int
+}
+
+// New — функция создания неотмененного токена
+func New() *Token {
+	return &Token{done: make(chan struct{}), callbacks: make(map[int]func())}
+}
+
+// Cancel — метод отмены токена
+// Зарегистрированные обратные вызовы выполняются синхронно в порядке
+// регистрации. Повторные вызовы ничего не делают
+func (t *Token) Cancel() {
+	t.once.Do(func() {
+		t.mutex.Lock()
+		close(t.done)
+		callbacks := make([]func(), 0, len(t.callbacks))
+		for i := 0; i < t.next; i++ {
+			if cb, ok := t.callbacks[i]; ok {
+				callbacks = append(callbacks, cb)
+			}
+		}
+		t.callbacks = nil
+		t.mutex.Unlock()
+
+		for _, cb := range callbacks {
+			cb()
+		}
+	})
+}
+
+// IsCanceled — метод проверки, отменен ли токен
+func (t *Token) IsCanceled() bool {
+	select {
+	case <-t.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Done — метод получения канала, закрываемого при отмене
+func (t *Token) Done() <-chan struct{} {
+	return t.done
+}
+
+// Register — метод подписки на отмену
+// Если токен уже отменен, cb выполняется сразу в вызывающей горутине.
+// Возвращает функцию отписки; она возвращает false, если cb уже
+// был вызван или отписка уже выполнена
+func (t *Token) Register(cb func()) (unregister func() bool) {
+	t.mutex.Lock()
+	if t.callbacks == nil {
+		t.mutex.Unlock()
+		cb()
+		return func() bool { return false }
+	}
+	id := t.next
+	t.next++
+	t.callbacks[id] = cb
+	t.mutex.Unlock()
+
+	return func() bool {
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+		if _, ok := t.callbacks[id]; !ok {
+			return false
+		}
+		delete(t.callbacks, id)
+		return true
+	}
+}
+
+// FromContext — функция получения токена, отменяемого вместе с ctx
+// Наблюдающая горутина завершается при отмене ctx или самого токена,
+// поэтому токен от вечного контекста нужно отменить явно
+func FromContext(ctx context.Context) *Token {
+	t := New()
+	if ctx.Done() == nil {
+		// Контекст никогда не отменяется
+		return t
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			t.Cancel()
+		case <-t.done:
+		}
+	}()
+	return t
+}
+
+// Context — метод получения контекста, отменяемого вместе с токеном
+// Причина отмены (cause.Cause) — ErrCanceled. Возвращаемую функцию отмены
+// нужно вызвать, когда контекст больше не нужен, чтобы отписаться от токена
+func (t *Token) Context(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := cause.WithCancelCause(parent)
+	unregister := t.Register(func() { cancel(ErrCanceled) })
+	return ctx, func() {
+		unregister()
+		cancel(nil)
+	}
+}
@@ -0,0 +1,122 @@
+package cause
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrShutdown — причина отмены при штатном завершении (например, Close области)
+var ErrShutdown = errors.New("завершение работы")
+
+// causeKey — ключ, по которому контекст с причиной находит сам себя
+type causeKey struct{}
+
+// CancelCauseFunc — функция отмены контекста с указанием причины
+// Учитывается только первая причина; nil означает context.Canceled
+type CancelCauseFunc func(cause error)
+
+// causeCtx — контекст, запоминающий причину своей отмены
+type causeCtx struct {
+	context.Context
+	// Защита cause
+	mutex sync.Mutex
+	cause error
+}
+
+// Value — метод поиска значения; по causeKey возвращает сам контекст
+func (c *causeCtx) Value(key any) any {
+	if key == (causeKey{}) {
+		return c
+	}
+	return c.Context.Value(key)
+}
+
+// WithCancelCause — функция создания отменяемого контекста с причиной отмены
+// Аналог context.WithCancelCause из Go 1.20 для модуля на Go 1.19:
+// причину можно получить через Cause. Когда модуль перейдет на новую
+// версию Go, пакет заменяется стандартными функциями без изменения вызовов
+func WithCancelCause(parent context.Context) (context.Context, CancelCauseFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	c := &causeCtx{Context: ctx}
+	return c, func(cause error) {
+		if cause == nil {
+			cause = context.Canceled
+		}
+		c.mutex.Lock()
+		// Если контекст уже отменен (например, родителем), причина
+		// остается прежней
+		if c.cause == nil && c.Context.Err() == nil {
+			c.cause = cause
+		}
+		c.mutex.Unlock()
+		cancel()
+	}
+}
+
+// Cause — функция получения причины отмены ctx
+// Возвращает причину, переданную в CancelCauseFunc ближайшего контекста
+// с причиной; если его отменил родитель — причину родителя. Для контекстов
+// без причины возвращает ctx.Err(), для неотмененных — nil
+func Cause(ctx context.Context) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	c, ok := ctx.Value(causeKey{}).(*causeCtx)
+	if !ok {
+		return ctx.Err()
+	}
+	c.mutex.Lock()
+	cause := c.cause
+	c.mutex.Unlock()
+	if cause != nil {
+		return cause
+	}
+	if c.Context.Err() == nil {
+		// ctx отменен собственным дедлайном ниже контекста с причиной
+		return ctx.Err()
+	}
+	// Контекст с причиной отменен родителем
+	return Cause(c.Context)
+}
+
+// Error — ошибка отмены контекста с причиной
+// errors.Is сопоставляет ее и с ошибкой контекста (context.Canceled,
+// context.DeadlineExceeded), и с причиной; errors.As проходит в причину
+type Error struct {
+	// Ошибка контекста
+	Err error
+	// Причина отмены
+	Cause error
+}
+
+// Error — метод получения текста ошибки
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: %v", e.Err, e.Cause)
+}
+
+// Unwrap — метод получения причины отмены
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Is — метод сопоставления с ошибкой контекста
+func (e *Error) Is(target error) bool {
+	return errors.Is(e.Err, target)
+}
+
+// Err — функция получения ошибки отмены ctx вместе с причиной
+// Используется вместо ctx.Err() там, где ошибка возвращается вызывающему
+// коду: если причина отличается от ctx.Err(), она включается в ошибку.
+// Для неотмененного контекста возвращает nil
+func Err(ctx context.Context) error {
+	err := ctx.Err()
+	if err == nil {
+		return nil
+	}
+	if c := Cause(ctx); c != nil && c != err {
+		return &Error{Err: err, Cause: c}
+	}
+	return err
+}
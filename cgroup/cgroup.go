@@ -0,0 +1,164 @@
+package cgroup
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Корневой каталог файловой системы cgroup
+const cgroupRoot = "/sys/fs/cgroup"
+
+// ErrNoQuota — ошибка отсутствия ограничения CPU для процесса
+var ErrNoQuota = errors.New("квота CPU для процесса не задана")
+
+// CPUQuota — функция определения квоты CPU контейнера (cgroup v2 или v1)
+// Возвращает количество процессоров, доступных по квоте (может быть дробным,
+// например 1.5), или ErrNoQuota, если квота не установлена
+func CPUQuota() (float64, error) {
+	paths, err := selfCgroups("/proc/self/cgroup")
+	if err != nil {
+		return 0, err
+	}
+
+	// cgroup v2: единая иерархия, файл cpu.max вида "<квота> <период>" или "max <период>"
+	if p, ok := paths[""]; ok {
+		for _, dir := range candidates(cgroupRoot, p) {
+			quota, err := readV2(filepath.Join(dir, "cpu.max"))
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return quota, err
+		}
+	}
+
+	// cgroup v1: отдельный контроллер cpu с файлами cpu.cfs_quota_us и cpu.cfs_period_us
+	for controllers, p := range paths {
+		if !hasController(controllers, "cpu") {
+			continue
+		}
+		for _, dir := range candidates(filepath.Join(cgroupRoot, controllers), p) {
+			quota, err := readV1(dir)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return quota, err
+		}
+	}
+	return 0, ErrNoQuota
+}
+
+// AvailableCPUs — функция получения количества процессоров, которые реально
+// доступны процессу: меньшее из GOMAXPROCS и квоты контейнера (с округлением вверх)
+// Используется для выбора количества воркеров и слотов по умолчанию
+func AvailableCPUs() int {
+	procs := runtime.GOMAXPROCS(0)
+	quota, err := CPUQuota()
+	if err != nil {
+		return procs
+	}
+	cpus := int(math.Ceil(quota))
+	if cpus < 1 {
+		cpus = 1
+	}
+	if cpus < procs {
+		return cpus
+	}
+	return procs
+}
+
+// selfCgroups — функция чтения групп процесса
+// Возвращает карту «список контроллеров» -> путь группы
+// (для cgroup v2 список контроллеров пустой)
+func selfCgroups(file string) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	paths := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Формат строки: "<id>:<контроллеры>:<путь>"
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		paths[parts[1]] = parts[2]
+	}
+	return paths, scanner.Err()
+}
+
+// candidates — функция получения каталогов, в которых ищутся файлы квоты
+// Внутри контейнера собственная группа обычно смонтирована как корень,
+// поэтому после пути группы проверяется и сам корень
+func candidates(root, groupPath string) []string {
+	dirs := []string{filepath.Join(root, groupPath)}
+	if groupPath != "/" {
+		dirs = append(dirs, root)
+	}
+	return dirs
+}
+
+// hasController — функция проверки наличия контроллера в списке вида "cpu,cpuacct"
+func hasController(controllers, name string) bool {
+	for _, c := range strings.Split(controllers, ",") {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// readV2 — функция чтения квоты из файла cpu.max (cgroup v2)
+func readV2(file string) (float64, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("неожиданный формат %s: %q", file, data)
+	}
+	if fields[0] == "max" {
+		return 0, ErrNoQuota
+	}
+	return ratio(fields[0], fields[1], file)
+}
+
+// readV1 — функция чтения квоты из файлов cpu.cfs_quota_us и cpu.cfs_period_us (cgroup v1)
+func readV1(dir string) (float64, error) {
+	quota, err := os.ReadFile(filepath.Join(dir, "cpu.cfs_quota_us"))
+	if err != nil {
+		return 0, err
+	}
+	period, err := os.ReadFile(filepath.Join(dir, "cpu.cfs_period_us"))
+	if err != nil {
+		return 0, err
+	}
+	q := strings.TrimSpace(string(quota))
+	if q == "-1" {
+		return 0, ErrNoQuota
+	}
+	return ratio(q, strings.TrimSpace(string(period)), dir)
+}
+
+// ratio — функция вычисления количества процессоров как отношения квоты к периоду
+func ratio(quota, period, source string) (float64, error) {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil {
+		return 0, fmt.Errorf("некорректная квота в %s: %w", source, err)
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0, fmt.Errorf("некорректный период в %s: %q", source, period)
+	}
+	return q / p, nil
+}
@@ -0,0 +1,91 @@
+package chanutil
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrClosed — ошибка чтения из закрытого и уже опустошенного канала
+var ErrClosed = errors.New("канал закрыт")
+
+// Collect — функция чтения из канала не более max значений
+// Читает, пока не наберет max значений, канал не закроется или не будет
+// отменен ctx. max <= 0 — читать до закрытия канала.
+// При отмене ctx возвращаются уже прочитанные значения вместе с ctx.Err(),
+// чтобы вызывающий код не потерял их
+func Collect[T any](ctx context.Context, ch <-chan T, max int) ([]T, error) {
+	var out []T
+	for max <= 0 || len(out) < max {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return out, nil
+			}
+			out = append(out, v)
+		case <-ctx.Done():
+			return out, ctx.Err()
+		}
+	}
+	return out, nil
+}
+
+// Drain — функция чтения всех значений, уже находящихся в буфере канала
+// Не блокируется: останавливается, как только канал пуст или закрыт
+func Drain[T any](ch <-chan T) []T {
+	var out []T
+	for {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return out
+			}
+			out = append(out, v)
+		default:
+			return out
+		}
+	}
+}
+
+// Batch — функция чтения пачки значений из канала
+// Ждет первое значение без ограничения по времени, затем добирает пачку,
+// пока она не достигнет size или с момента первого значения не пройдет maxWait.
+// Если канал закрылся, возвращается неполная пачка, а следующий вызов
+// вернет ErrClosed. При отмене ctx возвращаются уже прочитанные значения
+// вместе с ctx.Err()
+func Batch[T any](ctx context.Context, ch <-chan T, size int, maxWait time.Duration) ([]T, error) {
+	if size <= 0 {
+		return nil, errors.New("размер пачки должен быть положительным")
+	}
+
+	select {
+	case v, ok := <-ch:
+		if !ok {
+			return nil, ErrClosed
+		}
+		if size == 1 {
+			return []T{v}, nil
+		}
+		out := make([]T, 1, size)
+		out[0] = v
+
+		timer := time.NewTimer(maxWait)
+		defer timer.Stop()
+		for len(out) < size {
+			select {
+			case v, ok := <-ch:
+				if !ok {
+					return out, nil
+				}
+				out = append(out, v)
+			case <-timer.C:
+				return out, nil
+			case <-ctx.Done():
+				return out, ctx.Err()
+			}
+		}
+		return out, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,61 @@
+package chanutil
+
+import "context"
+
+// SendCtx — функция отправки значения в канал с отменой через ctx
+// Возвращает ctx.Err(), если отправить не удалось до отмены или дедлайна.
+// Если канал готов и ctx уже отменен одновременно, выбор между ними
+// случаен, поэтому отмененный заранее ctx проверяется до отправки
+func SendCtx[T any](ctx context.Context, ch chan<- T, v T) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case ch <- v:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RecvCtx — функция получения значения из канала с отменой через ctx
+// ok=false без ошибки означает, что канал закрыт и пуст
+func RecvCtx[T any](ctx context.Context, ch <-chan T) (value T, ok bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return value, false, err
+	}
+	select {
+	case value, ok = <-ch:
+		return value, ok, nil
+	case <-ctx.Done():
+		return value, false, ctx.Err()
+	}
+}
+
+// TrySend — функция отправки значения без блокировки
+// Возвращает false, если получатель не готов и буфер канала заполнен
+func TrySend[T any](ch chan<- T, v T) bool {
+	select {
+	case ch <- v:
+		return true
+	default:
+		return false
+	}
+}
+
+// SendNonBlocking — синоним TrySend для читаемости в местах,
+// где отбрасывание значения при заполненном канале — намеренное поведение
+func SendNonBlocking[T any](ch chan<- T, v T) bool {
+	return TrySend(ch, v)
+}
+
+// TryRecv — функция получения значения без блокировки
+// received=false означает, что значения нет; ok=false — что канал закрыт
+func TryRecv[T any](ch <-chan T) (value T, ok, received bool) {
+	select {
+	case value, ok = <-ch:
+		return value, ok, true
+	default:
+		return value, false, false
+	}
+}
@@ -0,0 +1,130 @@
+package chanutil
+
+import (
+	"container/heap"
+	"context"
+)
+
+// MergeSorted — функция слияния K отсортированных каналов в один отсортированный
+// Каждый источник читается отдельной горутиной заранее, не более чем на
+// readahead значений вперед (readahead < 1 считается 1), поэтому медленный
+// источник не задерживает чтение остальных, а память ограничена
+// K*readahead значениями. less задает порядок, в котором отсортированы
+// источники; равные значения выдаются в порядке номеров источников.
+// Выходной канал закрывается, когда закрыты все источники или отменен ctx.
+// Полезна после параллельной обработки по разделам, когда каждый раздел
+// упорядочен сам по себе
+func MergeSorted[T any](ctx context.Context, less func(a, b T) bool, readahead int, sources ...<-chan T) <-chan T {
+	if readahead < 1 {
+		readahead = 1
+	}
+	buffers := make([]chan T, len(sources))
+	for i, src := range sources {
+		buffers[i] = make(chan T, readahead)
+		go func(src <-chan T, buf chan<- T) {
+			defer close(buf)
+			for {
+				v, ok, err := RecvCtx(ctx, src)
+				if err != nil || !ok || SendCtx(ctx, buf, v) != nil {
+					return
+				}
+			}
+		}(src, buffers[i])
+	}
+
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		h := &mergeHeap[T]{less: less}
+		// Голова каждого источника нужна до первой выдачи: без нее
+		// нельзя знать, что минимальное значение уже найдено
+		for i, buf := range buffers {
+			if !h.pull(ctx, i, buf) && ctx.Err() != nil {
+				return
+			}
+		}
+		heap.Init(h)
+		for h.Len() > 0 {
+			head := h.items[0]
+			if SendCtx(ctx, out, head.value) != nil {
+				return
+			}
+			if v, ok, err := RecvCtx(ctx, buffers[head.source]); err != nil {
+				return
+			} else if ok {
+				h.items[0].value = v
+				heap.Fix(h, 0)
+			} else {
+				heap.Pop(h)
+			}
+		}
+	}()
+	return out
+}
+
+// MergeSortedIter — функция слияния K отсортированных итераторов
+// next возвращает очередное значение и false, когда значения кончились.
+// Каждый итератор вызывается из своей горутины; остальное — как у MergeSorted
+func MergeSortedIter[T any](ctx context.Context, less func(a, b T) bool, readahead int, iters ...func() (T, bool)) <-chan T {
+	sources := make([]<-chan T, len(iters))
+	for i, next := range iters {
+		ch := make(chan T)
+		sources[i] = ch
+		go func(next func() (T, bool), ch chan<- T) {
+			defer close(ch)
+			for {
+				v, ok := next()
+				if !ok || SendCtx(ctx, ch, v) != nil {
+					return
+				}
+			}
+		}(next, ch)
+	}
+	return MergeSorted(ctx, less, readahead, sources...)
+}
+
+// mergeHead — текущая голова источника слияния
+type mergeHead[T any] struct {
+	value  T
+	source int
+}
+
+// mergeHeap — куча голов источников по возрастанию (реализует heap.Interface)
+type mergeHeap[T any] struct {
+	items []mergeHead[T]
+	less  func(a, b T) bool
+}
+
+// pull — метод чтения первой головы источника
+// Возвращает false, если источник пуст или отменен ctx
+func (h *mergeHeap[T]) pull(ctx context.Context, source int, buf <-chan T) bool {
+	v, ok, err := RecvCtx(ctx, buf)
+	if err != nil || !ok {
+		return false
+	}
+	h.items = append(h.items, mergeHead[T]{value: v, source: source})
+	return true
+}
+
+func (h *mergeHeap[T]) Len() int { return len(h.items) }
+
+func (h *mergeHeap[T]) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if h.less(a.value, b.value) {
+		return true
+	}
+	if h.less(b.value, a.value) {
+		return false
+	}
+	return a.source < b.source
+}
+
+func (h *mergeHeap[T]) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *mergeHeap[T]) Push(x any) { h.items = append(h.items, x.(mergeHead[T])) }
+
+func (h *mergeHeap[T]) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
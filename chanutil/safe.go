@@ -0,0 +1,103 @@
+package chanutil
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrProducerDone — ошибка отправки производителем, уже завершившим работу
+var ErrProducerDone = errors.New("производитель уже завершил работу")
+
+// CloseOnce — канал, который можно безопасно закрывать несколько раз
+// Защищает только от повторного закрытия; для нескольких производителей,
+// которые отправляют значения, используйте SafeChannel
+type CloseOnce[T any] struct {
+	C    chan T
+	once sync.Once
+}
+
+// NewCloseOnce — функция создания канала с буфером size
+func NewCloseOnce[T any](size int) *CloseOnce[T] {
+	return &CloseOnce[T]{C: make(chan T, size)}
+}
+
+// Close — метод закрытия канала; повторные вызовы ничего не делают
+func (c *CloseOnce[T]) Close() {
+	c.once.Do(func() { close(c.C) })
+}
+
+// SafeChannel — канал с несколькими производителями и согласованным закрытием
+// Каждый производитель регистрируется через Register и по окончании вызывает
+// Done. Канал закрывается ровно один раз — после Done последнего
+// производителя, поэтому нет ни паники «close of closed channel», ни
+// «send on closed channel»: отправлять может только незавершенный
+// производитель, а пока он есть, канал не закрыт
+type SafeChannel[T any] struct {
+	ch chan T
+
+	// Защита producers и closed
+	mutex     sync.Mutex
+	producers int
+	closed    bool
+}
+
+// NewSafeChannel — функция создания канала с буфером size
+// Канал закрывается, когда завершится последний из зарегистрированных
+// производителей; зарегистрируйте первого до запуска потребителей
+func NewSafeChannel[T any](size int) *SafeChannel[T] {
+	return &SafeChannel[T]{ch: make(chan T, size)}
+}
+
+// C — метод получения канала для чтения
+func (c *SafeChannel[T]) C() <-chan T {
+	return c.ch
+}
+
+// Register — метод регистрации производителя
+// Возвращает ErrClosed, если канал уже закрыт
+func (c *SafeChannel[T]) Register() (*Producer[T], error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
+	c.producers++
+	return &Producer[T]{c: c}, nil
+}
+
+// unregister — метод снятия производителя с учета
+func (c *SafeChannel[T]) unregister() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.producers--
+	if c.producers == 0 {
+		c.closed = true
+		close(c.ch)
+	}
+}
+
+// Producer — зарегистрированный производитель SafeChannel
+type Producer[T any] struct {
+	c    *SafeChannel[T]
+	done atomic.Bool
+}
+
+// Send — метод отправки значения с отменой через ctx
+// Возвращает ErrProducerDone после Done этого производителя
+func (p *Producer[T]) Send(ctx context.Context, v T) error {
+	if p.done.Load() {
+		return ErrProducerDone
+	}
+	return SendCtx(ctx, p.c.ch, v)
+}
+
+// Done — метод завершения работы производителя
+// Повторные вызовы ничего не делают. Done нельзя вызывать одновременно
+// с Send того же производителя
+func (p *Producer[T]) Done() {
+	if p.done.CompareAndSwap(false, true) {
+		p.c.unregister()
+	}
+}
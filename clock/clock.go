@@ -0,0 +1,217 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock — источник времени
+// Примитивы пакета получают время и таймеры через этот интерфейс,
+// чтобы в тестах реальное время можно было заменить виртуальным
+type Clock interface {
+	// Now — текущее время
+	Now() time.Time
+	// Since — время, прошедшее с момента t
+	Since(t time.Time) time.Duration
+	// After — канал, в который придет время через d
+	After(d time.Duration) <-chan time.Time
+	// NewTimer — создание таймера на d
+	NewTimer(d time.Duration) Timer
+	// Sleep — приостановка текущей горутины на d
+	Sleep(d time.Duration)
+}
+
+// Timer — таймер, аналогичный time.Timer
+type Timer interface {
+	// C — канал срабатывания таймера
+	C() <-chan time.Time
+	// Stop — остановка таймера; возвращает false, если таймер уже сработал или остановлен
+	Stop() bool
+	// Reset — перезапуск таймера на d; возвращает true, если таймер был активен
+	Reset(d time.Duration) bool
+}
+
+// Real — функция получения часов, работающих в реальном времени
+func Real() Clock {
+	return realClock{}
+}
+
+// realClock — часы реального времени (обертка над пакетом time)
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+// realTimer — таймер реального времени
+type realTimer struct {
+	t *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time        { return t.t.C }
+func (t realTimer) Stop() bool                 { return t.t.Stop() }
+func (t realTimer) Reset(d time.Duration) bool { return t.t.Reset(d) }
+
+// Fake — часы с виртуальным временем для детерминированных тестов
+// Время стоит на месте, пока тест явно не продвинет его методом Advance.
+// Таймауты семафора и других примитивов, использующих эти часы,
+// срабатывают мгновенно и воспроизводимо, без реального ожидания
+type Fake struct {
+	// Защита состояния часов
+	mutex sync.Mutex
+	// Текущее виртуальное время
+	now time.Time
+	// Активные таймеры
+	timers []*fakeTimer
+	// Закрывается и заменяется новым при добавлении таймера (для BlockUntil)
+	changed chan struct{}
+}
+
+// NewFake — функция создания виртуальных часов, начинающих отсчет с start
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start, changed: make(chan struct{})}
+}
+
+// Now — метод получения текущего виртуального времени
+func (f *Fake) Now() time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.now
+}
+
+// Since — метод получения виртуального времени, прошедшего с момента t
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// After — метод получения канала, в который придет время через d виртуального времени
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// Sleep — метод приостановки горутины на d виртуального времени
+// Горутина проснется, только когда тест продвинет часы
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+// NewTimer — метод создания таймера в виртуальном времени
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// Advance — метод продвижения виртуального времени на d
+// Все таймеры, срок которых наступил, срабатывают в порядке своих сроков
+func (f *Fake) Advance(d time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.now = f.now.Add(d)
+
+	sort.SliceStable(f.timers, func(i, j int) bool {
+		return f.timers[i].deadline.Before(f.timers[j].deadline)
+	})
+	remaining := f.timers[:0]
+	for _, t := range f.timers {
+		if t.deadline.After(f.now) {
+			remaining = append(remaining, t)
+			continue
+		}
+		t.active = false
+		select {
+		case t.c <- t.deadline:
+		default:
+		}
+	}
+	for i := len(remaining); i < len(f.timers); i++ {
+		f.timers[i] = nil
+	}
+	f.timers = remaining
+}
+
+// Waiters — метод получения количества активных таймеров
+func (f *Fake) Waiters() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return len(f.timers)
+}
+
+// BlockUntil — метод ожидания, пока количество активных таймеров не достигнет n
+// Позволяет тесту убедиться, что горутины уже ждут на часах,
+// прежде чем продвигать время
+func (f *Fake) BlockUntil(n int) {
+	for {
+		f.mutex.Lock()
+		if len(f.timers) >= n {
+			f.mutex.Unlock()
+			return
+		}
+		changed := f.changed
+		f.mutex.Unlock()
+		<-changed
+	}
+}
+
+// fakeTimer — таймер виртуальных часов
+type fakeTimer struct {
+	clock    *Fake
+	c        chan time.Time
+	deadline time.Time
+	active   bool
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	f := t.clock
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.removeLocked(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	f := t.clock
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	wasActive := f.removeLocked(t)
+	t.deadline = f.now.Add(d)
+	if d <= 0 {
+		select {
+		case t.c <- t.deadline:
+		default:
+		}
+		return wasActive
+	}
+	t.active = true
+	f.timers = append(f.timers, t)
+	close(f.changed)
+	f.changed = make(chan struct{})
+	return wasActive
+}
+
+// removeLocked — метод удаления таймера из списка активных
+// Вызывается при захваченном мьютексе
+func (f *Fake) removeLocked(t *fakeTimer) bool {
+	if !t.active {
+		return false
+	}
+	t.active = false
+	for i, other := range f.timers {
+		if other == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			break
+		}
+	}
+	return true
+}
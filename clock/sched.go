@@ -0,0 +1,157 @@
+package clock
+
+import (
+	"math/rand"
+	"sync"
+
+	"goroutines-example/internal/runtimeinfo"
+)
+
+// Scheduler — кооперативный планировщик горутин с воспроизводимым порядком
+// В каждый момент выполняется только одна задача; она отдает управление
+// в точках Yield (например, через semaphore.WithYieldHook), и следующую
+// задачу выбирает генератор случайных чисел с заданным зерном. Один и тот
+// же seed дает одно и то же чередование, поэтому найденную гонку можно
+// воспроизвести, повторив запуск с тем же seed.
+// Задача не должна блокироваться напрямую: пока она ждет, остальные стоят.
+// Блокирующие вызовы оборачиваются в Block — на время ожидания задача
+// выходит из-под управления планировщика, и порядок ее возвращения
+// зависит уже от реального времени
+type Scheduler struct {
+	// Защита полей ниже
+	mutex sync.Mutex
+	// Сигнал о появлении готовых задач или завершении последней задачи
+	cond *sync.Cond
+	rand *rand.Rand
+	// Готовые к выполнению задачи в порядке постановки
+	runnable []*task
+	// Задачи по номеру их горутины (для Yield и Block)
+	byGID map[uint64]*task
+	// Количество незавершенных задач
+	alive int
+	// Имена задач в порядке получения управления
+	trace []string
+	// Сигнал планировщику о том, что текущая задача отдала управление
+	back chan struct{}
+}
+
+// task — задача планировщика
+type task struct {
+	name string
+	// Сигнал задаче о получении управления
+	wake chan struct{}
+	// Задача ждет внутри Block и не управляется планировщиком
+	blocked bool
+}
+
+// NewScheduler — функция создания планировщика с зерном seed
+func NewScheduler(seed int64) *Scheduler {
+	s := &Scheduler{
+		rand:  rand.New(rand.NewSource(seed)),
+		byGID: make(map[uint64]*task),
+		back:  make(chan struct{}),
+	}
+	s.cond = sync.NewCond(&s.mutex)
+	return s
+}
+
+// Go — метод добавления задачи fn с именем name
+// Может вызываться как до Run, так и из выполняющихся задач
+func (s *Scheduler) Go(name string, fn func()) {
+	t := &task{name: name, wake: make(chan struct{})}
+	s.mutex.Lock()
+	s.alive++
+	s.runnable = append(s.runnable, t)
+	s.mutex.Unlock()
+
+	go func() {
+		gid := runtimeinfo.GoroutineID()
+		s.mutex.Lock()
+		s.byGID[gid] = t
+		s.mutex.Unlock()
+
+		<-t.wake
+		defer s.finish(gid)
+		fn()
+	}()
+}
+
+// Yield — метод передачи управления другой задаче
+// Вызов из горутины, не запущенной через Go, или изнутри Block ничего не делает
+func (s *Scheduler) Yield() {
+	t := s.current()
+	if t == nil || t.blocked {
+		return
+	}
+	s.mutex.Lock()
+	s.runnable = append(s.runnable, t)
+	s.mutex.Unlock()
+	s.back <- struct{}{}
+	<-t.wake
+}
+
+// Block — метод выполнения блокирующего вызова fn вне очереди планировщика
+// Пока fn ждет, выполняются другие задачи; после возврата fn задача снова
+// становится готовой и ждет своей очереди
+func (s *Scheduler) Block(fn func()) {
+	t := s.current()
+	if t == nil || t.blocked {
+		fn()
+		return
+	}
+	s.mutex.Lock()
+	t.blocked = true
+	s.mutex.Unlock()
+	s.back <- struct{}{}
+
+	fn()
+
+	s.mutex.Lock()
+	t.blocked = false
+	s.runnable = append(s.runnable, t)
+	s.cond.Signal()
+	s.mutex.Unlock()
+	<-t.wake
+}
+
+// Run — метод выполнения задач до завершения всех
+// Возвращает имена задач в порядке получения управления
+func (s *Scheduler) Run() []string {
+	for {
+		s.mutex.Lock()
+		// Готовых задач нет, но часть задач ждет внутри Block
+		for len(s.runnable) == 0 && s.alive > 0 {
+			s.cond.Wait()
+		}
+		if s.alive == 0 {
+			trace := s.trace
+			s.mutex.Unlock()
+			return trace
+		}
+		i := s.rand.Intn(len(s.runnable))
+		t := s.runnable[i]
+		s.runnable = append(s.runnable[:i], s.runnable[i+1:]...)
+		s.trace = append(s.trace, t.name)
+		s.mutex.Unlock()
+
+		t.wake <- struct{}{}
+		<-s.back
+	}
+}
+
+// current — метод получения задачи текущей горутины (nil, если ее нет)
+func (s *Scheduler) current() *task {
+	gid := runtimeinfo.GoroutineID()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.byGID[gid]
+}
+
+// finish — метод учета завершения задачи горутины gid
+func (s *Scheduler) finish(gid uint64) {
+	s.mutex.Lock()
+	delete(s.byGID, gid)
+	s.alive--
+	s.mutex.Unlock()
+	s.back <- struct{}{}
+}
@@ -0,0 +1,66 @@
+package clock
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// runCounters — функция запуска трех задач, каждая из которых трижды
+// отдает управление, и получения трассы планировщика
+func runCounters(seed int64) []string {
+	s := NewScheduler(seed)
+	for _, name := range []string{"a", "b", "c"} {
+		s.Go(name, func() {
+			for i := 0; i < 3; i++ {
+				s.Yield()
+			}
+		})
+	}
+	return s.Run()
+}
+
+// TestSchedulerReproducible — один и тот же seed дает одно и то же чередование
+func TestSchedulerReproducible(t *testing.T) {
+	first := runCounters(42)
+	if len(first) != 12 {
+		t.Fatalf("в трассе %d шагов, ожидалось 12: %v", len(first), first)
+	}
+	for i := 0; i < 10; i++ {
+		if again := runCounters(42); !reflect.DeepEqual(first, again) {
+			t.Fatalf("трасса изменилась при том же seed:\n%v\n%v", first, again)
+		}
+	}
+	differs := false
+	for seed := int64(0); seed < 20 && !differs; seed++ {
+		differs = !reflect.DeepEqual(first, runCounters(seed))
+	}
+	if !differs {
+		t.Fatalf("разные seed дают одно и то же чередование: %v", first)
+	}
+}
+
+// TestSchedulerBlock — задача внутри Block не мешает выполнению остальных
+func TestSchedulerBlock(t *testing.T) {
+	s := NewScheduler(1)
+	ch := make(chan int)
+	got := 0
+	s.Go("receiver", func() {
+		s.Block(func() { got = <-ch })
+	})
+	s.Go("sender", func() {
+		s.Yield()
+		s.Block(func() { ch <- 7 })
+	})
+
+	done := make(chan []string)
+	go func() { done <- s.Run() }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("планировщик не завершился")
+	}
+	if got != 7 {
+		t.Fatalf("получено %d, ожидалось 7", got)
+	}
+}
@@ -0,0 +1,78 @@
+package clock
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Sleep — функция приостановки на d с учетом отмены ctx
+// В отличие от time.Sleep, возвращается сразу при отмене ctx с его ошибкой
+func Sleep(ctx context.Context, c Clock, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := c.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
+
+// WaitUntil — функция ожидания выполнения условия cond
+// Условие проверяется сразу и затем каждые poll, пока не станет истинным
+// или пока не будет отменен ctx
+func WaitUntil(ctx context.Context, c Clock, cond func() bool, poll time.Duration) error {
+	if poll <= 0 {
+		return fmt.Errorf("период проверки должен быть положительным: %v", poll)
+	}
+	for {
+		if cond() {
+			return nil
+		}
+		if err := Sleep(ctx, c, poll); err != nil {
+			return err
+		}
+	}
+}
+
+// Eventually — функция проверки, что условие станет истинным за время timeout
+// Предназначена для тестов: возвращает true, как только cond выполнилось
+func Eventually(c Clock, cond func() bool, timeout, poll time.Duration) bool {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	deadline := c.NewTimer(timeout)
+	defer deadline.Stop()
+	go func() {
+		select {
+		case <-deadline.C():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return WaitUntil(ctx, c, cond, poll) == nil
+}
+
+// Consistently — функция проверки, что условие остается истинным все время duration
+// Предназначена для тестов: возвращает false при первом нарушении условия
+func Consistently(c Clock, cond func() bool, duration, poll time.Duration) bool {
+	if poll <= 0 {
+		return false
+	}
+	end := c.Now().Add(duration)
+	for {
+		if !cond() {
+			return false
+		}
+		if !c.Now().Before(end) {
+			return true
+		}
+		c.Sleep(poll)
+	}
+}
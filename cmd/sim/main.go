@@ -0,0 +1,81 @@
+// Команда sim — анализ «что если» для семафора на записанной нагрузке
+// Воспроизводит запись (моменты поступления и время удержания) при разных
+// количествах разрешений и политиках обслуживания и выводит распределение
+// времени ожидания, чтобы планировать емкость без реальной нагрузки:
+//
+//	go run ./cmd/sim -trace workload.jsonl -permits 4,8,16 -policy fifo,lifo -max-wait 500ms
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"goroutines-example/workload"
+)
+
+func main() {
+	tracePath := flag.String("trace", "-", "файл записи нагрузки в формате JSON Lines (- — stdin)")
+	permitsFlag := flag.String("permits", "4,8,16", "количества разрешений через запятую")
+	policyFlag := flag.String("policy", "fifo", "политики обслуживания через запятую: fifo, lifo, random")
+	maxWait := flag.Duration("max-wait", 0, "время ожидания до ухода по таймауту (0 — без ограничения)")
+	seed := flag.Int64("seed", 1, "зерно генератора для политики random")
+	flag.Parse()
+
+	if err := run(*tracePath, *permitsFlag, *policyFlag, *maxWait, *seed, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "sim:", err)
+		os.Exit(1)
+	}
+}
+
+// run — функция моделирования всех сочетаний параметров и вывода таблицы
+func run(tracePath, permitsFlag, policyFlag string, maxWait time.Duration, seed int64, out io.Writer) error {
+	var in io.Reader = os.Stdin
+	if tracePath != "-" {
+		f, err := os.Open(tracePath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	events, err := workload.ReadTrace(in)
+	if err != nil {
+		return err
+	}
+
+	var permits []int
+	for _, s := range strings.Split(permitsFlag, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("некорректное количество разрешений %q", s)
+		}
+		permits = append(permits, n)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "permits\tpolicy\tserved\ttimed out\tmean\tp50\tp90\tp99\tmax\tutil\t\n")
+	for _, p := range permits {
+		for _, policy := range strings.Split(policyFlag, ",") {
+			r, err := workload.Simulate(events, workload.SimConfig{
+				Permits: p,
+				Policy:  workload.Policy(strings.TrimSpace(policy)),
+				MaxWait: maxWait,
+				Seed:    seed,
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%v\t%v\t%v\t%v\t%v\t%.1f%%\t\n",
+				p, r.Config.Policy, r.Served, r.TimedOut,
+				r.Wait.Mean, r.Wait.P50, r.Wait.P90, r.Wait.P99, r.Wait.Max,
+				r.Utilization*100)
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,109 @@
+package combinator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"goroutines-example/cause"
+)
+
+// ErrNoFunctions — ошибка вызова комбинатора без функций
+var ErrNoFunctions = errors.New("не передано ни одной функции")
+
+// ErrRaceLost — причина отмены функций, проигравших гонку
+// Проигравшая функция может отличить ее через cause.Cause(ctx) от отмены
+// вызывающей стороной и, например, не логировать отмену как сбой
+var ErrRaceLost = errors.New("гонка выиграна другой функцией")
+
+// AllFailedError — ошибка, возвращаемая Race, когда все функции завершились неудачей
+// Содержит ошибки всех функций в порядке их передачи в Race.
+// Отличается от отмены: при отмене контекста Race возвращает ошибку контекста
+type AllFailedError struct {
+	Errors []error
+}
+
+// Error — метод формирования текста ошибки
+func (e *AllFailedError) Error() string {
+	parts := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		parts[i] = fmt.Sprintf("[%d] %v", i, err)
+	}
+	return fmt.Sprintf("все %d функций завершились ошибкой: %s", len(e.Errors), strings.Join(parts, "; "))
+}
+
+// Unwrap — метод получения вложенных ошибок
+func (e *AllFailedError) Unwrap() []error {
+	return e.Errors
+}
+
+// Is — метод сопоставления с вложенными ошибками для errors.Is
+// errors.Is в go 1.19 не разворачивает Unwrap() []error, поэтому обход ручной
+func (e *AllFailedError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As — метод поиска вложенной ошибки нужного типа для errors.As
+func (e *AllFailedError) As(target any) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// RaceResult — результат успешного выполнения Race
+type RaceResult[T any] struct {
+	// Значение, возвращенное первой успешной функцией
+	Value T
+	// Номер этой функции в списке аргументов Race
+	Index int
+}
+
+// Race — функция конкурентного запуска нескольких функций
+// Возвращает результат первой успешно завершившейся функции и отменяет
+// контекст остальных с причиной ErrRaceLost. Если все функции завершились ошибкой, возвращается
+// *AllFailedError; если раньше был отменен ctx — ошибка ctx
+func Race[T any](ctx context.Context, fns ...func(ctx context.Context) (T, error)) (RaceResult[T], error) {
+	if len(fns) == 0 {
+		return RaceResult[T]{}, ErrNoFunctions
+	}
+
+	ctx, cancel := cause.WithCancelCause(ctx)
+	defer cancel(ErrRaceLost)
+
+	type outcome struct {
+		index int
+		value T
+		err   error
+	}
+	// Буфер на все функции, чтобы проигравшие горутины не блокировались
+	outcomes := make(chan outcome, len(fns))
+	for i, fn := range fns {
+		go func(i int, fn func(ctx context.Context) (T, error)) {
+			v, err := fn(ctx)
+			outcomes <- outcome{index: i, value: v, err: err}
+		}(i, fn)
+	}
+
+	errs := make([]error, len(fns))
+	for remaining := len(fns); remaining > 0; remaining-- {
+		select {
+		case <-ctx.Done():
+			return RaceResult[T]{}, cause.Err(ctx)
+		case o := <-outcomes:
+			if o.err == nil {
+				return RaceResult[T]{Value: o.value, Index: o.index}, nil
+			}
+			errs[o.index] = o.err
+		}
+	}
+	return RaceResult[T]{}, &AllFailedError{Errors: errs}
+}
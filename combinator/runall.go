@@ -0,0 +1,151 @@
+package combinator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"goroutines-example/cause"
+)
+
+// TaskResult — результат одной задачи RunAll
+type TaskResult struct {
+	// Номер задачи в списке аргументов RunAll
+	Index int
+	// Ошибка задачи (nil — успех)
+	Err error
+	// Время выполнения задачи (0, если задача не запускалась)
+	Duration time.Duration
+}
+
+// Progress — ход выполнения RunAll
+type Progress struct {
+	// Количество завершенных задач, включая неудачные
+	Done int
+	// Количество неудачных задач
+	Failed int
+	// Общее количество задач
+	Total int
+}
+
+// FailuresError — ошибка, возвращаемая RunAll, когда часть задач завершилась неудачей
+type FailuresError struct {
+	// Общее количество задач
+	Total int
+	// Результаты неудачных задач в порядке их номеров
+	Failures []TaskResult
+}
+
+// Error — метод формирования текста ошибки
+func (e *FailuresError) Error() string {
+	parts := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		parts[i] = fmt.Sprintf("[%d] %v", f.Index, f.Err)
+	}
+	return fmt.Sprintf("%d из %d задач завершились ошибкой: %s", len(e.Failures), e.Total, strings.Join(parts, "; "))
+}
+
+// Unwrap — метод получения вложенных ошибок
+func (e *FailuresError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f.Err
+	}
+	return errs
+}
+
+// Is — метод сопоставления с ошибками задач для errors.Is
+// errors.Is в go 1.19 не разворачивает Unwrap() []error, поэтому обход ручной
+func (e *FailuresError) Is(target error) bool {
+	for _, f := range e.Failures {
+		if errors.Is(f.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As — метод поиска ошибки задачи нужного типа для errors.As
+func (e *FailuresError) As(target any) bool {
+	for _, f := range e.Failures {
+		if errors.As(f.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// RunAll — функция выполнения всех задач не более чем по limit одновременно
+// В отличие от группы с прерыванием при первой ошибке, неудача одной задачи
+// не отменяет остальные: выполняются все, а результаты возвращаются в
+// порядке задач. progress (если не nil) вызывается после каждой задачи,
+// последовательно. Если хотя бы одна задача завершилась ошибкой,
+// возвращается *FailuresError. При отмене ctx еще не запущенные задачи
+// не запускаются и получают ошибку ctx, а RunAll возвращает ее после
+// завершения уже запущенных. limit <= 0 — без ограничения
+func RunAll(ctx context.Context, tasks []func(ctx context.Context) error, limit int, progress func(Progress)) ([]TaskResult, error) {
+	results := make([]TaskResult, len(tasks))
+	if len(tasks) == 0 {
+		return results, nil
+	}
+	if limit <= 0 || limit > len(tasks) {
+		limit = len(tasks)
+	}
+
+	var (
+		mutex sync.Mutex
+		state = Progress{Total: len(tasks)}
+	)
+	finish := func(r TaskResult) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		results[r.Index] = r
+		state.Done++
+		if r.Err != nil {
+			state.Failed++
+		}
+		if progress != nil {
+			progress(state)
+		}
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < limit; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if ctx.Err() != nil {
+					finish(TaskResult{Index: i, Err: cause.Err(ctx)})
+					continue
+				}
+				start := time.Now()
+				err := tasks[i](ctx)
+				finish(TaskResult{Index: i, Err: err, Duration: time.Since(start)})
+			}
+		}()
+	}
+	for i := range tasks {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return results, cause.Err(ctx)
+	}
+	var failures []TaskResult
+	for _, r := range results {
+		if r.Err != nil {
+			failures = append(failures, r)
+		}
+	}
+	if len(failures) > 0 {
+		return results, &FailuresError{Total: len(tasks), Failures: failures}
+	}
+	return results, nil
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Duration — длительность, записываемая в документе строкой ("500ms", "2s")
+type Duration time.Duration
+
+// UnmarshalJSON — метод разбора длительности из строки
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("длительность должна быть строкой вида \"1s\": %s", data)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("некорректная длительность %q: %w", s, err)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON — метод записи длительности строкой
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// SemaphoreConfig — параметры именованного семафора
+type SemaphoreConfig struct {
+	MaxPermits int      `json:"max_permits"`
+	Timeout    Duration `json:"timeout"`
+}
+
+// LimiterConfig — параметры именованного ограничителя частоты
+type LimiterConfig struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// Document — описание примитивов конкурентности
+// Пример:
+//
+//	{
+//	  "semaphores": {"db": {"max_permits": 20, "timeout": "2s"}},
+//	  "limiters":   {"api": {"rate": 100, "burst": 20}}
+//	}
+type Document struct {
+	Semaphores map[string]SemaphoreConfig `json:"semaphores"`
+	Limiters   map[string]LimiterConfig   `json:"limiters"`
+}
+
+// Parse — функция разбора и проверки документа в формате JSON
+// Неизвестные поля считаются ошибкой, чтобы опечатка в имени параметра
+// не превращалась молча в значение по умолчанию
+func Parse(data []byte) (*Document, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var doc Document
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("не удалось разобрать конфигурацию: %w", err)
+	}
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Validate — метод проверки документа
+// Возвращает все найденные ошибки одним сообщением
+func (d *Document) Validate() error {
+	var problems []string
+	for name, s := range d.Semaphores {
+		if s.MaxPermits <= 0 {
+			problems = append(problems, fmt.Sprintf("семафор %q: max_permits должен быть положительным: %d", name, s.MaxPermits))
+		}
+		if s.Timeout <= 0 {
+			problems = append(problems, fmt.Sprintf("семафор %q: timeout должен быть положительным: %v", name, time.Duration(s.Timeout)))
+		}
+	}
+	for name, l := range d.Limiters {
+		if l.Rate <= 0 {
+			problems = append(problems, fmt.Sprintf("ограничитель %q: rate должен быть положительным: %v", name, l.Rate))
+		}
+		if l.Burst <= 0 {
+			problems = append(problems, fmt.Sprintf("ограничитель %q: burst должен быть положительным: %d", name, l.Burst))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	// Порядок обхода карт случаен — сортируем для стабильного сообщения
+	sort.Strings(problems)
+	return fmt.Errorf("некорректная конфигурация:\n%s", strings.Join(problems, "\n"))
+}
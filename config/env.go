@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Префиксы переменных окружения для переопределения параметров
+// Имя примитива приводится к верхнему регистру, а все символы, кроме
+// латинских букв и цифр, заменяются на "_": семафор "db-main" переопределяется
+// переменной GOROUTINE_LIMIT_DB_MAIN
+const (
+	EnvLimitPrefix = "GOROUTINE_LIMIT_"
+	EnvRatePrefix  = "GOROUTINE_RATE_"
+	EnvBurstPrefix = "GOROUTINE_BURST_"
+)
+
+// EnvName — функция получения имени переменной окружения для примитива
+func EnvName(prefix, name string) string {
+	var b strings.Builder
+	b.WriteString(prefix)
+	for _, r := range strings.ToUpper(name) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// envInt — функция чтения положительного целого переопределения
+// Некорректное значение передается в ErrorHandler и игнорируется
+func envInt(prefix, name string) (int, bool) {
+	key := EnvName(prefix, name)
+	s, ok := os.LookupEnv(key)
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || v <= 0 {
+		ErrorHandler(fmt.Errorf("переменная %s: ожидается положительное целое, получено %q", key, s))
+		return 0, false
+	}
+	return v, true
+}
+
+// envFloat — функция чтения положительного вещественного переопределения
+func envFloat(prefix, name string) (float64, bool) {
+	key := EnvName(prefix, name)
+	s, ok := os.LookupEnv(key)
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || v <= 0 {
+		ErrorHandler(fmt.Errorf("переменная %s: ожидается положительное число, получено %q", key, s))
+		return 0, false
+	}
+	return v, true
+}
+
+// limiterConfig — функция получения параметров ограничителя с учетом окружения
+func limiterConfig(name string, cfg LimiterConfig) LimiterConfig {
+	if v, ok := envFloat(EnvRatePrefix, name); ok {
+		cfg.Rate = v
+	}
+	if v, ok := envInt(EnvBurstPrefix, name); ok {
+		cfg.Burst = v
+	}
+	return cfg
+}
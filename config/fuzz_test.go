@@ -0,0 +1,32 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// FuzzParse — корректный документ после записи в JSON разбирается заново
+// в то же значение, а некорректный вход приводит к ошибке, а не к панике
+func FuzzParse(f *testing.F) {
+	f.Add([]byte(`{"semaphores": {"db": {"max_permits": 20, "timeout": "2s"}}, "limiters": {"api": {"rate": 100, "burst": 20}}}`))
+	f.Add([]byte(`{"semaphores": {"db": {"max_permits": 0, "timeout": "1ms"}}}`))
+	f.Add([]byte(`{"limiters": {"api": {"rate": 0.5, "burst": 1, "extra": true}}}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		doc, err := Parse(data)
+		if err != nil {
+			return
+		}
+		encoded, err := json.Marshal(doc)
+		if err != nil {
+			t.Fatalf("не удалось записать разобранный документ: %v", err)
+		}
+		again, err := Parse(encoded)
+		if err != nil {
+			t.Fatalf("записанный документ не разбирается: %v\n%s", err, encoded)
+		}
+		if !reflect.DeepEqual(doc, again) {
+			t.Fatalf("документ изменился после записи и разбора:\n%+v\n%+v", doc, again)
+		}
+	})
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Source — источник значения лимита
+type Source string
+
+const (
+	SourceDocument    Source = "document"
+	SourceProvider    Source = "provider"
+	SourceEnvironment Source = "environment"
+)
+
+// ChangeEvent — событие изменения размера семафора для журнала аудита
+type ChangeEvent struct {
+	Name     string
+	Old, New int
+	Source   Source
+	At       time.Time
+}
+
+// Provider — внешний источник лимитов (флаги функциональности, etcd, Consul)
+// Реестр периодически опрашивает его методом Limits
+type Provider interface {
+	// Limits — текущие размеры семафоров по именам; отсутствующее имя
+	// означает, что Provider не управляет этим семафором
+	Limits(ctx context.Context) (map[string]int, error)
+}
+
+// Subscriber — Provider, умеющий сам сообщать об изменениях
+// Subscribe вызывает fn при каждом изменении, пока не будет отменен ctx.
+// Для такого Provider реестр не делает периодических опросов
+type Subscriber interface {
+	Provider
+	Subscribe(ctx context.Context, fn func(name string, limit int)) error
+}
+
+// OnChange — метод задания обработчика изменений размеров семафоров
+// Обработчик вызывается синхронно при удержании блокировки реестра,
+// поэтому не должен обращаться к реестру
+func (r *Registry) OnChange(fn func(ChangeEvent)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.onChange = fn
+}
+
+// pending — значение от Provider, ожидающее истечения интервала устойчивости
+type pending struct {
+	limit int
+	since time.Time
+}
+
+// update — значение, присланное подписанным Provider
+type update struct {
+	name  string
+	limit int
+}
+
+// UseProvider — метод подключения внешнего источника лимитов до отмены ctx
+// Значение применяется (через Resize), только если оно не менялось
+// в течение debounce: так частое переключение флага не дергает семафор.
+// interval — период опроса Provider и проверки устойчивости значений.
+// Ошибки опроса передаются в ErrorHandler
+func (r *Registry) UseProvider(ctx context.Context, p Provider, interval, debounce time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("период опроса должен быть положительным: %v", interval)
+	}
+
+	waiting := make(map[string]pending)
+	observe := func(name string, limit int) {
+		if limit <= 0 {
+			ErrorHandler(fmt.Errorf("семафор %q: Provider вернул неположительный лимит %d", name, limit))
+			return
+		}
+		if w, ok := waiting[name]; ok && w.limit == limit {
+			return
+		}
+		waiting[name] = pending{limit: limit, since: time.Now()}
+	}
+
+	updates := make(chan update)
+	sub, subscribed := p.(Subscriber)
+	if subscribed {
+		go func() {
+			err := sub.Subscribe(ctx, func(name string, limit int) {
+				select {
+				case updates <- update{name: name, limit: limit}:
+				case <-ctx.Done():
+				}
+			})
+			if err != nil && ctx.Err() == nil {
+				ErrorHandler(fmt.Errorf("подписка на Provider: %w", err))
+			}
+		}()
+	}
+	poll := func() {
+		limits, err := p.Limits(ctx)
+		if err != nil {
+			ErrorHandler(fmt.Errorf("опрос Provider: %w", err))
+			return
+		}
+		for name, limit := range limits {
+			observe(name, limit)
+		}
+	}
+	poll()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case u := <-updates:
+			observe(u.name, u.limit)
+		case <-ticker.C:
+			if !subscribed {
+				poll()
+			}
+			r.applyProvider(waiting, debounce)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// applyProvider — метод применения устоявшихся значений от Provider
+func (r *Registry) applyProvider(waiting map[string]pending, debounce time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	for name, w := range waiting {
+		if now.Sub(w.since) < debounce {
+			continue
+		}
+		delete(waiting, name)
+		if r.providerLimits[name] == w.limit {
+			continue
+		}
+		r.providerLimits[name] = w.limit
+		if cs, ok := r.semaphores[name]; ok {
+			cfg, source := r.semaphoreConfigLocked(name)
+			r.applySemaphoreLocked(name, cs, cfg, source)
+		}
+	}
+}
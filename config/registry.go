@@ -0,0 +1,229 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"goroutines-example/ratelimit"
+	"goroutines-example/semaphore"
+)
+
+// ErrorHandler — обработчик ошибок фоновой перезагрузки конфигурации
+// По умолчанию сообщение выводится в stderr
+var ErrorHandler = func(err error) {
+	fmt.Fprintln(os.Stderr, err)
+}
+
+// Registry — реестр именованных примитивов, построенных по документу
+// При перезагрузке документа существующие примитивы не пересоздаются
+// (ими уже пользуются), а меняются на лету через Resize и SetRate/SetBurst.
+// Таймаут семафора задается только при создании. Примитивы, исчезнувшие
+// из документа, остаются в реестре с последними параметрами.
+// Переменные окружения (см. EnvLimitPrefix) имеют приоритет над документом
+// и перечитываются при каждом получении примитива из реестра, поэтому
+// оператор может изменить лимит без повторного развертывания.
+// Размеры семафоров также может задавать внешний Provider (см. UseProvider):
+// его значения важнее документа, но уступают окружению
+type Registry struct {
+	// Защита карт
+	mutex      sync.RWMutex
+	semaphores map[string]*semaphore.CountingSemaphore
+	limiters   map[string]*ratelimit.Limiter
+	// Параметры из документа без учета окружения
+	semaphoreDocs map[string]SemaphoreConfig
+	limiterDocs   map[string]LimiterConfig
+	// Размеры семафоров, полученные от Provider
+	providerLimits map[string]int
+	// Обработчик изменений лимитов (может отсутствовать)
+	onChange func(ChangeEvent)
+}
+
+// NewRegistry — функция создания реестра по документу
+func NewRegistry(doc *Document) (*Registry, error) {
+	r := &Registry{
+		semaphores:     make(map[string]*semaphore.CountingSemaphore),
+		limiters:       make(map[string]*ratelimit.Limiter),
+		semaphoreDocs:  make(map[string]SemaphoreConfig),
+		limiterDocs:    make(map[string]LimiterConfig),
+		providerLimits: make(map[string]int),
+	}
+	if err := r.Apply(doc); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Load — функция создания реестра по файлу конфигурации
+func Load(path string) (*Registry, error) {
+	doc, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewRegistry(doc)
+}
+
+// readFile — функция чтения и разбора файла конфигурации
+func readFile(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать конфигурацию: %w", err)
+	}
+	return Parse(data)
+}
+
+// Semaphore — метод получения семафора по имени
+// Перед возвратом применяется переопределение из окружения, если оно задано
+func (r *Registry) Semaphore(name string) (*semaphore.CountingSemaphore, bool) {
+	// Блокировка на запись: получение может изменить размер семафора, и
+	// одновременные вызовы не должны применять его дважды
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	cs, ok := r.semaphores[name]
+	if ok {
+		cfg, source := r.semaphoreConfigLocked(name)
+		r.applySemaphoreLocked(name, cs, cfg, source)
+	}
+	return cs, ok
+}
+
+// Limiter — метод получения ограничителя частоты по имени
+// Перед возвратом применяется переопределение из окружения, если оно задано
+func (r *Registry) Limiter(name string) (*ratelimit.Limiter, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	l, ok := r.limiters[name]
+	if ok {
+		r.applyLimiterLocked(name, l, limiterConfig(name, r.limiterDocs[name]))
+	}
+	return l, ok
+}
+
+// semaphoreConfigLocked — метод получения действующих параметров семафора
+// Приоритет: окружение, затем Provider, затем документ
+func (r *Registry) semaphoreConfigLocked(name string) (SemaphoreConfig, Source) {
+	cfg, source := r.semaphoreDocs[name], SourceDocument
+	if v, ok := r.providerLimits[name]; ok {
+		cfg.MaxPermits, source = v, SourceProvider
+	}
+	if v, ok := envInt(EnvLimitPrefix, name); ok {
+		cfg.MaxPermits, source = v, SourceEnvironment
+	}
+	return cfg, source
+}
+
+// applySemaphoreLocked — метод изменения размера семафора, если он отличается
+// Ошибки передаются в ErrorHandler: получение из реестра не должно
+// отказывать из-за неудачного изменения параметров.
+// Вызывается при захваченном на запись mutex: сравнение, изменение размера
+// и ChangeEvent идут одним шагом, поэтому событие не дублируется и не
+// обгоняет другое изменение того же семафора
+func (r *Registry) applySemaphoreLocked(name string, cs *semaphore.CountingSemaphore, cfg SemaphoreConfig, source Source) {
+	old := cs.MaxPermits()
+	if old == cfg.MaxPermits {
+		return
+	}
+	if err := cs.Resize(cfg.MaxPermits); err != nil {
+		ErrorHandler(fmt.Errorf("семафор %q: %w", name, err))
+		return
+	}
+	if r.onChange != nil {
+		r.onChange(ChangeEvent{Name: name, Old: old, New: cfg.MaxPermits, Source: source, At: time.Now()})
+	}
+}
+
+// applyLimiterLocked — метод изменения параметров ограничителя, если они отличаются
+func (r *Registry) applyLimiterLocked(name string, l *ratelimit.Limiter, cfg LimiterConfig) {
+	if l.Rate() != cfg.Rate {
+		if err := l.SetRate(cfg.Rate); err != nil {
+			ErrorHandler(fmt.Errorf("ограничитель %q: %w", name, err))
+		}
+	}
+	if l.Burst() != cfg.Burst {
+		if err := l.SetBurst(cfg.Burst); err != nil {
+			ErrorHandler(fmt.Errorf("ограничитель %q: %w", name, err))
+		}
+	}
+}
+
+// Apply — метод применения документа к реестру
+// Документ проверяется целиком до каких-либо изменений, поэтому
+// некорректная конфигурация не применяется частично
+func (r *Registry) Apply(doc *Document) error {
+	if err := doc.Validate(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for name, fromDoc := range doc.Semaphores {
+		r.semaphoreDocs[name] = fromDoc
+		cfg, source := r.semaphoreConfigLocked(name)
+		cs, ok := r.semaphores[name]
+		if !ok {
+			r.semaphores[name] = semaphore.NewCountingSemaphore(cfg.MaxPermits, time.Duration(cfg.Timeout), semaphore.WithName(name))
+			continue
+		}
+		r.applySemaphoreLocked(name, cs, cfg, source)
+	}
+	for name, fromDoc := range doc.Limiters {
+		r.limiterDocs[name] = fromDoc
+		cfg := limiterConfig(name, fromDoc)
+		l, ok := r.limiters[name]
+		if !ok {
+			l, err := ratelimit.NewLimiter(cfg.Rate, cfg.Burst)
+			if err != nil {
+				return fmt.Errorf("ограничитель %q: %w", name, err)
+			}
+			r.limiters[name] = l
+			continue
+		}
+		r.applyLimiterLocked(name, l, cfg)
+	}
+	return nil
+}
+
+// Reload — метод перечитывания файла конфигурации и применения изменений
+func (r *Registry) Reload(path string) error {
+	doc, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	return r.Apply(doc)
+}
+
+// Watch — метод отслеживания изменений файла конфигурации до отмены ctx
+// Раз в interval проверяет время изменения файла и при изменении вызывает
+// Reload. Ошибки чтения и проверки передаются в ErrorHandler, а реестр
+// сохраняет последнюю корректную конфигурацию
+func (r *Registry) Watch(ctx context.Context, path string, interval time.Duration) error {
+	var lastMod time.Time
+	if info, err := os.Stat(path); err == nil {
+		lastMod = info.ModTime()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			info, err := os.Stat(path)
+			if err != nil {
+				ErrorHandler(fmt.Errorf("не удалось проверить конфигурацию: %w", err))
+				continue
+			}
+			if !info.ModTime().After(lastMod) {
+				continue
+			}
+			lastMod = info.ModTime()
+			if err := r.Reload(path); err != nil {
+				ErrorHandler(err)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
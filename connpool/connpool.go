@@ -0,0 +1,318 @@
+package connpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"goroutines-example/semaphore"
+)
+
+// ErrClosed — ошибка работы с закрытым пулом
+var ErrClosed = errors.New("пул соединений закрыт")
+
+// Config — параметры пула соединений
+type Config struct {
+	// Функция установки нового соединения (обязательна)
+	Dial func(ctx context.Context) (net.Conn, error)
+	// Максимальное количество открытых соединений (выданных и простаивающих)
+	MaxOpen int
+	// Максимальное количество простаивающих соединений
+	MaxIdle int
+	// Максимальное время жизни соединения (0 — без ограничения)
+	MaxLifetime time.Duration
+	// Максимальное время простоя соединения (0 — без ограничения)
+	MaxIdleTime time.Duration
+	// Проверка простаивавшего соединения перед выдачей (может отсутствовать)
+	HealthCheck func(conn net.Conn) error
+	// Таймаут ожидания свободного места в пуле
+	AcquireTimeout time.Duration
+}
+
+// idleConn — простаивающее соединение
+type idleConn struct {
+	conn     net.Conn
+	created  time.Time
+	returned time.Time
+}
+
+// Pool — пул сетевых соединений
+// Количество открытых соединений ограничено семафором на MaxOpen разрешений:
+// разрешение захватывается при выдаче соединения и освобождается при его
+// возврате или закрытии
+type Pool struct {
+	cfg Config
+	// Семафор, ограничивающий количество открытых соединений
+	sem *semaphore.CountingSemaphore
+
+	// Защита списка простаивающих соединений
+	mutex sync.Mutex
+	// Простаивающие соединения (последнее возвращенное — в конце)
+	idle   []idleConn
+	closed bool
+
+	// Счетчики статистики
+	dials     atomic.Uint64
+	dialFails atomic.Uint64
+	reused    atomic.Uint64
+	evicted   atomic.Uint64
+	unhealthy atomic.Uint64
+	inUse     atomic.Int64
+
+	// Остановка фонового вытеснения
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Stats — снимок статистики пула
+type Stats struct {
+	MaxOpen   int
+	InUse     int64
+	Idle      int
+	Dials     uint64
+	DialFails uint64
+	Reused    uint64
+	Evicted   uint64
+	Unhealthy uint64
+}
+
+// New — функция создания пула соединений
+func New(cfg Config) (*Pool, error) {
+	if cfg.Dial == nil {
+		return nil, fmt.Errorf("функция установки соединения не задана")
+	}
+	if cfg.MaxOpen <= 0 {
+		return nil, fmt.Errorf("максимальное количество соединений должно быть положительным: %d", cfg.MaxOpen)
+	}
+	if cfg.MaxIdle < 0 || cfg.MaxIdle > cfg.MaxOpen {
+		return nil, fmt.Errorf("количество простаивающих соединений должно быть от 0 до %d: %d", cfg.MaxOpen, cfg.MaxIdle)
+	}
+
+	p := &Pool{
+		cfg:  cfg,
+		sem:  semaphore.NewCountingSemaphore(cfg.MaxOpen, cfg.AcquireTimeout, semaphore.WithName("connpool")),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	interval := minPositive(cfg.MaxIdleTime, cfg.MaxLifetime) / 2
+	if interval > 0 {
+		go p.evictLoop(interval)
+	} else {
+		close(p.done)
+	}
+	return p, nil
+}
+
+// minPositive — функция выбора меньшей из положительных длительностей
+func minPositive(a, b time.Duration) time.Duration {
+	switch {
+	case a <= 0:
+		return b
+	case b <= 0:
+		return a
+	case a < b:
+		return a
+	default:
+		return b
+	}
+}
+
+// Conn — соединение, выданное пулом
+// После использования его нужно вернуть методом Release
+// или закрыть методом Discard, если оно неисправно
+type Conn struct {
+	net.Conn
+	pool    *Pool
+	created time.Time
+	once    sync.Once
+}
+
+// Get — метод получения соединения из пула
+// Простаивающее соединение проверяется перед выдачей; если простаивающих
+// нет, устанавливается новое. Блокируется, пока открыто MaxOpen соединений
+func (p *Pool) Get(ctx context.Context) (*Conn, error) {
+	if p.isClosed() {
+		return nil, ErrClosed
+	}
+	if _, err := p.sem.AcquireAtLeast(ctx, 1, 1); err != nil {
+		return nil, err
+	}
+
+	for {
+		ic, ok := p.popIdle()
+		if !ok {
+			break
+		}
+		if p.expired(ic, time.Now()) {
+			ic.conn.Close()
+			p.evicted.Add(1)
+			continue
+		}
+		if p.cfg.HealthCheck != nil {
+			if err := p.cfg.HealthCheck(ic.conn); err != nil {
+				ic.conn.Close()
+				p.unhealthy.Add(1)
+				continue
+			}
+		}
+		p.reused.Add(1)
+		p.inUse.Add(1)
+		return &Conn{Conn: ic.conn, pool: p, created: ic.created}, nil
+	}
+
+	conn, err := p.cfg.Dial(ctx)
+	if err != nil {
+		p.dialFails.Add(1)
+		p.sem.Release()
+		return nil, err
+	}
+	p.dials.Add(1)
+	p.inUse.Add(1)
+	return &Conn{Conn: conn, pool: p, created: time.Now()}, nil
+}
+
+// Release — метод возврата соединения в пул
+// Соединение закрывается, если пул закрыт, время жизни истекло
+// или простаивающих соединений уже MaxIdle. Повторный вызов ничего не делает
+func (c *Conn) Release() {
+	c.once.Do(func() {
+		p := c.pool
+		defer p.sem.Release()
+		p.inUse.Add(-1)
+
+		now := time.Now()
+		ic := idleConn{conn: c.Conn, created: c.created, returned: now}
+
+		p.mutex.Lock()
+		if p.closed || len(p.idle) >= p.cfg.MaxIdle || p.expired(ic, now) {
+			p.mutex.Unlock()
+			c.Conn.Close()
+			return
+		}
+		p.idle = append(p.idle, ic)
+		p.mutex.Unlock()
+	})
+}
+
+// Discard — метод закрытия неисправного соединения без возврата в пул
+func (c *Conn) Discard() {
+	c.once.Do(func() {
+		c.pool.inUse.Add(-1)
+		c.Conn.Close()
+		c.pool.sem.Release()
+	})
+}
+
+// popIdle — метод извлечения последнего возвращенного простаивающего соединения
+// Соединение из пула занимает разрешение, уже захваченное вызывающей стороной
+func (p *Pool) popIdle() (idleConn, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if len(p.idle) == 0 {
+		return idleConn{}, false
+	}
+	ic := p.idle[len(p.idle)-1]
+	p.idle[len(p.idle)-1] = idleConn{}
+	p.idle = p.idle[:len(p.idle)-1]
+	return ic, true
+}
+
+// expired — метод проверки истечения времени жизни или простоя соединения
+func (p *Pool) expired(ic idleConn, now time.Time) bool {
+	if p.cfg.MaxLifetime > 0 && now.Sub(ic.created) >= p.cfg.MaxLifetime {
+		return true
+	}
+	if p.cfg.MaxIdleTime > 0 && !ic.returned.IsZero() && now.Sub(ic.returned) >= p.cfg.MaxIdleTime {
+		return true
+	}
+	return false
+}
+
+// evictLoop — фоновый цикл закрытия просроченных простаивающих соединений
+func (p *Pool) evictLoop(interval time.Duration) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case now := <-ticker.C:
+			var stale []net.Conn
+			p.mutex.Lock()
+			kept := p.idle[:0]
+			for _, ic := range p.idle {
+				if p.expired(ic, now) {
+					stale = append(stale, ic.conn)
+				} else {
+					kept = append(kept, ic)
+				}
+			}
+			for i := len(kept); i < len(p.idle); i++ {
+				p.idle[i] = idleConn{}
+			}
+			p.idle = kept
+			p.mutex.Unlock()
+
+			// Закрываем вне мьютекса: закрытие сетевого соединения может быть долгим
+			for _, conn := range stale {
+				conn.Close()
+				p.evicted.Add(1)
+			}
+		}
+	}
+}
+
+// isClosed — метод проверки, закрыт ли пул
+func (p *Pool) isClosed() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.closed
+}
+
+// Stats — метод получения снимка статистики пула
+func (p *Pool) Stats() Stats {
+	p.mutex.Lock()
+	idle := len(p.idle)
+	p.mutex.Unlock()
+
+	return Stats{
+		MaxOpen:   p.cfg.MaxOpen,
+		InUse:     p.inUse.Load(),
+		Idle:      idle,
+		Dials:     p.dials.Load(),
+		DialFails: p.dialFails.Load(),
+		Reused:    p.reused.Load(),
+		Evicted:   p.evicted.Load(),
+		Unhealthy: p.unhealthy.Load(),
+	}
+}
+
+// Close — метод закрытия пула
+// Простаивающие соединения закрываются сразу, выданные — при возврате
+func (p *Pool) Close() error {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return nil
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.mutex.Unlock()
+
+	close(p.stop)
+	<-p.done
+
+	for _, ic := range idle {
+		ic.conn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package connpool
+
+import "encoding/json"
+
+// MarshalJSON — метод выгрузки конфигурации и статистики пула в JSON
+func (p *Pool) MarshalJSON() ([]byte, error) {
+	s := p.Stats()
+	return json.Marshal(struct {
+		MaxOpen     int    `json:"max_open"`
+		MaxIdle     int    `json:"max_idle"`
+		MaxLifetime string `json:"max_lifetime"`
+		MaxIdleTime string `json:"max_idle_time"`
+		InUse       int64  `json:"in_use"`
+		Idle        int    `json:"idle"`
+		Dials       uint64 `json:"dials"`
+		DialFails   uint64 `json:"dial_fails"`
+		Reused      uint64 `json:"reused"`
+		Evicted     uint64 `json:"evicted"`
+		Unhealthy   uint64 `json:"unhealthy"`
+		Closed      bool   `json:"closed"`
+	}{
+		MaxOpen:     s.MaxOpen,
+		MaxIdle:     p.cfg.MaxIdle,
+		MaxLifetime: p.cfg.MaxLifetime.String(),
+		MaxIdleTime: p.cfg.MaxIdleTime.String(),
+		InUse:       s.InUse,
+		Idle:        s.Idle,
+		Dials:       s.Dials,
+		DialFails:   s.DialFails,
+		Reused:      s.Reused,
+		Evicted:     s.Evicted,
+		Unhealthy:   s.Unhealthy,
+		Closed:      p.isClosed(),
+	})
+}
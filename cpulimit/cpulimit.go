@@ -0,0 +1,146 @@
+package cpulimit
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"goroutines-example/cgroup"
+	"goroutines-example/semaphore"
+)
+
+// Limiter — ограничитель CPU-задач по количеству процессоров
+// Количество разрешений равно количеству доступных процессоров (меньшему из
+// runtime.GOMAXPROCS и квоты CPU контейнера), умноженному на множитель,
+// и автоматически пересчитывается при их изменении
+type Limiter struct {
+	// Семафор со слотами процессора
+	sem *semaphore.CountingSemaphore
+	// Множитель количества слотов относительно GOMAXPROCS
+	multiplier float64
+	// Количество процессоров, по которому рассчитан текущий размер
+	procs int
+	// Источник количества доступных процессоров
+	cpus func() int
+
+	// Защита поля procs
+	mutex sync.Mutex
+	// Остановка фоновой проверки GOMAXPROCS
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Option — функциональная опция ограничителя
+type Option func(*Limiter)
+
+// WithCPUs — опция явного задания количества процессоров
+// Отключает автоматическое определение по GOMAXPROCS и квоте контейнера
+func WithCPUs(n int) Option {
+	return func(l *Limiter) {
+		l.cpus = func() int { return n }
+	}
+}
+
+// NewLimiter — функция создания ограничителя
+// multiplier — множитель количества слотов (1 — по слоту на процессор)
+// timeout — таймаут ожидания слота
+// pollInterval — период проверки изменения количества процессоров
+func NewLimiter(multiplier float64, timeout, pollInterval time.Duration, opts ...Option) (*Limiter, error) {
+	if multiplier <= 0 {
+		return nil, fmt.Errorf("множитель должен быть положительным: %v", multiplier)
+	}
+	if pollInterval <= 0 {
+		return nil, fmt.Errorf("период проверки должен быть положительным: %v", pollInterval)
+	}
+
+	l := &Limiter{
+		multiplier: multiplier,
+		cpus:       cgroup.AvailableCPUs,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.procs = l.cpus()
+	l.sem = semaphore.NewCountingSemaphore(slotsFor(l.procs, multiplier), timeout, semaphore.WithName("cpu"))
+
+	go l.watch(pollInterval)
+	return l, nil
+}
+
+// slotsFor — функция расчета количества слотов (не меньше одного)
+func slotsFor(procs int, multiplier float64) int {
+	slots := int(math.Round(float64(procs) * multiplier))
+	if slots < 1 {
+		slots = 1
+	}
+	return slots
+}
+
+// watch — фоновый цикл отслеживания изменений количества процессоров
+func (l *Limiter) watch(interval time.Duration) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.Refresh()
+		}
+	}
+}
+
+// Refresh — метод немедленного пересчета количества слотов
+// по текущему количеству доступных процессоров
+func (l *Limiter) Refresh() {
+	procs := l.cpus()
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if procs == l.procs {
+		return
+	}
+	l.procs = procs
+	l.sem.Resize(slotsFor(procs, l.multiplier))
+}
+
+// Acquire — метод захвата слота процессора
+func (l *Limiter) Acquire() error {
+	return l.sem.Acquire()
+}
+
+// TryAcquire — метод попытки захвата слота без ожидания
+func (l *Limiter) TryAcquire() bool {
+	return l.sem.TryAcquire()
+}
+
+// Release — метод освобождения слота
+func (l *Limiter) Release() error {
+	return l.sem.Release()
+}
+
+// Slots — метод получения текущего количества слотов
+func (l *Limiter) Slots() int {
+	return l.sem.MaxPermits()
+}
+
+// Semaphore — метод получения семафора, лежащего в основе ограничителя
+func (l *Limiter) Semaphore() *semaphore.CountingSemaphore {
+	return l.sem
+}
+
+// Close — метод остановки отслеживания количества процессоров
+// Ограничитель продолжает работать с последним рассчитанным количеством слотов
+func (l *Limiter) Close() {
+	l.stopOnce.Do(func() {
+		close(l.stop)
+		<-l.done
+	})
+}
@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"goroutines-example/latency"
+	"goroutines-example/semaphore"
+)
+
+// Gate — шлюз к базе данных, ограничивающий количество одновременных запросов
+// Каждый запрос захватывает разрешение у семафора, выполняется с собственным
+// таймаутом и учитывается в метриках насыщения
+type Gate struct {
+	// Обернутое подключение к базе данных
+	db *sql.DB
+	// Семафор, ограничивающий количество одновременных запросов
+	sem *semaphore.CountingSemaphore
+	// Максимальное количество одновременных запросов
+	maxConcurrent int
+	// Таймаут выполнения одного запроса (0 — без таймаута)
+	queryTimeout time.Duration
+
+	// Количество запросов, ожидающих разрешения
+	waiting atomic.Int64
+	// Количество запросов, которым не хватило свободного разрешения сразу
+	saturated atomic.Uint64
+	// Количество запросов, не дождавшихся разрешения
+	rejected atomic.Uint64
+	// Распределение длительности выполнения запросов
+	durations *latency.Recorder
+}
+
+// Stats — снимок метрик шлюза
+type Stats struct {
+	MaxConcurrent int
+	InUse         int
+	Waiting       int64
+	Saturated     uint64
+	Rejected      uint64
+	Wait          latency.Snapshot
+	Query         latency.Snapshot
+}
+
+// NewGate — функция создания шлюза
+// maxConcurrent — максимальное количество одновременных запросов
+// acquireTimeout — максимальное время ожидания разрешения
+// queryTimeout — таймаут выполнения одного запроса (0 — без таймаута)
+func NewGate(db *sql.DB, maxConcurrent int, acquireTimeout, queryTimeout time.Duration) (*Gate, error) {
+	if db == nil {
+		return nil, fmt.Errorf("подключение к базе данных не задано")
+	}
+	if maxConcurrent <= 0 {
+		return nil, fmt.Errorf("количество одновременных запросов должно быть положительным: %d", maxConcurrent)
+	}
+	return &Gate{
+		db:            db,
+		sem:           semaphore.NewCountingSemaphore(maxConcurrent, acquireTimeout),
+		maxConcurrent: maxConcurrent,
+		queryTimeout:  queryTimeout,
+		durations:     latency.NewRecorder(),
+	}, nil
+}
+
+// DB — метод получения обернутого подключения
+func (g *Gate) DB() *sql.DB {
+	return g.db
+}
+
+// acquire — метод захвата разрешения и подготовки контекста запроса
+// Возвращает функцию, которую нужно вызвать после завершения запроса
+func (g *Gate) acquire(ctx context.Context) (context.Context, func(), error) {
+	if !g.sem.TryAcquire() {
+		g.saturated.Add(1)
+		g.waiting.Add(1)
+		err := g.sem.AcquireContext(ctx)
+		g.waiting.Add(-1)
+		if err != nil {
+			g.rejected.Add(1)
+			return nil, nil, err
+		}
+	}
+
+	cancel := context.CancelFunc(func() {})
+	if g.queryTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, g.queryTimeout)
+	}
+	stop := g.durations.Start()
+
+	return ctx, func() {
+		stop()
+		cancel()
+		g.sem.Release()
+	}, nil
+}
+
+// Exec — метод выполнения запроса, не возвращающего строк
+func (g *Gate) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, done, err := g.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return g.db.ExecContext(ctx, query, args...)
+}
+
+// Query — метод выполнения запроса, возвращающего строки
+// Разрешение удерживается, пока работает scan; строки закрываются автоматически
+func (g *Gate) Query(ctx context.Context, query string, scan func(rows *sql.Rows) error, args ...any) error {
+	ctx, done, err := g.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	rows, err := g.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if err := scan(rows); err != nil {
+		return err
+	}
+	return rows.Err()
+}
+
+// QueryRow — метод выполнения запроса, возвращающего не более одной строки
+// Значения столбцов записываются в dest
+func (g *Gate) QueryRow(ctx context.Context, query string, dest []any, args ...any) error {
+	ctx, done, err := g.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+	return g.db.QueryRowContext(ctx, query, args...).Scan(dest...)
+}
+
+// Stats — метод получения снимка метрик шлюза
+func (g *Gate) Stats() Stats {
+	return Stats{
+		MaxConcurrent: g.maxConcurrent,
+		InUse:         g.maxConcurrent - g.sem.AvailablePermits(),
+		Waiting:       g.waiting.Load(),
+		Saturated:     g.saturated.Load(),
+		Rejected:      g.rejected.Load(),
+		Wait:          g.sem.WaitStats().Snapshot(),
+		Query:         g.durations.Snapshot(),
+	}
+}
@@ -0,0 +1,71 @@
+package debugstate
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// DebugHandler — HTTP-обработчик с состоянием примитивов конкурентности
+// Отдает JSON-объект, в котором под зарегистрированными именами лежат
+// результаты MarshalJSON семафоров, ограничителей, пулов и т.д.
+// Обычно монтируется как /debug/concurrency:
+//
+//	h := debugstate.NewDebugHandler()
+//	h.Register("db", sem)
+//	http.Handle("/debug/concurrency", h)
+type DebugHandler struct {
+	// Защита components
+	mutex      sync.RWMutex
+	components map[string]json.Marshaler
+}
+
+// NewDebugHandler — функция создания обработчика
+func NewDebugHandler() *DebugHandler {
+	return &DebugHandler{components: make(map[string]json.Marshaler)}
+}
+
+// Register — метод регистрации компонента под именем
+// Компонент с тем же именем заменяется
+func (h *DebugHandler) Register(name string, v json.Marshaler) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.components[name] = v
+}
+
+// Unregister — метод удаления компонента
+func (h *DebugHandler) Unregister(name string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	delete(h.components, name)
+}
+
+// ServeHTTP — метод выгрузки состояния всех компонентов
+// Параметр запроса name ограничивает выгрузку одним компонентом
+func (h *DebugHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mutex.RLock()
+	out := make(map[string]json.Marshaler, len(h.components))
+	if name := r.URL.Query().Get("name"); name != "" {
+		v, ok := h.components[name]
+		if !ok {
+			h.mutex.RUnlock()
+			http.Error(w, "компонент не найден: "+name, http.StatusNotFound)
+			return
+		}
+		out[name] = v
+	} else {
+		for name, v := range h.components {
+			out[name] = v
+		}
+	}
+	h.mutex.RUnlock()
+
+	// encoding/json сортирует ключи, поэтому вывод стабилен
+	body, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(body)
+}
@@ -0,0 +1,111 @@
+package dedup
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"goroutines-example/shardmap"
+)
+
+// Deduper — подавитель дубликатов в скользящем временном окне
+// Ключ считается дубликатом, если он уже был принят не раньше, чем ttl назад.
+// Повторное появление дубликата не продлевает окно: ключ, который приходит
+// постоянно, принимается раз в ttl
+type Deduper struct {
+	// Время принятия ключей
+	seen *shardmap.Map[time.Time]
+	// Длина окна
+	ttl time.Duration
+
+	// Количество принятых ключей и отброшенных дубликатов
+	accepted   atomic.Uint64
+	duplicates atomic.Uint64
+
+	// Остановка фоновой очистки
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewDeduper — функция создания подавителя дубликатов
+// ttl — длина окна; просроченные ключи удаляются фоновой очисткой каждые ttl
+func NewDeduper(ttl time.Duration) (*Deduper, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("длина окна должна быть положительной: %v", ttl)
+	}
+	seen, err := shardmap.New[time.Time](32)
+	if err != nil {
+		return nil, err
+	}
+	d := &Deduper{
+		seen: seen,
+		ttl:  ttl,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go d.cleanup()
+	return d, nil
+}
+
+// Seen — метод проверки ключа
+// Возвращает true, если ключ уже встречался в пределах окна (дубликат).
+// Иначе запоминает ключ и возвращает false
+func (d *Deduper) Seen(key string) bool {
+	now := time.Now()
+	duplicate := false
+	d.seen.Compute(key, func(at time.Time, ok bool) (time.Time, bool) {
+		if ok && now.Sub(at) < d.ttl {
+			duplicate = true
+			return at, true
+		}
+		return now, true
+	})
+
+	if duplicate {
+		d.duplicates.Add(1)
+	} else {
+		d.accepted.Add(1)
+	}
+	return duplicate
+}
+
+// Forget — метод удаления ключа из окна
+// Полезно, если обработка принятого элемента завершилась ошибкой
+// и повтор должен быть принят
+func (d *Deduper) Forget(key string) {
+	d.seen.Delete(key)
+}
+
+// Stats — метод получения количества принятых ключей и отброшенных дубликатов
+func (d *Deduper) Stats() (accepted, duplicates uint64) {
+	return d.accepted.Load(), d.duplicates.Load()
+}
+
+// cleanup — фоновый цикл удаления просроченных ключей
+func (d *Deduper) cleanup() {
+	defer close(d.done)
+
+	ticker := time.NewTicker(d.ttl)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case now := <-ticker.C:
+			d.seen.DeleteIf(func(_ string, at time.Time) bool {
+				return now.Sub(at) >= d.ttl
+			})
+		}
+	}
+}
+
+// Close — метод остановки фоновой очистки
+func (d *Deduper) Close() {
+	d.closeOnce.Do(func() {
+		close(d.stop)
+		<-d.done
+	})
+}
@@ -0,0 +1,184 @@
+package donetree
+
+import (
+	"context"
+	"sync"
+)
+
+// tree — общее состояние всех узлов одного дерева
+type tree struct {
+	// Защита структуры дерева и флагов узлов
+	mutex sync.Mutex
+	// Закрывается и заменяется при каждом освобождении узла
+	changed chan struct{}
+}
+
+// Node — узел дерева сигналов завершения
+// Close закрывает Done узла и всех его потомков, в том числе подключенных
+// позже. Горутина-владелец узла, увидев Done, завершает работу и вызывает
+// Release; Survivors и Wait позволяют родителю дождаться, пока все потомки
+// освободятся. Так иерархическое завершение координируется без протаскивания
+// context.Context через старые слои. Нулевое значение не готово к
+// использованию — создавайте корень через New
+type Node struct {
+	tree   *tree
+	parent *Node
+	done   chan struct{}
+
+	// Поля ниже защищены tree.mutex
+	children map[*Node]struct{}
+	closed   bool
+	released bool
+}
+
+// New — функция создания корня нового дерева
+func New() *Node {
+	t := &tree{changed: make(chan struct{})}
+	return newNode(t, nil)
+}
+
+// newNode — функция создания узла дерева t
+func newNode(t *tree, parent *Node) *Node {
+	return &Node{
+		tree:     t,
+		parent:   parent,
+		done:     make(chan struct{}),
+		children: make(map[*Node]struct{}),
+	}
+}
+
+// Child — метод подключения дочернего узла
+// Если узел уже закрыт, потомок создается закрытым; если узел уже
+// освобожден, потомок подключается к ближайшему неосвобожденному предку
+func (n *Node) Child() *Node {
+	n.tree.mutex.Lock()
+	defer n.tree.mutex.Unlock()
+
+	parent := n
+	for parent != nil && parent.released {
+		parent = parent.parent
+	}
+	child := newNode(n.tree, parent)
+	if parent == nil {
+		// Все дерево освобождено — потомок сразу закрыт и освобожден
+		child.closeLocked()
+		child.released = true
+		return child
+	}
+	parent.children[child] = struct{}{}
+	if n.closed || parent.closed {
+		child.closeLocked()
+	}
+	return child
+}
+
+// Done — метод получения канала, закрываемого при закрытии узла
+func (n *Node) Done() <-chan struct{} {
+	return n.done
+}
+
+// IsClosed — метод проверки, закрыт ли узел
+func (n *Node) IsClosed() bool {
+	n.tree.mutex.Lock()
+	defer n.tree.mutex.Unlock()
+	return n.closed
+}
+
+// Close — метод закрытия узла и всех его потомков
+// Возвращает количество узлов, закрытых этим вызовом. Повторные вызовы
+// безопасны и возвращают 0
+func (n *Node) Close() int {
+	n.tree.mutex.Lock()
+	defer n.tree.mutex.Unlock()
+	return n.closeLocked()
+}
+
+// closeLocked — метод рекурсивного закрытия поддерева
+func (n *Node) closeLocked() int {
+	count := 0
+	if !n.closed {
+		n.closed = true
+		close(n.done)
+		count++
+	}
+	for child := range n.children {
+		count += child.closeLocked()
+	}
+	return count
+}
+
+// Release — метод сообщения о том, что владелец узла завершил работу
+// Узел закрывается и отключается от родителя. Его неосвобожденные потомки
+// переходят к родителю, поэтому предки продолжают их учитывать.
+// Повторные вызовы ничего не делают
+func (n *Node) Release() {
+	n.tree.mutex.Lock()
+	defer n.tree.mutex.Unlock()
+	if n.released {
+		return
+	}
+	n.closeLocked()
+	n.released = true
+
+	parent := n.parent
+	if parent != nil {
+		delete(parent.children, n)
+	}
+	for child := range n.children {
+		child.parent = parent
+		if parent != nil {
+			parent.children[child] = struct{}{}
+		}
+	}
+	n.children = make(map[*Node]struct{})
+
+	close(n.tree.changed)
+	n.tree.changed = make(chan struct{})
+}
+
+// Survivors — метод получения количества неосвобожденных потомков узла
+func (n *Node) Survivors() int {
+	n.tree.mutex.Lock()
+	defer n.tree.mutex.Unlock()
+	return n.survivorsLocked()
+}
+
+// survivorsLocked — метод рекурсивного подсчета неосвобожденных потомков
+func (n *Node) survivorsLocked() int {
+	count := len(n.children)
+	for child := range n.children {
+		count += child.survivorsLocked()
+	}
+	return count
+}
+
+// Wait — метод ожидания освобождения всех потомков узла
+// Сам узел Wait не закрывает — обычно сначала вызывают Close.
+// Возвращает ошибку контекста, если ctx завершился раньше
+func (n *Node) Wait(ctx context.Context) error {
+	for {
+		n.tree.mutex.Lock()
+		survivors := n.survivorsLocked()
+		changed := n.tree.changed
+		n.tree.mutex.Unlock()
+
+		if survivors == 0 {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Shutdown — метод закрытия узла с ожиданием освобождения всех потомков
+// Возвращает количество потомков, не успевших освободиться до завершения ctx
+func (n *Node) Shutdown(ctx context.Context) (survivors int, err error) {
+	n.Close()
+	if err := n.Wait(ctx); err != nil {
+		return n.Survivors(), err
+	}
+	return 0, nil
+}
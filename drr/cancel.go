@@ -0,0 +1,91 @@
+package drr
+
+import (
+	"context"
+	"fmt"
+
+	"goroutines-example/flightrec"
+	"goroutines-example/tasklocal"
+)
+
+// SubmitContext — метод добавления задачи, отменяемой вместе с ctx
+// Если ctx отменен до запуска задачи, она сразу удаляется из очереди
+// арендатора и не занимает в ней место, а не отбрасывается лишь при выборе.
+// Такие задачи учитываются в CanceledBeforeRun. Запущенная задача получает
+// контекст планировщика, как и при Submit, но со значениями tasklocal,
+// заданными в ctx к моменту вызова (идентификатор трассировки, логгер)
+func (s *Scheduler) SubmitContext(ctx context.Context, name string, cost int, tags Tags, fn func(ctx context.Context)) error {
+	if cost <= 0 {
+		return fmt.Errorf("стоимость задачи должна быть положительной: %d", cost)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return ErrClosed
+	}
+	t := s.tenantLocked(name)
+	tags.Tenant = name
+	dequeued := make(chan struct{})
+	s.enqueueLocked(t, task{fn: fn, cost: cost, tags: tags, ctx: ctx, locals: tasklocal.Capture(ctx), dequeued: dequeued, submitted: s.clock.Now()})
+	id := s.nextID
+	s.mutex.Unlock()
+
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				s.mutex.Lock()
+				removed := s.removeLocked(t, id)
+				s.mutex.Unlock()
+				if removed {
+					s.record(flightrec.Cancel, tags)
+				}
+			case <-dequeued:
+			}
+		}()
+	}
+
+	s.record(flightrec.Submit, tags)
+	s.notify()
+	return nil
+}
+
+// removeLocked — метод удаления задачи с номером id из очереди арендатора
+// Возвращает false, если задача уже покинула очередь
+func (s *Scheduler) removeLocked(t *tenant, id uint64) bool {
+	for i, queued := range t.queue {
+		if queued.id != id {
+			continue
+		}
+		copy(t.queue[i:], t.queue[i+1:])
+		t.queue[len(t.queue)-1] = task{}
+		t.queue = t.queue[:len(t.queue)-1]
+		s.queued--
+		s.canceledBeforeRun++
+		close(queued.dequeued)
+
+		if len(t.queue) == 0 && t.active {
+			t.deficit, t.credited, t.active = 0, false, false
+			for j, active := range s.active {
+				if active == t {
+					s.active = append(s.active[:j], s.active[j+1:]...)
+					break
+				}
+			}
+		}
+		s.changedLocked()
+		return true
+	}
+	return false
+}
+
+// CanceledBeforeRun — метод получения количества задач, отмененных до запуска
+func (s *Scheduler) CanceledBeforeRun() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.canceledBeforeRun
+}
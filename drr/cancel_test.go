@@ -0,0 +1,43 @@
+package drr
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"goroutines-example/semaphore"
+	"goroutines-example/tasklocal"
+)
+
+var traceID = tasklocal.NewKey[string]("trace_id")
+
+// TestSubmitContextTaskLocal — задача видит значения tasklocal отправителя,
+// а контекст планировщика после нее их не содержит
+func TestSubmitContextTaskLocal(t *testing.T) {
+	s, err := NewScheduler(semaphore.NewCountingSemaphore(1, time.Second), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := tasklocal.With(context.Background(), traceID, "req-1")
+	var got []string
+	for i := 0; i < 2; i++ {
+		submitCtx := ctx
+		if i == 1 {
+			submitCtx = context.Background()
+		}
+		err := s.SubmitContext(submitCtx, "tenant", 1, Tags{}, func(ctx context.Context) {
+			v, _ := tasklocal.Get(ctx, traceID)
+			got = append(got, v)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Close()
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != "req-1" || got[1] != "" {
+		t.Fatalf("задачи увидели trace_id %q, ожидалось [\"req-1\" \"\"]", got)
+	}
+}
@@ -0,0 +1,455 @@
+package drr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"goroutines-example/cause"
+	"goroutines-example/clock"
+	"goroutines-example/flightrec"
+	"goroutines-example/semaphore"
+	"goroutines-example/tasklocal"
+	"goroutines-example/workload"
+)
+
+// ErrClosed — ошибка добавления задачи в закрытый планировщик
+var ErrClosed = errors.New("планировщик закрыт")
+
+// retryPause — пауза после отказа семафора без подсказки о повторе
+const retryPause = 10 * time.Millisecond
+
+// task — задача арендатора
+type task struct {
+	fn   func(ctx context.Context)
+	cost int
+	tags Tags
+	// Эффективный приоритет: Tags.Priority с учетом наследования (Boost)
+	priority int
+	// Контекст отправителя (может отсутствовать)
+	ctx context.Context
+	// Значения задачи из контекста отправителя
+	locals tasklocal.Values
+	// Номер задачи: порядок постановки и удаление из очереди при отмене ctx
+	id uint64
+	// Закрывается, когда задача покидает очередь (может отсутствовать)
+	dequeued chan struct{}
+	// Момент постановки в очередь
+	submitted time.Time
+}
+
+// tenant — очередь задач одного арендатора
+type tenant struct {
+	name   string
+	weight int
+	// Накопленный, но не израсходованный кредит
+	deficit int
+	// Кредит за текущий обход уже начислен
+	credited bool
+	queue    []task
+	// Арендатор находится в кольце активных
+	active bool
+}
+
+// Scheduler — справедливый планировщик задач нескольких арендаторов
+// Задачи выбираются по алгоритму Deficit Round Robin: при каждом обходе
+// арендатор получает кредит quantum*weight и запускает задачи из своей
+// очереди, пока их стоимость укладывается в кредит. Поэтому арендатор,
+// заваливший планировщик задачами, получает лишь свою долю исполнителей,
+// а остальные не ждут бесконечно. Количество одновременно выполняемых
+// задач ограничивается семафором
+type Scheduler struct {
+	sem *semaphore.CountingSemaphore
+	// Кредит за обход при весе 1
+	quantum int
+
+	// Защита полей ниже
+	mutex   sync.Mutex
+	tenants map[string]*tenant
+	// Кольцо арендаторов с непустыми очередями; обход начинается с active[0]
+	active []*tenant
+	closed bool
+	// Выбор новых задач приостановлен
+	paused bool
+	// Количество выполняющихся задач и сигнал об их отсутствии:
+	// закрывается и заменяется новым, когда running становится нулем
+	running int
+	idle    chan struct{}
+	// Количество задач в очередях всех арендаторов
+	queued int
+	// Закрывается и заменяется новым при запуске и завершении каждой задачи
+	changed chan struct{}
+	// Отмена контекста выполняющихся задач (nil, пока Run не запущен)
+	cancelTasks cause.CancelCauseFunc
+	// Сигнал о новой задаче или снятии паузы для Run (буфер 1)
+	wake chan struct{}
+
+	// Метрики задач по типам
+	metrics metrics
+	// Бортовой самописец (может отсутствовать)
+	recorder *flightrec.Recorder
+
+	// Номер последней поставленной в очередь задачи
+	nextID uint64
+	// Количество задач, отмененных до запуска
+	canceledBeforeRun uint64
+
+	// Запись нагрузки (может отсутствовать) и момент начала записи
+	trace      *workload.TraceWriter
+	traceStart time.Time
+
+	// Источник времени для меток постановки, длительности задач и Replay
+	clock clock.Clock
+}
+
+// NewScheduler — функция создания планировщика
+// sem — семафор, ограничивающий количество одновременно выполняемых задач
+// quantum — кредит за обход для арендатора с весом 1 (в единицах стоимости задач)
+// opts — дополнительные опции (бортовой самописец, запись нагрузки, часы)
+func NewScheduler(sem *semaphore.CountingSemaphore, quantum int, opts ...Option) (*Scheduler, error) {
+	if sem == nil {
+		return nil, fmt.Errorf("семафор не задан")
+	}
+	if quantum <= 0 {
+		return nil, fmt.Errorf("квант должен быть положительным: %d", quantum)
+	}
+	s := &Scheduler{
+		sem:     sem,
+		quantum: quantum,
+		tenants: make(map[string]*tenant),
+		idle:    make(chan struct{}),
+		changed: make(chan struct{}),
+		wake:    make(chan struct{}, 1),
+		metrics: metrics{byName: make(map[string]*taskMetrics)},
+		clock:   clock.Real(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.trace != nil {
+		s.traceStart = s.clock.Now()
+	}
+	return s, nil
+}
+
+// WithClock — опция источника времени планировщика (по умолчанию реальное время)
+func WithClock(c clock.Clock) Option {
+	return func(s *Scheduler) {
+		s.clock = c
+	}
+}
+
+// SetWeight — метод задания веса арендатора (по умолчанию 1)
+func (s *Scheduler) SetWeight(name string, weight int) error {
+	if weight <= 0 {
+		return fmt.Errorf("вес арендатора должен быть положительным: %d", weight)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.tenantLocked(name).weight = weight
+	return nil
+}
+
+// tenantLocked — метод получения арендатора с созданием при необходимости
+func (s *Scheduler) tenantLocked(name string) *tenant {
+	t, ok := s.tenants[name]
+	if !ok {
+		t = &tenant{name: name, weight: 1}
+		s.tenants[name] = t
+	}
+	return t
+}
+
+// Submit — метод добавления задачи арендатора
+// cost — стоимость задачи (например, ожидаемое время или объем данных);
+// задачи с большей стоимостью расходуют больше кредита
+func (s *Scheduler) Submit(name string, cost int, fn func(ctx context.Context)) error {
+	return s.SubmitTagged(name, cost, Tags{}, fn)
+}
+
+// SubmitTagged — метод добавления задачи арендатора с метками
+// Поле Tenant меток заполняется именем арендатора
+func (s *Scheduler) SubmitTagged(name string, cost int, tags Tags, fn func(ctx context.Context)) error {
+	if cost <= 0 {
+		return fmt.Errorf("стоимость задачи должна быть положительной: %d", cost)
+	}
+
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return ErrClosed
+	}
+	t := s.tenantLocked(name)
+	tags.Tenant = name
+	s.enqueueLocked(t, task{fn: fn, cost: cost, tags: tags, submitted: s.clock.Now()})
+	s.mutex.Unlock()
+
+	s.record(flightrec.Submit, tags)
+	s.notify()
+	return nil
+}
+
+// notify — метод пробуждения Run
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// nextLocked — метод выбора следующей задачи по алгоритму DRR
+// Возвращает false, если очереди всех арендаторов пусты
+func (s *Scheduler) nextLocked() (task, bool) {
+	for len(s.active) > 0 {
+		t := s.active[0]
+		if !t.credited {
+			t.deficit += s.quantum * t.weight
+			t.credited = true
+		}
+		head := t.queue[0]
+		if head.ctx != nil && head.ctx.Err() != nil {
+			// Контекст отменен, а наблюдатель еще не успел убрать задачу
+			s.removeLocked(t, head.id)
+			continue
+		}
+		if head.cost <= t.deficit {
+			t.queue[0] = task{}
+			t.queue = t.queue[1:]
+			t.deficit -= head.cost
+			s.queued--
+			if len(t.queue) == 0 {
+				// Опустевшая очередь не копит кредит впрок
+				t.deficit, t.credited, t.active = 0, false, false
+				s.active = s.active[1:]
+			}
+			if head.dequeued != nil {
+				close(head.dequeued)
+			}
+			return head, true
+		}
+		// Кредита не хватает — ход переходит к следующему арендатору
+		t.credited = false
+		s.active = append(s.active[1:], t)
+	}
+	return task{}, false
+}
+
+// Run — метод выполнения задач до отмены ctx или закрытия планировщика
+// Задача выбирается только после получения разрешения семафора, поэтому
+// очередность определяется алгоритмом DRR, а не порядком добавления.
+// Перед возвратом Run дожидается завершения запущенных задач
+func (s *Scheduler) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// Контекст задач отменяется отдельно от ctx при эскалации Shutdown
+	taskCtx, cancelTasks := cause.WithCancelCause(ctx)
+	defer cancelTasks(nil)
+	s.mutex.Lock()
+	s.cancelTasks = cancelTasks
+	s.mutex.Unlock()
+
+	for {
+		// Таймаут семафора здесь не нужен: ждем разрешение до отмены ctx
+		if err := s.sem.AcquireNUntil(ctx, 1); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// Отказ без ожидания (выключатель, переполненная очередь) —
+			// пауза по подсказке семафора, чтобы не крутить цикл впустую
+			if err := s.sem.SleepRetryAfter(ctx, err, retryPause); err != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+
+		var (
+			t  task
+			ok bool
+		)
+		for {
+			s.mutex.Lock()
+			if !s.paused {
+				t, ok = s.nextLocked()
+				if ok {
+					s.running++
+					s.changedLocked()
+				}
+			}
+			closed := s.closed
+			s.mutex.Unlock()
+			if ok {
+				break
+			}
+			if closed {
+				s.sem.Release()
+				return nil
+			}
+			select {
+			case <-s.wake:
+			case <-ctx.Done():
+				s.sem.Release()
+				return ctx.Err()
+			}
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer s.finished()
+			s.execute(taskCtx, t)
+		}()
+	}
+}
+
+// finished — метод учета завершения задачи
+func (s *Scheduler) finished() {
+	s.sem.Release()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.running--
+	s.changedLocked()
+	if s.running == 0 {
+		close(s.idle)
+		s.idle = make(chan struct{})
+	}
+}
+
+// changedLocked — метод оповещения об изменении количества задач
+func (s *Scheduler) changedLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Pause — метод приостановки выбора новых задач
+// Задачи продолжают приниматься в очереди, а уже запущенные — выполняться.
+// Pause дожидается завершения запущенных задач (например, перед
+// развертыванием или при срабатывании автоматического выключателя
+// зависимости); при отмене ctx возвращает ошибку, но пауза остается в силе
+func (s *Scheduler) Pause(ctx context.Context) error {
+	s.mutex.Lock()
+	s.paused = true
+	running, idle := s.running, s.idle
+	s.mutex.Unlock()
+
+	if running == 0 {
+		return nil
+	}
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Resume — метод возобновления выбора задач после паузы
+func (s *Scheduler) Resume() {
+	s.mutex.Lock()
+	s.paused = false
+	s.mutex.Unlock()
+
+	s.notify()
+}
+
+// Close — метод закрытия планировщика
+// Новые задачи не принимаются; Run завершается, выполнив уже добавленные.
+// На паузе Run завершается, не дожидаясь снятия паузы
+func (s *Scheduler) Close() {
+	s.mutex.Lock()
+	s.closed = true
+	s.mutex.Unlock()
+
+	s.notify()
+}
+
+// ShutdownProgress — состояние планировщика во время завершения
+type ShutdownProgress struct {
+	// Задачи в очередях, еще не запущенные
+	Queued int
+	// Выполняющиеся задачи
+	Running int
+}
+
+// ShutdownSummary — итог завершения планировщика
+type ShutdownSummary struct {
+	// Задачи, оставшиеся в очередях и так и не запущенные
+	Abandoned int
+	// Выполнявшиеся задачи, контекст которых был отменен
+	Cancelled int
+}
+
+// Shutdown — метод плавного завершения планировщика
+// Закрывает планировщик для новых задач, снимает паузу и ждет, пока Run
+// выполнит уже добавленные задачи, отправляя в progress (если не nil)
+// состояние при каждом запуске и завершении задачи. Медленный читатель
+// пропускает промежуточные состояния, а не задерживает завершение.
+// Если ctx отменен раньше, задачи в очередях отбрасываются, контекст
+// выполняющихся задач отменяется с причиной cause.ErrShutdown, и
+// возвращается итог с количеством брошенных задач вместе с ошибкой ctx
+func (s *Scheduler) Shutdown(ctx context.Context, progress chan<- ShutdownProgress) (ShutdownSummary, error) {
+	s.mutex.Lock()
+	s.closed = true
+	s.paused = false
+	s.mutex.Unlock()
+	s.notify()
+
+	for {
+		s.mutex.Lock()
+		p := ShutdownProgress{Queued: s.queued, Running: s.running}
+		changed := s.changed
+		s.mutex.Unlock()
+
+		if progress != nil {
+			select {
+			case progress <- p:
+			default:
+			}
+		}
+		if p.Queued == 0 && p.Running == 0 {
+			return ShutdownSummary{}, nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return s.abandon(), ctx.Err()
+		}
+	}
+}
+
+// abandon — метод отбрасывания очередей и отмены выполняющихся задач
+func (s *Scheduler) abandon() ShutdownSummary {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	summary := ShutdownSummary{Abandoned: s.queued, Cancelled: s.running}
+	for _, t := range s.tenants {
+		for _, queued := range t.queue {
+			if queued.dequeued != nil {
+				close(queued.dequeued)
+			}
+		}
+		t.queue = nil
+		t.deficit, t.credited, t.active = 0, false, false
+	}
+	s.active = nil
+	s.queued = 0
+	if s.cancelTasks != nil {
+		s.cancelTasks(cause.ErrShutdown)
+	}
+	return summary
+}
+
+// Pending — метод получения количества ожидающих задач арендатора
+func (s *Scheduler) Pending(name string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if t, ok := s.tenants[name]; ok {
+		return len(t.queue)
+	}
+	return 0
+}
@@ -0,0 +1,293 @@
+package drr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"goroutines-example/cause"
+	"goroutines-example/clock"
+	"goroutines-example/semaphore"
+	"goroutines-example/testsync"
+	"goroutines-example/workload"
+)
+
+// newScheduler — функция создания планировщика с семафором на permits разрешений
+func newScheduler(t *testing.T, permits, quantum int, opts ...Option) *Scheduler {
+	t.Helper()
+	s, err := NewScheduler(semaphore.NewCountingSemaphore(permits, time.Second), quantum, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// runAsync — функция запуска Run в отдельной горутине
+// Возвращает канал с результатом Run
+func runAsync(s *Scheduler) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- s.Run(context.Background()) }()
+	return done
+}
+
+// TestDRRFairness — арендаторы получают исполнителей пропорционально
+// весам, а дорогие задачи расходуют больше кредита
+func TestDRRFairness(t *testing.T) {
+	tests := []struct {
+		name    string
+		quantum int
+		weights map[string]int
+		// Задачи в порядке постановки: арендатор и стоимость
+		tasks []struct {
+			tenant string
+			cost   int
+			count  int
+		}
+		want string
+	}{
+		{
+			name:    "weights",
+			quantum: 1,
+			weights: map[string]int{"a": 3},
+			tasks: []struct {
+				tenant string
+				cost   int
+				count  int
+			}{{"a", 1, 8}, {"b", 1, 8}},
+			// Пока у a есть задачи, на каждую задачу b приходится три задачи a
+			want: "aaabaaabaabbbbbb",
+		},
+		{
+			name:    "cost",
+			quantum: 2,
+			tasks: []struct {
+				tenant string
+				cost   int
+				count  int
+			}{{"a", 2, 3}, {"b", 1, 6}},
+			// Задача a стоимостью 2 расходует кредит обхода целиком
+			want: "abbabbabb",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScheduler(t, 1, tt.quantum)
+			for name, w := range tt.weights {
+				if err := s.SetWeight(name, w); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var mu sync.Mutex
+			var order strings.Builder
+			for _, group := range tt.tasks {
+				tenant := group.tenant
+				for i := 0; i < group.count; i++ {
+					err := s.Submit(tenant, group.cost, func(context.Context) {
+						mu.Lock()
+						order.WriteString(tenant)
+						mu.Unlock()
+					})
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			s.Close()
+			if err := s.Run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got := order.String(); got != tt.want {
+				t.Fatalf("порядок %s, ожидался %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPauseResume — на паузе задачи копятся в очереди и запускаются после Resume,
+// а Pause дожидается завершения уже запущенных
+func TestPauseResume(t *testing.T) {
+	s := newScheduler(t, 1, 1)
+	done := runAsync(s)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	if err := s.Submit("t", 1, func(context.Context) {
+		close(started)
+		<-release
+	}); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	// Отмененный ctx не снимает паузу: Pause сразу возвращает ошибку,
+	// но новые задачи уже не выбираются
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.Pause(canceled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Pause с отмененным ctx при выполняющейся задаче: %v", err)
+	}
+	ran := make(chan struct{})
+	if err := s.Submit("t", 1, func(context.Context) { close(ran) }); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	if err := s.Pause(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.Pending("t"); n != 1 {
+		t.Fatalf("на паузе в очереди %d задач, ожидалась 1", n)
+	}
+
+	s.Resume()
+	<-ran
+	s.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestShutdownAbandons — при отмене ctx Shutdown отбрасывает очереди
+// и отменяет выполняющиеся задачи с причиной cause.ErrShutdown
+func TestShutdownAbandons(t *testing.T) {
+	s := newScheduler(t, 1, 1)
+	done := runAsync(s)
+
+	started := make(chan struct{})
+	causes := make(chan error, 1)
+	if err := s.Submit("t", 1, func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		causes <- cause.Cause(ctx)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	for i := 0; i < 2; i++ {
+		if err := s.Submit("t", 1, func(context.Context) { t.Error("брошенная задача запущена") }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	progress := make(chan ShutdownProgress, 1)
+	summary, err := s.Shutdown(ctx, progress)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Shutdown вернул %v, ожидалась отмена", err)
+	}
+	if p := <-progress; p != (ShutdownProgress{Queued: 2, Running: 1}) {
+		t.Fatalf("первое состояние %+v", p)
+	}
+	if summary != (ShutdownSummary{Abandoned: 2, Cancelled: 1}) {
+		t.Fatalf("итог %+v", summary)
+	}
+	if c := <-causes; !errors.Is(c, cause.ErrShutdown) {
+		t.Fatalf("причина отмены задачи %v, ожидалась ErrShutdown", c)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Submit("t", 1, func(context.Context) {}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Submit после Shutdown: %v", err)
+	}
+}
+
+// TestSubmitContextRemovedOnCancel — задача с отмененным ctx покидает
+// очередь сразу, не дожидаясь выбора
+func TestSubmitContextRemovedOnCancel(t *testing.T) {
+	s := newScheduler(t, 1, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := s.SubmitContext(ctx, "t", 1, Tags{}, func(context.Context) { t.Error("отмененная задача запущена") }); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.Pending("t"); n != 1 {
+		t.Fatalf("в очереди %d задач, ожидалась 1", n)
+	}
+	cancel()
+	testsync.AwaitCondition(t, func() bool { return s.Pending("t") == 0 }, time.Second)
+	if n := s.CanceledBeforeRun(); n != 1 {
+		t.Fatalf("CanceledBeforeRun = %d, ожидалось 1", n)
+	}
+	if err := s.SubmitContext(ctx, "t", 1, Tags{}, func(context.Context) {}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("SubmitContext с отмененным ctx: %v", err)
+	}
+
+	s.Close()
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestReplayFakeClock — воспроизведение ставит задачи в записанные моменты
+// виртуального времени, а метрики и новая запись отражают их длительность
+func TestReplayFakeClock(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	var buf bytes.Buffer
+	trace := workload.NewTraceWriter(&buf)
+	s := newScheduler(t, 3, 1, WithClock(fake), WithTrace(trace))
+	done := runAsync(s)
+
+	events := []workload.Event{
+		{At: 0, Hold: 20 * time.Millisecond, Tenant: "a", Tags: map[string]string{"name": "job"}},
+		{At: 30 * time.Millisecond, Hold: 20 * time.Millisecond, Tenant: "a", Tags: map[string]string{"name": "job"}},
+		{At: 60 * time.Millisecond, Hold: 20 * time.Millisecond, Tenant: "b", Tags: map[string]string{"name": "job"}},
+	}
+	replayed := make(chan error, 1)
+	go func() { replayed <- s.Replay(context.Background(), events, 1) }()
+
+	// Часы продвигаются только после того, как задача учла свою
+	// длительность, иначе она измерит и следующий шаг
+	completed := func(n uint64) {
+		testsync.AwaitCondition(t, func() bool {
+			stats := s.Stats()
+			return len(stats) == 1 && stats[0].Completed == n
+		}, time.Second)
+	}
+	// t=0: первая задача удерживает 20ms, воспроизведение ждет t=30ms
+	fake.BlockUntil(2)
+	fake.Advance(20 * time.Millisecond)
+	completed(1)
+	fake.Advance(10 * time.Millisecond)
+	// t=30ms: вторая задача до t=50ms, воспроизведение ждет t=60ms
+	fake.BlockUntil(2)
+	fake.Advance(20 * time.Millisecond)
+	completed(2)
+	fake.Advance(10 * time.Millisecond)
+	if err := <-replayed; err != nil {
+		t.Fatal(err)
+	}
+	// t=60ms: третья задача до t=80ms
+	fake.BlockUntil(1)
+	fake.Advance(20 * time.Millisecond)
+	completed(3)
+
+	if _, err := s.Shutdown(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	stats := s.Stats()
+	if len(stats) != 1 || stats[0].Latency.Mean != 20*time.Millisecond || stats[0].Latency.Max != 20*time.Millisecond {
+		t.Fatalf("метрики %+v", stats)
+	}
+	if err := trace.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := workload.ReadTrace(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(events) {
+		t.Fatalf("записано %d событий, ожидалось %d", len(got), len(events))
+	}
+	for i, e := range got {
+		if e.At != events[i].At || e.Hold != events[i].Hold || e.Tenant != events[i].Tenant || e.Tags["name"] != "job" {
+			t.Fatalf("событие %d записано как %+v, ожидалось %+v", i, e, events[i])
+		}
+	}
+}
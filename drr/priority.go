@@ -0,0 +1,66 @@
+package drr
+
+import "sort"
+
+// LockLabel — метка задачи с ключом блокировки для наследования приоритета
+// Задачи в очередях с Labels[LockLabel] == key повышает Boost(key, ...)
+const LockLabel = "lock"
+
+// enqueueLocked — метод постановки задачи в очередь арендатора
+// Очередь упорядочена по эффективному приоритету, а при равенстве — по
+// порядку постановки. DRR по-прежнему делит исполнителей между арендаторами,
+// а приоритет определяет лишь порядок задач внутри одного арендатора
+func (s *Scheduler) enqueueLocked(t *tenant, tk task) {
+	s.nextID++
+	tk.id = s.nextID
+	tk.priority = tk.tags.Priority
+	i := sort.Search(len(t.queue), func(i int) bool { return t.queue[i].priority < tk.priority })
+	t.queue = append(t.queue, task{})
+	copy(t.queue[i+1:], t.queue[i:])
+	t.queue[i] = tk
+
+	s.queued++
+	if !t.active {
+		t.active = true
+		s.active = append(s.active, t)
+	}
+}
+
+// Boost — метод наследования приоритета задачами с ключом блокировки key
+// Задачи в очередях с Labels[LockLabel] == key получают эффективный приоритет
+// max(Tags.Priority, priority) и переставляются в очереди арендатора; меньшее
+// значение возвращает их к собственному приоритету. Задачи, добавленные
+// позже, получают собственный приоритет. Метод подходит как обработчик
+// повышения keyed.NewPriorityMutex: работа держателя ключа, стоящая в
+// очереди, обгоняет задачи со средним приоритетом, пока ключа ждет задача
+// с высоким (защита от инверсии приоритетов)
+func (s *Scheduler) Boost(key string, priority int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, t := range s.tenants {
+		changed := false
+		for i := range t.queue {
+			tk := &t.queue[i]
+			if tk.tags.Labels[LockLabel] != key {
+				continue
+			}
+			effective := tk.tags.Priority
+			if priority > effective {
+				effective = priority
+			}
+			if tk.priority != effective {
+				tk.priority = effective
+				changed = true
+			}
+		}
+		if changed {
+			sort.SliceStable(t.queue, func(i, j int) bool {
+				if t.queue[i].priority != t.queue[j].priority {
+					return t.queue[i].priority > t.queue[j].priority
+				}
+				return t.queue[i].id < t.queue[j].id
+			})
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package drr
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"goroutines-example/keyed"
+	"goroutines-example/semaphore"
+)
+
+// runOrder — функция выполнения всех поставленных задач по одной
+// и получения порядка их запуска
+func runOrder(t *testing.T, s *Scheduler, order *[]string) []string {
+	t.Helper()
+	s.Close()
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return *order
+}
+
+// submitNamed — функция постановки задачи, записывающей свое имя при запуске
+func submitNamed(t *testing.T, s *Scheduler, mu *sync.Mutex, order *[]string, name string, tags Tags) {
+	t.Helper()
+	tags.Name = name
+	err := s.SubmitTagged("tenant", 1, tags, func(ctx context.Context) {
+		mu.Lock()
+		*order = append(*order, name)
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestPriorityOrder — внутри арендатора задачи идут по приоритету,
+// при равенстве — по порядку постановки
+func TestPriorityOrder(t *testing.T) {
+	s, err := NewScheduler(semaphore.NewCountingSemaphore(1, time.Second), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var order []string
+	submitNamed(t, s, &mu, &order, "low", Tags{Priority: 1})
+	submitNamed(t, s, &mu, &order, "mid1", Tags{Priority: 5})
+	submitNamed(t, s, &mu, &order, "high", Tags{Priority: 9})
+	submitNamed(t, s, &mu, &order, "mid2", Tags{Priority: 5})
+
+	want := []string{"high", "mid1", "mid2", "low"}
+	if got := runOrder(t, s, &order); !reflect.DeepEqual(got, want) {
+		t.Fatalf("порядок %v, ожидался %v", got, want)
+	}
+}
+
+// TestBoostFromPriorityMutex — пока ключа ждет задача с высоким приоритетом,
+// работа держателя обгоняет задачи со средним приоритетом; когда ожидающий
+// уходит, она возвращается на свое место
+func TestBoostFromPriorityMutex(t *testing.T) {
+	for _, waiting := range []bool{true, false} {
+		s, err := NewScheduler(semaphore.NewCountingSemaphore(1, time.Second), 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := keyed.NewPriorityMutex(s.Boost)
+		var mu sync.Mutex
+		var order []string
+		submitNamed(t, s, &mu, &order, "mid", Tags{Priority: 5})
+		submitNamed(t, s, &mu, &order, "holder", Tags{Priority: 1, Labels: map[string]string{LockLabel: "k"}})
+
+		lease, err := m.Lock(context.Background(), "k", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			_, err := m.Lock(ctx, "k", 10)
+			done <- err
+		}()
+		for lease.Priority() != 10 {
+			time.Sleep(time.Millisecond)
+		}
+		if !waiting {
+			cancel()
+			if err := <-done; err == nil {
+				t.Fatal("ожидалась отмена захвата")
+			}
+		}
+
+		want := []string{"holder", "mid"}
+		if !waiting {
+			want = []string{"mid", "holder"}
+		}
+		if got := runOrder(t, s, &order); !reflect.DeepEqual(got, want) {
+			t.Fatalf("ожидающий=%v: порядок %v, ожидался %v", waiting, got, want)
+		}
+		cancel()
+		if waiting {
+			lease.Unlock()
+			<-done
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package drr
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"goroutines-example/flightrec"
+	"goroutines-example/latency"
+)
+
+// Tags — метаданные задачи
+// Попадают в метрики планировщика, в сообщение о панике задачи,
+// в бортовой самописец и в контекст задачи (см. TagsFromContext)
+type Tags struct {
+	// Тип задачи; по нему ведутся метрики
+	Name string
+	// Арендатор (заполняется планировщиком)
+	Tenant string
+	// Приоритет задачи: порядок внутри очереди арендатора (больше — раньше)
+	Priority int
+	// Произвольные метки
+	Labels map[string]string
+}
+
+// String — метод форматирования меток вида name=... tenant=... priority=...
+func (t Tags) String() string {
+	parts := []string{"name=" + t.Name, "tenant=" + t.Tenant, fmt.Sprintf("priority=%d", t.Priority)}
+	keys := make([]string, 0, len(t.Labels))
+	for k := range t.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts = append(parts, k+"="+t.Labels[k])
+	}
+	return strings.Join(parts, " ")
+}
+
+// tagsKey — ключ контекста для меток задачи
+type tagsKey struct{}
+
+// TagsFromContext — функция получения меток выполняющейся задачи
+func TagsFromContext(ctx context.Context) (Tags, bool) {
+	t, ok := ctx.Value(tagsKey{}).(Tags)
+	return t, ok
+}
+
+// PanicError — паника задачи вместе с ее метками
+type PanicError struct {
+	Tags  Tags
+	Value any
+	Stack []byte
+}
+
+// Error — метод получения текста ошибки
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("паника в задаче [%s]: %v\n%s", e.Tags, e.Value, e.Stack)
+}
+
+// PanicHandler — обработчик паники задачи
+// Паника не роняет процесс: она перехватывается, учитывается в метриках
+// и передается сюда. По умолчанию сообщение выводится в stderr
+var PanicHandler = func(err *PanicError) {
+	fmt.Fprintln(os.Stderr, err)
+}
+
+// TaskStats — метрики задач одного типа
+type TaskStats struct {
+	Name      string
+	Completed uint64
+	Panics    uint64
+	Latency   latency.Snapshot
+}
+
+// taskMetrics — накопленные метрики задач одного типа
+type taskMetrics struct {
+	completed uint64
+	panics    uint64
+	latency   *latency.Recorder
+}
+
+// metrics — метрики задач по типам
+type metrics struct {
+	// Защита byName
+	mutex  sync.Mutex
+	byName map[string]*taskMetrics
+}
+
+// observe — метод учета завершения задачи
+func (m *metrics) observe(name string, d time.Duration, panicked bool) {
+	m.mutex.Lock()
+	tm, ok := m.byName[name]
+	if !ok {
+		tm = &taskMetrics{latency: latency.NewRecorder()}
+		m.byName[name] = tm
+	}
+	tm.completed++
+	if panicked {
+		tm.panics++
+	}
+	m.mutex.Unlock()
+
+	tm.latency.Record(d)
+}
+
+// Option — функциональная опция планировщика
+type Option func(*Scheduler)
+
+// WithFlightRecorder — опция записи постановки и завершения задач в самописец
+// Метки задачи записываются в поле Tags события
+func WithFlightRecorder(r *flightrec.Recorder) Option {
+	return func(s *Scheduler) {
+		s.recorder = r
+	}
+}
+
+// record — метод записи события задачи в самописец, если он подключен
+func (s *Scheduler) record(kind flightrec.Kind, tags Tags) {
+	if s.recorder == nil {
+		return
+	}
+	s.recorder.Record(flightrec.Event{
+		Kind:      kind,
+		Source:    tags.Tenant,
+		Count:     1,
+		Available: -1,
+		Tags:      tags.String(),
+	})
+}
+
+// execute — метод выполнения задачи с учетом метрик и перехватом паники
+func (s *Scheduler) execute(ctx context.Context, t task) {
+	start := s.clock.Now()
+	panicked := true
+	defer func() {
+		if panicked {
+			PanicHandler(&PanicError{Tags: t.tags, Value: recover(), Stack: debug.Stack()})
+		}
+		hold := s.clock.Since(start)
+		s.metrics.observe(t.tags.Name, hold, panicked)
+		s.record(flightrec.Complete, t.tags)
+		s.traceTask(t, hold)
+	}()
+
+	t.fn(context.WithValue(t.locals.Bind(ctx), tagsKey{}, t.tags))
+	panicked = false
+}
+
+// Stats — метод получения метрик по типам задач, упорядоченных по имени
+func (s *Scheduler) Stats() []TaskStats {
+	s.metrics.mutex.Lock()
+	defer s.metrics.mutex.Unlock()
+
+	out := make([]TaskStats, 0, len(s.metrics.byName))
+	for name, tm := range s.metrics.byName {
+		out = append(out, TaskStats{
+			Name:      name,
+			Completed: tm.completed,
+			Panics:    tm.panics,
+			Latency:   tm.latency.Snapshot(),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
@@ -0,0 +1,83 @@
+package drr
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"goroutines-example/clock"
+	"goroutines-example/workload"
+)
+
+// WithTrace — опция записи нагрузки планировщика
+// Каждая выполненная задача записывается в w с моментом постановки в
+// очередь (относительно создания планировщика), временем выполнения,
+// арендатором, стоимостью и метками. Запись воспроизводится через Replay
+// против планировщика с другими настройками или анализируется cmd/sim
+func WithTrace(w *workload.TraceWriter) Option {
+	return func(s *Scheduler) {
+		s.trace = w
+	}
+}
+
+// traceTask — метод записи выполненной задачи, если запись включена
+func (s *Scheduler) traceTask(t task, hold time.Duration) {
+	if s.trace == nil {
+		return
+	}
+	s.trace.Write(workload.Event{
+		At:     t.submitted.Sub(s.traceStart),
+		Hold:   hold,
+		Tenant: t.tags.Tenant,
+		Cost:   t.cost,
+		Tags:   eventTags(t.tags),
+	})
+}
+
+// eventTags — функция представления меток задачи в записи нагрузки
+func eventTags(t Tags) map[string]string {
+	out := make(map[string]string, len(t.Labels)+2)
+	for k, v := range t.Labels {
+		out[k] = v
+	}
+	if t.Name != "" {
+		out["name"] = t.Name
+	}
+	if t.Priority != 0 {
+		out["priority"] = strconv.Itoa(t.Priority)
+	}
+	return out
+}
+
+// tagsFromEvent — функция восстановления меток задачи из записи нагрузки
+func tagsFromEvent(e workload.Event) Tags {
+	tags := Tags{Name: e.Tags["name"], Labels: make(map[string]string)}
+	tags.Priority, _ = strconv.Atoi(e.Tags["priority"])
+	for k, v := range e.Tags {
+		if k != "name" && k != "priority" {
+			tags.Labels[k] = v
+		}
+	}
+	return tags
+}
+
+// Replay — метод воспроизведения записанной нагрузки в планировщике
+// Задачи ставятся в очереди своих арендаторов в моменты из записи,
+// пересчитанные со скоростью speed (2 — вдвое быстрее), и выполняются
+// ожиданием записанного времени, тоже пересчитанного. Для проверки
+// производительности настроек конкурентности: та же нагрузка
+// воспроизводится против планировщика с другими весами, квантом или
+// размером семафора, а результат сравнивается по Stats. Возвращается
+// после постановки всех задач; их завершения ждут через Shutdown
+func (s *Scheduler) Replay(ctx context.Context, events []workload.Event, speed float64) error {
+	return workload.Replay(ctx, events, speed, s.clock, func(e workload.Event) error {
+		cost := e.Cost
+		if cost <= 0 {
+			cost = 1
+		}
+		hold := workload.Scale(e.Hold, speed)
+		return s.SubmitTagged(e.Tenant, cost, tagsFromEvent(e), func(ctx context.Context) {
+			clock.Sleep(ctx, s.clock, hold)
+		})
+	})
+}
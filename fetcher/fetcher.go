@@ -0,0 +1,409 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"goroutines-example/cause"
+	"goroutines-example/clock"
+	"goroutines-example/keyed"
+	"goroutines-example/ratelimit"
+	"goroutines-example/semaphore"
+)
+
+// FetchFunc — функция загрузки страницы
+// Возвращает тело и абсолютные ссылки, найденные на странице
+type FetchFunc func(ctx context.Context, rawURL string) (body []byte, links []string, err error)
+
+// Page — загруженная страница
+type Page struct {
+	URL string
+	// Глубина от начальных адресов (у них 0)
+	Depth int
+	Body  []byte
+	Links []string
+	// Количество попыток загрузки
+	Attempts int
+}
+
+// Handler — функция обработки загруженной страницы
+// Ошибка обработчика считается неудачей страницы: ее ссылки не обходятся
+type Handler func(ctx context.Context, page Page) error
+
+// Stats — статистика обхода
+type Stats struct {
+	// Успешно загружено и обработано страниц
+	Fetched uint64
+	// Страниц, загрузка или обработка которых не удалась
+	Failed uint64
+	// Отброшено повторно встреченных адресов
+	Duplicates uint64
+	// Отброшено адресов сверх WithMaxPages или глубже WithMaxDepth
+	Skipped uint64
+	// Повторных попыток загрузки
+	Retries uint64
+}
+
+// Option — функция настройки обходчика
+type Option func(*Fetcher)
+
+// WithConcurrency — опция общего количества одновременных загрузок
+// (по умолчанию 8)
+func WithConcurrency(n int) Option {
+	return func(f *Fetcher) { f.concurrency = n }
+}
+
+// WithPerHost — опция количества одновременных загрузок с одного хоста
+// (по умолчанию 2). Освободившиеся слоты раздаются хостам по кругу
+func WithPerHost(n int) Option {
+	return func(f *Fetcher) { f.perHost = n }
+}
+
+// WithHostRate — опция ограничения частоты запросов к одному хосту
+// rate — запросов в секунду, burst — допустимый всплеск. Без опции
+// частота ограничивается только количеством одновременных загрузок
+func WithHostRate(rate float64, burst int) Option {
+	return func(f *Fetcher) {
+		f.hostRate = rate
+		f.hostBurst = burst
+	}
+}
+
+// WithSemaphore — опция общего семафора, разрешение которого берется на
+// каждую загрузку (например, общий для нескольких обходчиков бюджет)
+func WithSemaphore(sem semaphore.Semaphore) Option {
+	return func(f *Fetcher) { f.sem = sem }
+}
+
+// WithRetries — опция повторных попыток загрузки
+// n — количество повторов, backoff — пауза перед первым повтором,
+// удваиваемая с каждым следующим до maxBackoff
+func WithRetries(n int, backoff, maxBackoff time.Duration) Option {
+	return func(f *Fetcher) {
+		f.retries = n
+		f.backoff = backoff
+		f.maxBackoff = maxBackoff
+	}
+}
+
+// WithMaxDepth — опция максимальной глубины обхода (0 — только начальные адреса)
+// По умолчанию глубина не ограничена
+func WithMaxDepth(depth int) Option {
+	return func(f *Fetcher) { f.maxDepth = depth }
+}
+
+// WithMaxPages — опция максимального количества адресов, принятых в обход
+func WithMaxPages(n int) Option {
+	return func(f *Fetcher) { f.maxPages = n }
+}
+
+// WithOnError — опция функции, получающей неудачные адреса
+// Вызывается из горутин обхода и должна быть потокобезопасной
+func WithOnError(fn func(rawURL string, err error)) Option {
+	return func(f *Fetcher) { f.onError = fn }
+}
+
+// WithClock — опция источника времени
+func WithClock(c clock.Clock) Option {
+	return func(f *Fetcher) { f.clock = c }
+}
+
+// Fetcher — обходчик с ограничениями вежливости
+// Адреса берутся из общей очереди (frontier) с подавлением дубликатов.
+// Каждая загрузка занимает слот хоста и общий слот (keyed.FairLimiter),
+// токен частоты хоста (ratelimit.Limiter) и, если задан, разрешение
+// общего семафора; неудачные загрузки повторяются с нарастающей паузой.
+// Обходчик можно запускать повторно; очередь и статистика у каждого
+// запуска свои
+type Fetcher struct {
+	fetch       FetchFunc
+	concurrency int
+	perHost     int
+	hostRate    float64
+	hostBurst   int
+	sem         semaphore.Semaphore
+	retries     int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	maxDepth    int
+	maxPages    int
+	onError     func(rawURL string, err error)
+	clock       clock.Clock
+}
+
+// New — функция создания обходчика
+func New(fetch FetchFunc, opts ...Option) (*Fetcher, error) {
+	if fetch == nil {
+		return nil, fmt.Errorf("функция загрузки не задана")
+	}
+	f := &Fetcher{
+		fetch:       fetch,
+		concurrency: 8,
+		perHost:     2,
+		maxDepth:    -1,
+		clock:       clock.Real(),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	if f.concurrency <= 0 || f.perHost <= 0 {
+		return nil, fmt.Errorf("лимиты загрузок должны быть положительными: общий %d, на хост %d", f.concurrency, f.perHost)
+	}
+	if f.hostRate < 0 || (f.hostRate > 0 && f.hostBurst <= 0) {
+		return nil, fmt.Errorf("некорректная частота запросов к хосту: %v в секунду, всплеск %d", f.hostRate, f.hostBurst)
+	}
+	if f.retries < 0 {
+		return nil, fmt.Errorf("количество повторов не может быть отрицательным: %d", f.retries)
+	}
+	return f, nil
+}
+
+// item — адрес в очереди обхода
+type item struct {
+	url   string
+	depth int
+}
+
+// crawl — состояние одного запуска обхода
+type crawl struct {
+	*Fetcher
+	handle Handler
+	hosts  *keyed.FairLimiter
+
+	// Защита очереди, множества адресов и ограничителей хостов
+	mutex sync.Mutex
+	queue []item
+	seen  map[string]struct{}
+	// Количество адресов, взятых из очереди и еще не обработанных
+	active int
+	// Закрывается и заменяется при изменении очереди
+	changed chan struct{}
+	// Ограничители частоты по хостам
+	rates map[string]*ratelimit.Limiter
+
+	fetched    atomic.Uint64
+	failed     atomic.Uint64
+	duplicates atomic.Uint64
+	skipped    atomic.Uint64
+	retried    atomic.Uint64
+}
+
+// Run — метод обхода, начиная с seeds
+// Возвращает управление, когда очередь опустела и все загрузки завершены,
+// или после отмены ctx (тогда возвращается ошибка ctx). Неудачи отдельных
+// страниц не прерывают обход: они учитываются в Stats и передаются WithOnError
+func (f *Fetcher) Run(ctx context.Context, seeds []string, handle Handler) (Stats, error) {
+	hosts, err := keyed.NewFairLimiter(f.concurrency, f.perHost)
+	if err != nil {
+		return Stats{}, err
+	}
+	c := &crawl{
+		Fetcher: f,
+		handle:  handle,
+		hosts:   hosts,
+		seen:    make(map[string]struct{}),
+		changed: make(chan struct{}),
+		rates:   make(map[string]*ratelimit.Limiter),
+	}
+	c.push(seeds, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < f.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				it, ok := c.pop(ctx)
+				if !ok {
+					return
+				}
+				c.process(ctx, it)
+				c.done()
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := Stats{
+		Fetched:    c.fetched.Load(),
+		Failed:     c.failed.Load(),
+		Duplicates: c.duplicates.Load(),
+		Skipped:    c.skipped.Load(),
+		Retries:    c.retried.Load(),
+	}
+	if ctx.Err() != nil {
+		return stats, cause.Err(ctx)
+	}
+	return stats, nil
+}
+
+// push — метод добавления адресов глубины depth в очередь
+func (c *crawl) push(urls []string, depth int) {
+	if c.maxDepth >= 0 && depth > c.maxDepth {
+		c.skipped.Add(uint64(len(urls)))
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	added := false
+	for _, u := range urls {
+		if _, ok := c.seen[u]; ok {
+			c.duplicates.Add(1)
+			continue
+		}
+		if c.maxPages > 0 && len(c.seen) >= c.maxPages {
+			c.skipped.Add(1)
+			continue
+		}
+		c.seen[u] = struct{}{}
+		c.queue = append(c.queue, item{url: u, depth: depth})
+		added = true
+	}
+	if added {
+		c.notifyLocked()
+	}
+}
+
+// pop — метод получения следующего адреса
+// Возвращает false, когда очередь пуста и обрабатываемых адресов нет
+// (новым взяться неоткуда), или после отмены ctx
+func (c *crawl) pop(ctx context.Context) (item, bool) {
+	for {
+		c.mutex.Lock()
+		if ctx.Err() != nil {
+			c.mutex.Unlock()
+			return item{}, false
+		}
+		if len(c.queue) > 0 {
+			it := c.queue[0]
+			c.queue[0] = item{}
+			c.queue = c.queue[1:]
+			c.active++
+			c.mutex.Unlock()
+			return it, true
+		}
+		if c.active == 0 {
+			c.mutex.Unlock()
+			return item{}, false
+		}
+		changed := c.changed
+		c.mutex.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return item{}, false
+		}
+	}
+}
+
+// done — метод завершения обработки адреса, взятого pop
+func (c *crawl) done() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.active--
+	if c.active == 0 && len(c.queue) == 0 {
+		// Будим ожидающих, чтобы они увидели конец обхода
+		c.notifyLocked()
+	}
+}
+
+// notifyLocked — метод оповещения ожидающих об изменении очереди
+func (c *crawl) notifyLocked() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// process — метод загрузки и обработки одного адреса
+func (c *crawl) process(ctx context.Context, it item) {
+	page, err := c.load(ctx, it)
+	if err == nil && c.handle != nil {
+		err = c.handle(ctx, page)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			// Отмена обхода — не неудача страницы
+			return
+		}
+		c.failed.Add(1)
+		if c.onError != nil {
+			c.onError(it.url, err)
+		}
+		return
+	}
+	c.fetched.Add(1)
+	c.push(page.Links, it.depth+1)
+}
+
+// load — метод загрузки адреса с повторами
+func (c *crawl) load(ctx context.Context, it item) (Page, error) {
+	u, err := url.Parse(it.url)
+	if err != nil {
+		return Page{}, err
+	}
+	if u.Host == "" {
+		return Page{}, fmt.Errorf("адрес без хоста: %q", it.url)
+	}
+
+	page := Page{URL: it.url, Depth: it.depth}
+	backoff := c.backoff
+	for {
+		page.Attempts++
+		page.Body, page.Links, err = c.attempt(ctx, u.Host, it.url)
+		if err == nil || page.Attempts > c.retries || ctx.Err() != nil {
+			return page, err
+		}
+		c.retried.Add(1)
+		if err := clock.Sleep(ctx, c.clock, backoff); err != nil {
+			return page, err
+		}
+		backoff *= 2
+		if c.maxBackoff > 0 && backoff > c.maxBackoff {
+			backoff = c.maxBackoff
+		}
+	}
+}
+
+// attempt — метод одной попытки загрузки с соблюдением всех ограничений
+// Слот хоста берется первым: пока он занят, токены частоты хоста
+// и разрешения общего семафора не расходуются впустую
+func (c *crawl) attempt(ctx context.Context, host, rawURL string) ([]byte, []string, error) {
+	if err := c.hosts.Acquire(ctx, host); err != nil {
+		return nil, nil, err
+	}
+	defer c.hosts.Release(host)
+
+	if rate := c.rateFor(host); rate != nil {
+		if err := rate.Wait(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+	if c.sem != nil {
+		if err := c.sem.AcquireContext(ctx); err != nil {
+			return nil, nil, err
+		}
+		defer c.sem.Release()
+	}
+	return c.fetch(ctx, rawURL)
+}
+
+// rateFor — метод получения ограничителя частоты хоста (nil — без ограничения)
+func (c *crawl) rateFor(host string) *ratelimit.Limiter {
+	if c.hostRate == 0 {
+		return nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	l, ok := c.rates[host]
+	if !ok {
+		// Параметры проверены в New, поэтому ошибки быть не может
+		l, _ = ratelimit.NewLimiter(c.hostRate, c.hostBurst, ratelimit.WithClock(c.clock))
+		c.rates[host] = l
+	}
+	return l
+}
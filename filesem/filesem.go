@@ -0,0 +1,226 @@
+package filesem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"goroutines-example/semaphore"
+)
+
+// ErrTimeout — ошибка захвата, не дождавшегося свободного слота за таймаут
+var ErrTimeout = errors.New("не удалось захватить разрешение у файлового семафора")
+
+// FileSemaphore — межпроцессный семафор на файловых блокировках
+// Каждое разрешение — файл-слот в общем каталоге, занятый рекомендательной
+// блокировкой flock (на системах без flock — эксклюзивно созданный файл).
+// Несколько процессов на одном хосте, открывшие семафор с одним каталогом
+// и количеством разрешений, делят общий бюджет — например, чтобы ограничить
+// количество одновременных тяжелых запусков утилиты командной строки.
+// Блокировка flock снимается ядром при завершении процесса, поэтому
+// упавший процесс не уносит разрешение с собой
+type FileSemaphore struct {
+	dir     string
+	permits int
+	// Время ожидания захвата (0 — без ограничения)
+	timeout time.Duration
+	// Период повторных попыток занять слот
+	poll time.Duration
+
+	// Защита held
+	mutex sync.Mutex
+	// Функции освобождения слотов, занятых этим процессом
+	held []func()
+}
+
+var _ semaphore.Semaphore = (*FileSemaphore)(nil)
+
+// Option — функциональная опция файлового семафора
+type Option func(*FileSemaphore)
+
+// WithPollInterval — опция периода повторных попыток (по умолчанию 20 мс)
+func WithPollInterval(d time.Duration) Option {
+	return func(s *FileSemaphore) {
+		s.poll = d
+	}
+}
+
+// New — функция открытия файлового семафора
+// dir — общий для процессов каталог слотов (создается при необходимости),
+// permits — количество разрешений; все процессы должны передавать одно
+// и то же значение. timeout — время ожидания захвата (0 — без ограничения)
+func New(dir string, permits int, timeout time.Duration, opts ...Option) (*FileSemaphore, error) {
+	if permits <= 0 {
+		return nil, fmt.Errorf("количество разрешений должно быть положительным: %d", permits)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("не удалось создать каталог семафора: %w", err)
+	}
+	s := &FileSemaphore{
+		dir:     dir,
+		permits: permits,
+		timeout: timeout,
+		poll:    20 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.poll <= 0 {
+		return nil, fmt.Errorf("период повторных попыток должен быть положительным: %v", s.poll)
+	}
+	return s, nil
+}
+
+// slot — метод получения пути файла i-го слота
+func (s *FileSemaphore) slot(i int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("slot-%d.lock", i))
+}
+
+// Acquire — метод захвата одного разрешения с таймаутом семафора
+func (s *FileSemaphore) Acquire() error {
+	return s.AcquireContext(context.Background())
+}
+
+// AcquireContext — метод захвата одного разрешения с отменой через ctx
+func (s *FileSemaphore) AcquireContext(ctx context.Context) error {
+	return s.AcquireNContext(ctx, 1)
+}
+
+// AcquireNContext — метод захвата n разрешений с отменой через ctx
+// Разрешения занимаются по одному. Если всех n сразу не хватило, занятые
+// в этом вызове слоты освобождаются до следующей попытки: иначе два
+// процесса, каждому из которых нужно n слотов, держали бы по части
+// и ждали бы друг друга вечно. Паузы между попытками растут от периода
+// опроса до 16 периодов со случайным разбросом, чтобы конкурирующие
+// процессы не повторяли попытки в такт
+func (s *FileSemaphore) AcquireNContext(ctx context.Context, n int) error {
+	if n <= 0 || n > s.permits {
+		return fmt.Errorf("некорректное количество разрешений: %d", n)
+	}
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	backoff := s.poll
+	for {
+		taken, err := s.tryN(n)
+		if err != nil {
+			return err
+		}
+		if taken != nil {
+			s.mutex.Lock()
+			s.held = append(s.held, taken...)
+			s.mutex.Unlock()
+			return nil
+		}
+
+		pause := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		select {
+		case <-time.After(pause):
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return ErrTimeout
+			}
+			return ctx.Err()
+		}
+		if backoff < 16*s.poll {
+			backoff *= 2
+		}
+	}
+}
+
+// tryN — метод попытки занять n слотов без ожидания
+// Возвращает nil без ошибки, если свободных слотов меньше n; занятые
+// при этом слоты освобождаются
+func (s *FileSemaphore) tryN(n int) ([]func(), error) {
+	taken := make([]func(), 0, n)
+	for len(taken) < n {
+		unlock, err := s.tryAny()
+		if err != nil {
+			releaseAll(taken)
+			return nil, err
+		}
+		if unlock == nil {
+			releaseAll(taken)
+			return nil, nil
+		}
+		taken = append(taken, unlock)
+	}
+	return taken, nil
+}
+
+// TryAcquire — метод попытки захвата разрешения без ожидания
+func (s *FileSemaphore) TryAcquire() bool {
+	unlock, err := s.tryAny()
+	if err != nil || unlock == nil {
+		return false
+	}
+	s.mutex.Lock()
+	s.held = append(s.held, unlock)
+	s.mutex.Unlock()
+	return true
+}
+
+// tryAny — метод попытки занять любой свободный слот
+// Возвращает nil без ошибки, если все слоты заняты
+func (s *FileSemaphore) tryAny() (func(), error) {
+	for i := 0; i < s.permits; i++ {
+		unlock, ok, err := tryLock(s.slot(i))
+		if err != nil {
+			return nil, fmt.Errorf("не удалось занять слот %d: %w", i, err)
+		}
+		if ok {
+			return unlock, nil
+		}
+	}
+	return nil, nil
+}
+
+// Release — метод освобождения одного разрешения, захваченного этим процессом
+func (s *FileSemaphore) Release() error {
+	s.mutex.Lock()
+	if len(s.held) == 0 {
+		s.mutex.Unlock()
+		return fmt.Errorf("Не удалось освободить разрешение у файлового семафора")
+	}
+	unlock := s.held[len(s.held)-1]
+	s.held = s.held[:len(s.held)-1]
+	s.mutex.Unlock()
+
+	unlock()
+	return nil
+}
+
+// AvailablePermits — метод получения количества свободных разрешений
+// Значение — снимок: слоты проверяются пробной блокировкой, и другие
+// процессы могут занять их сразу после проверки
+func (s *FileSemaphore) AvailablePermits() int {
+	free := 0
+	for i := 0; i < s.permits; i++ {
+		unlock, ok, err := tryLock(s.slot(i))
+		if err == nil && ok {
+			free++
+			unlock()
+		}
+	}
+	return free
+}
+
+// MaxPermits — метод получения количества разрешений
+func (s *FileSemaphore) MaxPermits() int {
+	return s.permits
+}
+
+// releaseAll — функция освобождения занятых слотов
+func releaseAll(unlocks []func()) {
+	for _, unlock := range unlocks {
+		unlock()
+	}
+}
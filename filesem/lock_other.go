@@ -0,0 +1,23 @@
+//go:build !unix
+
+package filesem
+
+import (
+	"errors"
+	"os"
+)
+
+// tryLock — функция попытки захвата файла path без блокировки
+// Без flock слот занимается созданием файла с O_EXCL; после аварийного
+// завершения процесса файл нужно удалить вручную
+func tryLock(path string) (unlock func(), ok bool, err error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	f.Close()
+	return func() { os.Remove(path) }, true, nil
+}
@@ -0,0 +1,110 @@
+package flight
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// call — логическая операция по ключу, которую выполняют до K исполнителей
+type call[T any] struct {
+	// Закрывается, когда результат готов
+	done  chan struct{}
+	value T
+	err   error
+	// Количество выполняющихся исполнителей
+	running int
+	// Контекст исполнителей; отменяется, когда результат готов,
+	// чтобы остальные исполнители не тратили ресурсы
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Group — ограничитель спекулятивного выполнения одинаковой работы
+// В отличие от singleflight, одну и ту же операцию по ключу могут выполнять
+// одновременно до K исполнителей (например, чтение с нескольких реплик при
+// read-repair): первый успешный результат получают все ожидающие вызовы,
+// а остальные исполнители отменяются. Вызовы сверх K не запускают fn,
+// а только ждут общий результат. Если все исполнители завершились ошибкой,
+// вызовы получают ошибку последнего из них
+type Group[T any] struct {
+	// Максимум одновременных исполнителей на ключ
+	k int
+	// Защита calls
+	mutex sync.Mutex
+	calls map[string]*call[T]
+}
+
+// NewGroup — функция создания группы с не более чем k исполнителями на ключ
+func NewGroup[T any](k int) (*Group[T], error) {
+	if k <= 0 {
+		return nil, fmt.Errorf("количество исполнителей должно быть положительным: %d", k)
+	}
+	return &Group[T]{k: k, calls: make(map[string]*call[T])}, nil
+}
+
+// Do — метод выполнения операции по ключу
+// Если для ключа выполняется меньше k исполнителей, fn запускается еще раз.
+// Исполнители работают в контексте, не зависящем от ctx отдельного вызова,
+// поэтому уход одного вызова не прерывает общую работу; отмена ctx лишь
+// прекращает ожидание результата этим вызовом
+func (g *Group[T]) Do(ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	g.mutex.Lock()
+	c, ok := g.calls[key]
+	if !ok {
+		c = &call[T]{done: make(chan struct{})}
+		c.ctx, c.cancel = context.WithCancel(context.Background())
+		g.calls[key] = c
+	}
+	if c.running < g.k {
+		c.running++
+		go g.run(key, c, fn)
+	}
+	g.mutex.Unlock()
+
+	select {
+	case <-c.done:
+		return c.value, c.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// run — метод работы одного исполнителя
+func (g *Group[T]) run(key string, c *call[T], fn func(ctx context.Context) (T, error)) {
+	value, err := fn(c.ctx)
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	c.running--
+	select {
+	case <-c.done:
+		// Результат уже получен другим исполнителем
+		return
+	default:
+	}
+	if err != nil && c.running > 0 {
+		// Ждем остальных исполнителей: кто-то из них может успеть
+		return
+	}
+
+	c.value, c.err = value, err
+	close(c.done)
+	c.cancel()
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+}
+
+// Running — метод получения количества исполнителей, выполняющихся для ключа
+func (g *Group[T]) Running(key string) int {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if c, ok := g.calls[key]; ok {
+		return c.running
+	}
+	return 0
+}
@@ -0,0 +1,164 @@
+package flightrec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Kind — тип события
+type Kind int
+
+const (
+	// Acquire — захват разрешения
+	Acquire Kind = iota
+	// Release — освобождение разрешения
+	Release
+	// Timeout — неудачная попытка захвата из-за таймаута
+	Timeout
+	// Submit — постановка задачи в очередь
+	Submit
+	// Complete — завершение задачи
+	Complete
+	// Cancel — отмена задачи до запуска
+	Cancel
+)
+
+// String — метод получения названия типа события
+func (k Kind) String() string {
+	switch k {
+	case Acquire:
+		return "acquire"
+	case Release:
+		return "release"
+	case Timeout:
+		return "timeout"
+	case Submit:
+		return "submit"
+	case Complete:
+		return "complete"
+	case Cancel:
+		return "cancel"
+	default:
+		return fmt.Sprintf("kind(%d)", int(k))
+	}
+}
+
+// Event — одно событие примитива синхронизации
+type Event struct {
+	// Время события
+	Time time.Time
+	// Тип события
+	Kind Kind
+	// Имя примитива, в котором произошло событие
+	Source string
+	// Количество разрешений (или задач), к которому относится событие
+	Count int
+	// Количество доступных разрешений после события (-1, если неизвестно)
+	Available int
+	// Метки задачи в виде "ключ=значение ..." (может быть пустым)
+	Tags string
+}
+
+// Recorder — бортовой самописец событий
+// Хранит в кольцевом буфере последние N событий всех подключенных примитивов.
+// Запись дешевая и не выводит ничего сама по себе, а содержимое буфера
+// сбрасывается по запросу, например при получении SIGQUIT во время зависания
+type Recorder struct {
+	// Защита кольцевого буфера
+	mutex sync.Mutex
+	// Кольцевой буфер событий
+	events []Event
+	// Позиция для записи следующего события
+	next int
+	// Был ли буфер заполнен хотя бы раз
+	wrapped bool
+}
+
+// NewRecorder — функция создания самописца на size последних событий
+func NewRecorder(size int) (*Recorder, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("размер буфера самописца должен быть положительным: %d", size)
+	}
+	return &Recorder{events: make([]Event, size)}, nil
+}
+
+// Record — метод записи события
+// Если время события не задано, используется текущее время
+func (r *Recorder) Record(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	r.mutex.Lock()
+	r.events[r.next] = e
+	r.next++
+	if r.next == len(r.events) {
+		r.next = 0
+		r.wrapped = true
+	}
+	r.mutex.Unlock()
+}
+
+// Events — метод получения копии записанных событий от старых к новым
+func (r *Recorder) Events() []Event {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.wrapped {
+		return append([]Event(nil), r.events[:r.next]...)
+	}
+	result := make([]Event, 0, len(r.events))
+	result = append(result, r.events[r.next:]...)
+	return append(result, r.events[:r.next]...)
+}
+
+// Dump — метод вывода записанных событий в текстовом виде
+func (r *Recorder) Dump(w io.Writer) error {
+	events := r.Events()
+	if _, err := fmt.Fprintf(w, "--- бортовой самописец: последние %d событий ---\n", len(events)); err != nil {
+		return err
+	}
+	for _, e := range events {
+		_, err := fmt.Fprintf(w, "%s %-8s %-20s count=%d available=%d",
+			e.Time.Format("15:04:05.000000"), e.Kind, e.Source, e.Count, e.Available)
+		if err == nil && e.Tags != "" {
+			_, err = fmt.Fprintf(w, " [%s]", e.Tags)
+		}
+		if err == nil {
+			_, err = fmt.Fprintln(w)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DumpOnSignal — метод вывода событий в w при каждом получении одного из сигналов
+// Без sigs используется syscall.SIGQUIT: перехват всех сигналов помешал бы
+// остановить процесс по SIGINT и SIGTERM. Работает до отмены ctx
+func (r *Recorder) DumpOnSignal(ctx context.Context, w io.Writer, sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGQUIT}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ch:
+				r.Dump(w)
+			}
+		}
+	}()
+}
@@ -0,0 +1,161 @@
+package future
+
+import (
+	"context"
+
+	"goroutines-example/cause"
+	"goroutines-example/combinator"
+	"goroutines-example/semaphore"
+)
+
+// Future — результат асинхронного вычисления, который станет доступен позже
+type Future[T any] struct {
+	// Закрывается после завершения вычисления
+	done chan struct{}
+	// Результат вычисления (доступен только после закрытия done)
+	value T
+	err   error
+}
+
+// Go — функция запуска fn в отдельной горутине
+// Возвращает Future, через который можно дождаться результата
+func Go[T any](ctx context.Context, fn func(ctx context.Context) (T, error)) *Future[T] {
+	return goLimited(ctx, nil, fn)
+}
+
+// goLimited — функция запуска fn с захватом разрешения у семафора
+// Если sem равен nil, количество одновременных вычислений не ограничивается
+func goLimited[T any](ctx context.Context, sem *semaphore.CountingSemaphore, fn func(ctx context.Context) (T, error)) *Future[T] {
+	f := &Future[T]{done: make(chan struct{})}
+	go func() {
+		defer close(f.done)
+
+		if sem != nil {
+			if err := sem.AcquireContext(ctx); err != nil {
+				f.err = err
+				return
+			}
+			defer sem.Release()
+		}
+		// Вычисление, которое дождалось очереди уже после отмены, не запускаем
+		if err := ctx.Err(); err != nil {
+			f.err = err
+			return
+		}
+		f.value, f.err = fn(ctx)
+	}()
+	return f
+}
+
+// Done — метод получения канала, закрываемого после завершения вычисления
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Await — метод ожидания результата вычисления
+// Возвращает ошибку ctx, если он отменен раньше завершения вычисления
+func (f *Future[T]) Await(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Outcome — итог одного вычисления для Settle
+type Outcome[T any] struct {
+	Value T
+	Err   error
+}
+
+// All — функция ожидания результатов всех вычислений
+// Количество одновременно выполняемых fns ограничивается семафором sem
+// (nil — без ограничения). При failFast первая ошибка сразу возвращается,
+// а контекст остальных вычислений отменяется с этой ошибкой в качестве
+// причины; иначе All дожидается всех
+// вычислений и возвращает первую по порядку ошибку
+func All[T any](ctx context.Context, sem *semaphore.CountingSemaphore, failFast bool, fns ...func(ctx context.Context) (T, error)) ([]T, error) {
+	ctx, cancel := cause.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	futures := start(ctx, sem, fns)
+	values := make([]T, len(futures))
+
+	if !failFast {
+		var firstErr error
+		for i, f := range futures {
+			<-f.done
+			values[i] = f.value
+			if f.err != nil && firstErr == nil {
+				firstErr = f.err
+			}
+		}
+		if firstErr != nil {
+			return nil, firstErr
+		}
+		return values, nil
+	}
+
+	// Ждем завершения вычислений в порядке их готовности, чтобы
+	// заметить ошибку как можно раньше
+	finished := make(chan int, len(futures))
+	for i, f := range futures {
+		go func(i int, f *Future[T]) {
+			<-f.done
+			finished <- i
+		}(i, f)
+	}
+	for range futures {
+		select {
+		case <-ctx.Done():
+			return nil, cause.Err(ctx)
+		case i := <-finished:
+			if err := futures[i].err; err != nil {
+				// Остальные вычисления видят эту ошибку через cause.Cause
+				cancel(err)
+				return nil, err
+			}
+			values[i] = futures[i].value
+		}
+	}
+	return values, nil
+}
+
+// Any — функция получения первого успешного результата
+// Остальные вычисления отменяются. Если все вычисления завершились ошибкой,
+// возвращается *combinator.AllFailedError
+func Any[T any](ctx context.Context, sem *semaphore.CountingSemaphore, fns ...func(ctx context.Context) (T, error)) (T, error) {
+	limited := make([]func(ctx context.Context) (T, error), len(fns))
+	for i, fn := range fns {
+		fn := fn
+		limited[i] = func(ctx context.Context) (T, error) {
+			return goLimited(ctx, sem, fn).Await(ctx)
+		}
+	}
+	res, err := combinator.Race(ctx, limited...)
+	return res.Value, err
+}
+
+// Settle — функция ожидания завершения всех вычислений
+// В отличие от All, никогда не прерывается из-за ошибок и возвращает
+// итог каждого вычисления в порядке передачи fns
+func Settle[T any](ctx context.Context, sem *semaphore.CountingSemaphore, fns ...func(ctx context.Context) (T, error)) []Outcome[T] {
+	futures := start(ctx, sem, fns)
+	outcomes := make([]Outcome[T], len(futures))
+	for i, f := range futures {
+		<-f.done
+		outcomes[i] = Outcome[T]{Value: f.value, Err: f.err}
+	}
+	return outcomes
+}
+
+// start — функция запуска всех вычислений с общим ограничением
+func start[T any](ctx context.Context, sem *semaphore.CountingSemaphore, fns []func(ctx context.Context) (T, error)) []*Future[T] {
+	futures := make([]*Future[T], len(fns))
+	for i, fn := range fns {
+		futures[i] = goLimited(ctx, sem, fn)
+	}
+	return futures
+}
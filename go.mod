@@ -1,3 +1,5 @@
 module goroutines-example
 
-go 1.19
\ No newline at end of file
+go 1.19
+
+require golang.org/x/sync v0.7.0
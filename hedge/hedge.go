@@ -0,0 +1,100 @@
+package hedge
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"goroutines-example/semaphore"
+)
+
+// Options — параметры хеджированного выполнения
+type Options struct {
+	// Задержка перед запуском очередной дополнительной попытки
+	Delay time.Duration
+	// Максимальное общее количество попыток, включая первую
+	MaxAttempts int
+	// Бюджет дополнительных попыток: каждая дополнительная попытка
+	// занимает одно разрешение семафора на время своего выполнения.
+	// Общий бюджет для всех вызовов не дает хеджированию удвоить нагрузку
+	// на зависимость при ее деградации. nil — без ограничения
+	Budget *semaphore.CountingSemaphore
+}
+
+// result — результат одной попытки
+type result[T any] struct {
+	value T
+	err   error
+}
+
+// Do — функция хеджированного выполнения fn
+// Запускает fn; если она не завершилась за opts.Delay, запускает дополнительные
+// попытки (не более opts.MaxAttempts всего и в пределах бюджета).
+// Возвращает первый успешный результат и отменяет контекст остальных попыток.
+// Если все попытки завершились ошибкой, возвращается ошибка последней из них
+func Do[T any](ctx context.Context, opts Options, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	if opts.MaxAttempts <= 0 {
+		return zero, fmt.Errorf("количество попыток должно быть положительным: %d", opts.MaxAttempts)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	// Отмена контекста останавливает все еще работающие попытки
+	defer cancel()
+
+	// Буфер на все попытки: завершившиеся после нашего возврата горутины
+	// не блокируются на отправке результата и не утекают
+	results := make(chan result[T], opts.MaxAttempts)
+
+	launch := func(hedged bool) bool {
+		if hedged && opts.Budget != nil && !opts.Budget.TryAcquire() {
+			return false
+		}
+		go func() {
+			if hedged && opts.Budget != nil {
+				defer opts.Budget.Release()
+			}
+			v, err := fn(ctx)
+			results <- result[T]{value: v, err: err}
+		}()
+		return true
+	}
+
+	launch(false)
+	launched, finished := 1, 0
+
+	timer := time.NewTimer(opts.Delay)
+	defer timer.Stop()
+
+	var lastErr error
+	for {
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+
+		case r := <-results:
+			finished++
+			if r.err == nil {
+				return r.value, nil
+			}
+			lastErr = r.err
+
+			// Попытка завершилась ошибкой — сразу пробуем следующую,
+			// не дожидаясь таймера
+			if launched < opts.MaxAttempts && launch(true) {
+				launched++
+			}
+			if finished == launched {
+				return zero, lastErr
+			}
+
+		case <-timer.C:
+			if launched < opts.MaxAttempts && launch(true) {
+				launched++
+			}
+			if launched < opts.MaxAttempts {
+				timer.Reset(opts.Delay)
+			}
+		}
+	}
+}
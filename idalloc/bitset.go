@@ -0,0 +1,106 @@
+package idalloc
+
+import (
+	"fmt"
+	"math/bits"
+	"sync/atomic"
+)
+
+// Bitset — битовое множество фиксированного размера, безопасное для конкурентного доступа
+// Каждый бит меняется атомарно (CAS по 64-битному слову), блокировок нет
+type Bitset struct {
+	words []atomic.Uint64
+	size  int
+}
+
+// NewBitset — функция создания множества из size битов (все сброшены)
+func NewBitset(size int) (*Bitset, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("размер множества должен быть положительным: %d", size)
+	}
+	return &Bitset{words: make([]atomic.Uint64, (size+63)/64), size: size}, nil
+}
+
+// Len — метод получения размера множества в битах
+func (b *Bitset) Len() int {
+	return b.size
+}
+
+// Set — метод установки бита i
+// Возвращает true, если бит был сброшен и его установил именно этот вызов
+func (b *Bitset) Set(i int) bool {
+	w, mask := b.locate(i)
+	for {
+		old := w.Load()
+		if old&mask != 0 {
+			return false
+		}
+		if w.CompareAndSwap(old, old|mask) {
+			return true
+		}
+	}
+}
+
+// Clear — метод сброса бита i
+// Возвращает true, если бит был установлен и его сбросил именно этот вызов
+func (b *Bitset) Clear(i int) bool {
+	w, mask := b.locate(i)
+	for {
+		old := w.Load()
+		if old&mask == 0 {
+			return false
+		}
+		if w.CompareAndSwap(old, old&^mask) {
+			return true
+		}
+	}
+}
+
+// Test — метод проверки бита i
+func (b *Bitset) Test(i int) bool {
+	w, mask := b.locate(i)
+	return w.Load()&mask != 0
+}
+
+// Count — метод подсчета установленных битов
+// При конкурентных изменениях результат не является атомарным снимком
+func (b *Bitset) Count() int {
+	n := 0
+	for i := range b.words {
+		n += bits.OnesCount64(b.words[i].Load())
+	}
+	return n
+}
+
+// setFirstClear — метод установки первого сброшенного бита, начиная со слова start
+// Возвращает номер установленного бита или -1, если все биты установлены
+func (b *Bitset) setFirstClear(start int) int {
+	for k := 0; k < len(b.words); k++ {
+		wi := (start + k) % len(b.words)
+		w := &b.words[wi]
+		for {
+			old := w.Load()
+			free := ^old
+			if wi == len(b.words)-1 && b.size%64 != 0 {
+				// Биты за пределами размера в последнем слове не выдаем
+				free &= 1<<(b.size%64) - 1
+			}
+			if free == 0 {
+				break
+			}
+			bit := bits.TrailingZeros64(free)
+			if w.CompareAndSwap(old, old|1<<bit) {
+				return wi*64 + bit
+			}
+		}
+	}
+	return -1
+}
+
+// locate — метод получения слова и маски для бита i
+func (b *Bitset) locate(i int) (*atomic.Uint64, uint64) {
+	if i < 0 || i >= b.size {
+		panic(fmt.Sprintf("номер бита %d вне диапазона [0, %d)", i, b.size))
+	}
+	return &b.words[i/64], 1 << (i % 64)
+}
@@ -0,0 +1,57 @@
+package idalloc
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// IDAllocator — распределитель небольших целых идентификаторов в диапазоне [0, size)
+// Выдает и переиспользует номера слотов без блокировок поверх Bitset.
+// Типичное применение — таблица ресурсов, индексированная номером слота,
+// под защитой семафора того же размера: после успешного Acquire вызов
+// Alloc гарантированно находит свободный номер
+type IDAllocator struct {
+	bits *Bitset
+	// Слово, с которого начинается следующий поиск, чтобы конкурентные
+	// вызовы Alloc не соревновались за одни и те же первые биты
+	hint atomic.Uint32
+}
+
+// NewIDAllocator — функция создания распределителя size идентификаторов
+func NewIDAllocator(size int) (*IDAllocator, error) {
+	b, err := NewBitset(size)
+	if err != nil {
+		return nil, err
+	}
+	return &IDAllocator{bits: b}, nil
+}
+
+// Alloc — метод выдачи свободного идентификатора
+// Возвращает false, если все идентификаторы заняты
+func (a *IDAllocator) Alloc() (int, bool) {
+	start := int(a.hint.Add(1)-1) % len(a.bits.words)
+	id := a.bits.setFirstClear(start)
+	return id, id >= 0
+}
+
+// Free — метод возврата идентификатора для повторного использования
+// Возвращает ошибку, если идентификатор не был выдан
+func (a *IDAllocator) Free(id int) error {
+	if id < 0 || id >= a.bits.Len() {
+		return fmt.Errorf("идентификатор %d вне диапазона [0, %d)", id, a.bits.Len())
+	}
+	if !a.bits.Clear(id) {
+		return fmt.Errorf("идентификатор %d не был выдан", id)
+	}
+	return nil
+}
+
+// InUse — метод получения количества выданных идентификаторов
+func (a *IDAllocator) InUse() int {
+	return a.bits.Count()
+}
+
+// Size — метод получения общего количества идентификаторов
+func (a *IDAllocator) Size() int {
+	return a.bits.Len()
+}
@@ -0,0 +1,207 @@
+package idempotency
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Store — хранилище результатов для сохранения между перезапусками
+// Позволяет не выполнять операцию повторно, если результат уже был получен
+// другим экземпляром сервиса или до перезапуска
+type Store[T any] interface {
+	// Load — загрузка результата по ключу; ok=false, если результата нет или он устарел
+	Load(ctx context.Context, key string) (value T, ok bool, err error)
+	// Save — сохранение результата до момента expires
+	Save(ctx context.Context, key string, value T, expires time.Time) error
+}
+
+// call — выполняющийся вызов, результат которого ждут все запросы с тем же ключом
+type call[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+	// Операция выполнена успешно: результат кешируется, даже если err —
+	// ошибка сохранения в хранилище
+	succeeded bool
+}
+
+// entry — закешированный результат
+type entry[T any] struct {
+	key     string
+	value   T
+	expires time.Time
+}
+
+// Executor — исполнитель операций с ключом идемпотентности
+// Для каждого ключа операция выполняется не более одного раза за время ttl:
+// одновременные запросы с одним ключом ждут общий результат (как singleflight),
+// а успешный результат кешируется в LRU-кеше и, при наличии, в хранилище.
+// Ошибки (в том числе паника fn) не кешируются: после ошибки операцию
+// можно повторить. Если fn выполнена, но результат не удалось сохранить
+// в хранилище, вызов возвращает ошибку сохранения, а результат остается
+// в кеше, чтобы fn не выполнялась повторно
+type Executor[T any] struct {
+	// Защита состояния
+	mutex sync.Mutex
+	// Выполняющиеся вызовы по ключам
+	inflight map[string]*call[T]
+	// LRU-кеш результатов: список от недавно использованных к давно
+	// использованным и индекс по ключам
+	order    list.List
+	index    map[string]*list.Element
+	capacity int
+	// Время жизни результата
+	ttl time.Duration
+	// Хранилище результатов (может отсутствовать)
+	store Store[T]
+}
+
+// NewExecutor — функция создания исполнителя
+// capacity — максимальное количество результатов в кеше
+// ttl — время, в течение которого результат считается действительным
+// store — хранилище результатов (nil — только кеш в памяти)
+func NewExecutor[T any](capacity int, ttl time.Duration, store Store[T]) (*Executor[T], error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("емкость кеша должна быть положительной: %d", capacity)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("время жизни результата должно быть положительным: %v", ttl)
+	}
+	return &Executor[T]{
+		inflight: make(map[string]*call[T]),
+		index:    make(map[string]*list.Element),
+		capacity: capacity,
+		ttl:      ttl,
+		store:    store,
+	}, nil
+}
+
+// Do — метод выполнения операции с ключом идемпотентности
+// Возвращает результат и признак того, что он получен без выполнения fn
+// в этом вызове (из кеша, хранилища или от одновременного вызова)
+func (e *Executor[T]) Do(ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (value T, shared bool, err error) {
+	e.mutex.Lock()
+	if v, ok := e.cachedLocked(key); ok {
+		e.mutex.Unlock()
+		return v, true, nil
+	}
+	if c, ok := e.inflight[key]; ok {
+		e.mutex.Unlock()
+		select {
+		case <-c.done:
+			return c.value, true, c.err
+		case <-ctx.Done():
+			var zero T
+			return zero, true, ctx.Err()
+		}
+	}
+	c := &call[T]{done: make(chan struct{})}
+	e.inflight[key] = c
+	e.mutex.Unlock()
+
+	// Очистка в defer: при панике fn ждущие того же ключа не должны
+	// заблокироваться навечно
+	defer func() {
+		e.mutex.Lock()
+		delete(e.inflight, key)
+		if c.succeeded {
+			e.putLocked(key, c.value, time.Now().Add(e.ttl))
+		}
+		e.mutex.Unlock()
+		close(c.done)
+	}()
+
+	shared = e.execute(ctx, key, c, fn)
+	return c.value, shared, c.err
+}
+
+// execute — метод получения результата из хранилища или выполнения fn
+// Возвращает true, если результат взят из хранилища
+func (e *Executor[T]) execute(ctx context.Context, key string, c *call[T], fn func(ctx context.Context) (T, error)) bool {
+	if e.store != nil {
+		v, ok, err := e.store.Load(ctx, key)
+		if err != nil {
+			c.err = fmt.Errorf("загрузка результата %q из хранилища: %w", key, err)
+			return false
+		}
+		if ok {
+			c.value = v
+			return true
+		}
+	}
+
+	c.value, c.err = run(ctx, key, fn)
+	if c.err != nil {
+		return false
+	}
+	// Результат остается в кеше и при ошибке сохранения: повторный вызов
+	// в этом процессе не выполнит fn еще раз
+	c.succeeded = true
+	if e.store != nil {
+		if err := e.store.Save(ctx, key, c.value, time.Now().Add(e.ttl)); err != nil {
+			c.err = fmt.Errorf("сохранение результата %q в хранилище: %w", key, err)
+		}
+	}
+	return false
+}
+
+// run — функция выполнения fn с превращением паники в ошибку
+func run[T any](ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (value T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			var zero T
+			value, err = zero, fmt.Errorf("операция %q завершилась паникой: %v", key, r)
+		}
+	}()
+	return fn(ctx)
+}
+
+// cachedLocked — метод поиска действительного результата в кеше
+// Вызывается при захваченном мьютексе
+func (e *Executor[T]) cachedLocked(key string) (T, bool) {
+	el, ok := e.index[key]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	ent := el.Value.(*entry[T])
+	if time.Now().After(ent.expires) {
+		e.order.Remove(el)
+		delete(e.index, key)
+		var zero T
+		return zero, false
+	}
+	e.order.MoveToFront(el)
+	return ent.value, true
+}
+
+// putLocked — метод добавления результата в кеш с вытеснением давно
+// использованных результатов
+// Вызывается при захваченном мьютексе
+func (e *Executor[T]) putLocked(key string, value T, expires time.Time) {
+	if el, ok := e.index[key]; ok {
+		el.Value = &entry[T]{key: key, value: value, expires: expires}
+		e.order.MoveToFront(el)
+		return
+	}
+	e.index[key] = e.order.PushFront(&entry[T]{key: key, value: value, expires: expires})
+	for e.order.Len() > e.capacity {
+		oldest := e.order.Back()
+		e.order.Remove(oldest)
+		delete(e.index, oldest.Value.(*entry[T]).key)
+	}
+}
+
+// Forget — метод удаления результата из кеша
+// Хранилище не затрагивается
+func (e *Executor[T]) Forget(key string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if el, ok := e.index[key]; ok {
+		e.order.Remove(el)
+		delete(e.index, key)
+	}
+}
@@ -0,0 +1,45 @@
+package inflight
+
+import "sync/atomic"
+
+// Gauge — счетчик выполняющихся операций с отметкой максимума
+// Максимум (high-water mark) показывает наблюдаемый пик одновременности
+// с момента последнего Reset, поэтому планирование емкости может опираться
+// на реальные пики, а не на догадки. Нулевое значение готово к использованию
+type Gauge struct {
+	current atomic.Int64
+	peak    atomic.Int64
+}
+
+// Add — метод учета delta начатых операций
+func (g *Gauge) Add(delta int64) {
+	v := g.current.Add(delta)
+	for {
+		peak := g.peak.Load()
+		if v <= peak || g.peak.CompareAndSwap(peak, v) {
+			return
+		}
+	}
+}
+
+// Done — метод учета одной завершенной операции
+func (g *Gauge) Done() {
+	g.current.Add(-1)
+}
+
+// Value — метод получения количества выполняющихся операций
+func (g *Gauge) Value() int64 {
+	return g.current.Load()
+}
+
+// HighWaterMark — метод получения максимума с момента последнего Reset
+func (g *Gauge) HighWaterMark() int64 {
+	return g.peak.Load()
+}
+
+// Reset — метод сброса максимума до текущего значения
+// Возвращает максимум до сброса, чтобы его можно было выгрузить
+// в метрики за прошедший период без гонки между чтением и сбросом
+func (g *Gauge) Reset() int64 {
+	return g.peak.Swap(g.current.Load())
+}
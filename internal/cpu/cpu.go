@@ -0,0 +1,6 @@
+package cpu
+
+// CacheLineSize — размер кеш-линии с запасом (на части процессоров
+// соседние линии подгружаются парами); используется для выравнивания
+// полей, которые разные горутины меняют независимо (false sharing)
+const CacheLineSize = 128
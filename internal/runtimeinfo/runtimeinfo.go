@@ -0,0 +1,34 @@
+package runtimeinfo
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+)
+
+// GoroutineID — функция получения номера текущей горутины
+// Номер разбирается из заголовка стека; это медленно, поэтому вызывается
+// только в отладочных и диагностических путях
+func GoroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	// Стек начинается с "goroutine 123 [running]:"
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	var id uint64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			break
+		}
+		id = id*10 + uint64(c-'0')
+	}
+	return id
+}
+
+// CallerSite — функция получения места вызова в виде файл:строка
+// skip имеет тот же смысл, что и в runtime.Caller
+func CallerSite(skip int) string {
+	if _, file, line, ok := runtime.Caller(skip); ok {
+		return fmt.Sprintf("%s:%d", file, line)
+	}
+	return ""
+}
@@ -0,0 +1,279 @@
+package keyed
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// waiter — ожидающий захвата разрешения
+type waiter struct {
+	// Закрывается, когда разрешение выдано
+	ready chan struct{}
+	// Выдано ли разрешение (защищено мьютексом ограничителя)
+	granted bool
+}
+
+// keyState — состояние одного ключа
+type keyState struct {
+	// Количество разрешений, занятых ключом
+	inUse int
+	// Максимальное количество одновременных разрешений ключа
+	max int
+	// Очередь ожидающих по этому ключу (FIFO)
+	waiters []*waiter
+}
+
+// FairLimiter — ограничитель с раздельными лимитами по ключам и справедливым
+// распределением общего лимита между ключами
+// Каждый ключ (например, арендатор) имеет свой лимит одновременных операций,
+// а все ключи вместе — общий лимит. Освободившееся разрешение выдается
+// ожидающим по кругу между ключами, поэтому ключ с длинной очередью
+// не может вытеснить остальных
+type FairLimiter struct {
+	// Защита состояния
+	mutex sync.Mutex
+	// Общий лимит разрешений для всех ключей
+	capacity int
+	// Количество занятых разрешений по всем ключам
+	inUse int
+	// Лимит ключа по умолчанию
+	defaultMax int
+	// Индивидуальные лимиты ключей
+	limits map[string]int
+	// Состояние ключей, у которых есть занятые разрешения или ожидающие
+	keys map[string]*keyState
+	// Ключи с ожидающими в порядке кругового обхода
+	ring []string
+	// Позиция кругового обхода
+	cursor int
+}
+
+// NewFairLimiter — функция создания справедливого ограничителя
+// capacity — общий лимит для всех ключей
+// perKey — лимит одного ключа по умолчанию
+func NewFairLimiter(capacity, perKey int) (*FairLimiter, error) {
+	if capacity <= 0 || perKey <= 0 {
+		return nil, fmt.Errorf("лимиты должны быть положительными: общий %d, на ключ %d", capacity, perKey)
+	}
+	return &FairLimiter{
+		capacity:   capacity,
+		defaultMax: perKey,
+		limits:     make(map[string]int),
+		keys:       make(map[string]*keyState),
+	}, nil
+}
+
+// SetKeyLimit — метод задания индивидуального лимита ключа
+func (l *FairLimiter) SetKeyLimit(key string, max int) error {
+	if max <= 0 {
+		return fmt.Errorf("лимит ключа %q должен быть положительным: %d", key, max)
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.limits[key] = max
+	if ks, ok := l.keys[key]; ok {
+		ks.max = max
+	}
+	// При увеличении лимита ожидающие могут получить разрешения
+	l.dispatchLocked()
+	return nil
+}
+
+// stateLocked — метод получения (или создания) состояния ключа
+// Вызывается при захваченном мьютексе
+func (l *FairLimiter) stateLocked(key string) *keyState {
+	ks, ok := l.keys[key]
+	if !ok {
+		max, ok := l.limits[key]
+		if !ok {
+			max = l.defaultMax
+		}
+		ks = &keyState{max: max}
+		l.keys[key] = ks
+	}
+	return ks
+}
+
+// Acquire — метод захвата разрешения для ключа
+// Блокируется, пока разрешение не будет выдано или пока не отменен ctx
+func (l *FairLimiter) Acquire(ctx context.Context, key string) error {
+	l.mutex.Lock()
+	ks := l.stateLocked(key)
+
+	// Быстрый путь: есть свободное место и никто из тех, кто может его занять,
+	// не ждет — иначе новичок обогнал бы стоящих в очереди
+	if l.inUse < l.capacity && ks.inUse < ks.max && !l.eligibleWaitingLocked() {
+		l.inUse++
+		ks.inUse++
+		l.mutex.Unlock()
+		return nil
+	}
+
+	w := &waiter{ready: make(chan struct{})}
+	if len(ks.waiters) == 0 {
+		l.ring = append(l.ring, key)
+	}
+	ks.waiters = append(ks.waiters, w)
+	// Свободное место могло быть, но занято очередью — распределяем по кругу
+	l.dispatchLocked()
+	l.mutex.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if w.granted {
+		// Разрешение выдано одновременно с отменой — возвращаем его
+		l.releaseLocked(key)
+		return ctx.Err()
+	}
+	for i, other := range ks.waiters {
+		if other == w {
+			ks.waiters = append(ks.waiters[:i], ks.waiters[i+1:]...)
+			break
+		}
+	}
+	if len(ks.waiters) == 0 {
+		l.removeFromRingLocked(key)
+	}
+	l.forgetLocked(key, ks)
+	return ctx.Err()
+}
+
+// TryAcquire — метод попытки захвата разрешения для ключа без ожидания
+func (l *FairLimiter) TryAcquire(key string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	ks := l.stateLocked(key)
+	if l.inUse < l.capacity && ks.inUse < ks.max && !l.eligibleWaitingLocked() {
+		l.inUse++
+		ks.inUse++
+		return true
+	}
+	l.forgetLocked(key, ks)
+	return false
+}
+
+// Release — метод освобождения разрешения ключа
+func (l *FairLimiter) Release(key string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	ks, ok := l.keys[key]
+	if !ok || ks.inUse == 0 {
+		return fmt.Errorf("ключ %q не удерживает разрешений", key)
+	}
+	l.releaseLocked(key)
+	return nil
+}
+
+// releaseLocked — метод освобождения разрешения и передачи его ожидающим
+// Вызывается при захваченном мьютексе
+func (l *FairLimiter) releaseLocked(key string) {
+	ks := l.keys[key]
+	ks.inUse--
+	l.inUse--
+	l.dispatchLocked()
+	l.forgetLocked(key, ks)
+}
+
+// dispatchLocked — метод выдачи свободных разрешений ожидающим по кругу между ключами
+// Вызывается при захваченном мьютексе
+func (l *FairLimiter) dispatchLocked() {
+	for l.inUse < l.capacity && len(l.ring) > 0 {
+		granted := false
+		// Обходим ключи не более одного полного круга, начиная с cursor
+		for i := 0; i < len(l.ring); i++ {
+			idx := (l.cursor + i) % len(l.ring)
+			key := l.ring[idx]
+			ks := l.keys[key]
+			if ks.inUse >= ks.max {
+				continue
+			}
+
+			w := ks.waiters[0]
+			ks.waiters[0] = nil
+			ks.waiters = ks.waiters[1:]
+			w.granted = true
+			close(w.ready)
+			ks.inUse++
+			l.inUse++
+
+			if len(ks.waiters) == 0 {
+				// Ключ уходит из круга, следующий ключ занимает его позицию
+				l.ring = append(l.ring[:idx], l.ring[idx+1:]...)
+				l.cursor = idx
+			} else {
+				l.cursor = idx + 1
+			}
+			if len(l.ring) > 0 {
+				l.cursor %= len(l.ring)
+			} else {
+				l.cursor = 0
+			}
+			granted = true
+			break
+		}
+		if !granted {
+			// У всех ключей с ожидающими исчерпаны собственные лимиты
+			return
+		}
+	}
+}
+
+// eligibleWaitingLocked — метод проверки, есть ли ожидающие, которым
+// собственный лимит ключа позволяет получить разрешение
+// Вызывается при захваченном мьютексе
+func (l *FairLimiter) eligibleWaitingLocked() bool {
+	for _, key := range l.ring {
+		if ks := l.keys[key]; ks.inUse < ks.max {
+			return true
+		}
+	}
+	return false
+}
+
+// removeFromRingLocked — метод удаления ключа из круга ожидающих
+// Вызывается при захваченном мьютексе
+func (l *FairLimiter) removeFromRingLocked(key string) {
+	for i, k := range l.ring {
+		if k == key {
+			l.ring = append(l.ring[:i], l.ring[i+1:]...)
+			if l.cursor > i {
+				l.cursor--
+			}
+			break
+		}
+	}
+	if len(l.ring) == 0 {
+		l.cursor = 0
+	} else {
+		l.cursor %= len(l.ring)
+	}
+}
+
+// forgetLocked — метод удаления состояния неиспользуемого ключа,
+// чтобы карта ключей не росла бесконечно
+// Вызывается при захваченном мьютексе
+func (l *FairLimiter) forgetLocked(key string, ks *keyState) {
+	if ks.inUse == 0 && len(ks.waiters) == 0 {
+		delete(l.keys, key)
+	}
+}
+
+// InUse — метод получения количества занятых разрешений (всего и по ключу)
+func (l *FairLimiter) InUse(key string) (total, byKey int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if ks, ok := l.keys[key]; ok {
+		byKey = ks.inUse
+	}
+	return l.inUse, byKey
+}
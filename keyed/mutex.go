@@ -0,0 +1,80 @@
+package keyed
+
+import "sync"
+
+// lockEntry — мьютекс одного ключа со счетчиком использующих его горутин
+type lockEntry struct {
+	mutex sync.Mutex
+	// Количество горутин, удерживающих или ожидающих мьютекс
+	// (защищено мьютексом Mutex.mutex)
+	refs int
+}
+
+// Mutex — набор мьютексов по ключам
+// Горутины с одинаковым ключом выполняются по очереди, с разными — параллельно.
+// Мьютекс ключа существует, только пока его кто-то удерживает или ждет,
+// поэтому количество ключей не ограничено
+type Mutex struct {
+	// Защита карты мьютексов
+	mutex sync.Mutex
+	// Мьютексы используемых ключей
+	locks map[string]*lockEntry
+}
+
+// NewMutex — функция создания набора мьютексов по ключам
+func NewMutex() *Mutex {
+	return &Mutex{locks: make(map[string]*lockEntry)}
+}
+
+// Lock — метод захвата мьютекса ключа
+func (m *Mutex) Lock(key string) {
+	m.mutex.Lock()
+	e, ok := m.locks[key]
+	if !ok {
+		e = &lockEntry{}
+		m.locks[key] = e
+	}
+	e.refs++
+	m.mutex.Unlock()
+
+	e.mutex.Lock()
+}
+
+// TryLock — метод попытки захвата мьютекса ключа без ожидания
+func (m *Mutex) TryLock(key string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.locks[key]; ok {
+		return false
+	}
+	e := &lockEntry{refs: 1}
+	e.mutex.Lock()
+	m.locks[key] = e
+	return true
+}
+
+// Unlock — метод освобождения мьютекса ключа
+// Вызов Unlock для незахваченного ключа приводит к панике, как и у sync.Mutex
+func (m *Mutex) Unlock(key string) {
+	m.mutex.Lock()
+	e, ok := m.locks[key]
+	if !ok {
+		m.mutex.Unlock()
+		panic("keyed: Unlock для незахваченного ключа " + key)
+	}
+	e.refs--
+	if e.refs == 0 {
+		delete(m.locks, key)
+	}
+	m.mutex.Unlock()
+
+	e.mutex.Unlock()
+}
+
+// Do — метод выполнения fn под мьютексом ключа
+func (m *Mutex) Do(key string, fn func() error) error {
+	m.Lock(key)
+	defer m.Unlock(key)
+	return fn()
+}
@@ -0,0 +1,182 @@
+package keyed
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// priorityWaiter — ожидающий захвата ключа PriorityMutex
+type priorityWaiter struct {
+	priority int
+	// Порядковый номер для очередности внутри одного приоритета
+	seq uint64
+	// Получает аренду при передаче ключа (буфер 1)
+	ready chan *Lease
+}
+
+// priorityKey — состояние одного ключа PriorityMutex
+type priorityKey struct {
+	holder *Lease
+	// Ожидающие по убыванию приоритета, при равенстве — по порядку прихода
+	waiters []*priorityWaiter
+}
+
+// Lease — удерживаемый ключ PriorityMutex
+type Lease struct {
+	m    *PriorityMutex
+	key  string
+	base int
+	// Эффективный приоритет с учетом наследования (защищен m.mutex)
+	effective int
+	released  bool
+}
+
+// PriorityMutex — набор мьютексов по ключам с приоритетами и наследованием приоритета
+// Ключ достается ожидающему с наибольшим приоритетом. Если держатель ключа
+// с низким приоритетом блокирует ожидающего с более высоким, эффективный
+// приоритет держателя поднимается до приоритета ожидающего (наследование
+// приоритета), а обработчик повышения переставляет работу держателя в
+// очереди планировщика (например, drr.Scheduler.Boost для задач с меткой
+// drr.LockLabel). Так задача с низким приоритетом не держит
+// высокоприоритетную за спиной задач со средним приоритетом
+// (инверсия приоритетов). Наследование не транзитивно: если держатель
+// сам ждет другой ключ, держатель того ключа не повышается
+type PriorityMutex struct {
+	// Вызывается при изменении эффективного приоритета держателя
+	onBoost func(key string, priority int)
+
+	// Защита keys, seq и состояния аренд
+	mutex sync.Mutex
+	keys  map[string]*priorityKey
+	seq   uint64
+}
+
+// NewPriorityMutex — функция создания набора мьютексов с приоритетами
+// onBoost (может быть nil) вызывается вне блокировок каждый раз, когда
+// меняется эффективный приоритет держателя ключа — при повышении из-за
+// нового ожидающего, при возврате, когда ожидающий уходит, и при передаче
+// ключа, если у нового держателя эффективный приоритет другой
+func NewPriorityMutex(onBoost func(key string, priority int)) *PriorityMutex {
+	return &PriorityMutex{onBoost: onBoost, keys: make(map[string]*priorityKey)}
+}
+
+// Lock — метод захвата ключа с приоритетом priority
+// Ожидание прерывается отменой ctx
+func (m *PriorityMutex) Lock(ctx context.Context, key string, priority int) (*Lease, error) {
+	m.mutex.Lock()
+	k, ok := m.keys[key]
+	if !ok {
+		k = &priorityKey{}
+		m.keys[key] = k
+	}
+	if k.holder == nil {
+		k.holder = &Lease{m: m, key: key, base: priority, effective: priority}
+		lease := k.holder
+		m.mutex.Unlock()
+		return lease, nil
+	}
+
+	m.seq++
+	w := &priorityWaiter{priority: priority, seq: m.seq, ready: make(chan *Lease, 1)}
+	k.waiters = append(k.waiters, w)
+	sort.SliceStable(k.waiters, func(i, j int) bool {
+		return k.waiters[i].priority > k.waiters[j].priority
+	})
+	boost := m.inheritLocked(k)
+	m.mutex.Unlock()
+	boost()
+
+	select {
+	case lease := <-w.ready:
+		return lease, nil
+	case <-ctx.Done():
+	}
+
+	m.mutex.Lock()
+	select {
+	case lease := <-w.ready:
+		// Ключ передали одновременно с отменой — возвращаем его
+		m.mutex.Unlock()
+		lease.Unlock()
+		return nil, ctx.Err()
+	default:
+	}
+	for i, other := range k.waiters {
+		if other == w {
+			k.waiters = append(k.waiters[:i], k.waiters[i+1:]...)
+			break
+		}
+	}
+	boost = m.inheritLocked(k)
+	m.mutex.Unlock()
+	boost()
+	return nil, ctx.Err()
+}
+
+// inheritLocked — метод пересчета эффективного приоритета держателя ключа
+// Возвращает функцию вызова обработчика повышения, которую нужно
+// выполнить после снятия блокировки
+func (m *PriorityMutex) inheritLocked(k *priorityKey) func() {
+	h := k.holder
+	if h == nil {
+		return func() {}
+	}
+	effective := h.base
+	if len(k.waiters) > 0 && k.waiters[0].priority > effective {
+		effective = k.waiters[0].priority
+	}
+	if effective == h.effective {
+		return func() {}
+	}
+	h.effective = effective
+	if m.onBoost == nil {
+		return func() {}
+	}
+	key := h.key
+	return func() { m.onBoost(key, effective) }
+}
+
+// Unlock — метод освобождения ключа
+// Ключ передается ожидающему с наибольшим приоритетом. Повторный вызов
+// приводит к панике, как и у sync.Mutex
+func (l *Lease) Unlock() {
+	m := l.m
+	m.mutex.Lock()
+	if l.released {
+		m.mutex.Unlock()
+		panic("keyed: повторный Unlock ключа " + l.key)
+	}
+	l.released = true
+
+	k := m.keys[l.key]
+	if len(k.waiters) == 0 {
+		delete(m.keys, l.key)
+		m.mutex.Unlock()
+		return
+	}
+	w := k.waiters[0]
+	k.waiters = k.waiters[1:]
+	// Эффективный приоритет ключа пока прежний: inheritLocked сообщит
+	// об изменении, если у нового держателя он другой
+	next := &Lease{m: m, key: l.key, base: w.priority, effective: l.effective}
+	k.holder = next
+	boost := m.inheritLocked(k)
+	w.ready <- next
+	m.mutex.Unlock()
+	boost()
+}
+
+// Priority — метод получения эффективного приоритета держателя
+// Не меньше приоритета, с которым ключ был захвачен, и приоритета
+// любого из ожидающих этот ключ
+func (l *Lease) Priority() int {
+	l.m.mutex.Lock()
+	defer l.m.mutex.Unlock()
+	return l.effective
+}
+
+// Key — метод получения захваченного ключа
+func (l *Lease) Key() string {
+	return l.key
+}
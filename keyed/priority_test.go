@@ -0,0 +1,51 @@
+package keyed
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestPriorityMutexBoostCallbacks — обработчик узнает о повышении держателя
+// и о конце наследования при передаче ключа
+func TestPriorityMutexBoostCallbacks(t *testing.T) {
+	var mu sync.Mutex
+	var boosts []int
+	m := NewPriorityMutex(func(key string, priority int) {
+		mu.Lock()
+		boosts = append(boosts, priority)
+		mu.Unlock()
+	})
+
+	low, err := m.Lock(context.Background(), "k", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(chan *Lease, 1)
+	go func() {
+		lease, err := m.Lock(context.Background(), "k", 10)
+		if err != nil {
+			t.Error(err)
+		}
+		got <- lease
+	}()
+	for low.Priority() != 10 {
+		time.Sleep(time.Millisecond)
+	}
+	low.Unlock()
+	high := <-got
+	if high.Priority() != 10 {
+		t.Fatalf("приоритет нового держателя %d, ожидался 10", high.Priority())
+	}
+	high.Unlock()
+
+	// Повышение до 10 при появлении ожидающего; при передаче ключа
+	// эффективный приоритет не меняется, а без повышения освобождение молчит
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []int{10}; !reflect.DeepEqual(boosts, want) {
+		t.Fatalf("вызовы обработчика %v, ожидались %v", boosts, want)
+	}
+}
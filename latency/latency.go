@@ -0,0 +1,183 @@
+package latency
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// Параметры гистограммы: значения хранятся в логарифмических корзинах,
+// каждая степень двойки делится на subBuckets равных частей.
+// Это дает относительную погрешность не хуже 1/subBuckets (~6%)
+// при фиксированном объеме памяти и без блокировок при записи
+const (
+	subBucketBits = 4
+	subBuckets    = 1 << subBucketBits
+	bucketCount   = subBuckets + (64-subBucketBits)*subBuckets
+)
+
+// Recorder — потокобезопасный регистратор задержек
+// Накапливает распределение длительностей и позволяет получать перцентили
+// в потоковом режиме, не храня сами значения
+type Recorder struct {
+	// Счетчики попаданий в каждую корзину гистограммы
+	counts [bucketCount]atomic.Uint64
+	// Общее количество измерений
+	total atomic.Uint64
+	// Сумма всех измерений в наносекундах (для среднего значения)
+	sum atomic.Uint64
+	// Максимальное измеренное значение в наносекундах
+	max atomic.Uint64
+}
+
+// NewRecorder — функция создания регистратора задержек
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Record — метод добавления одного измерения
+// Отрицательные длительности считаются нулевыми
+func (r *Recorder) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	v := uint64(d)
+
+	r.counts[bucketIndex(v)].Add(1)
+	r.total.Add(1)
+	r.sum.Add(v)
+
+	for {
+		cur := r.max.Load()
+		if v <= cur || r.max.CompareAndSwap(cur, v) {
+			break
+		}
+	}
+}
+
+// Start — метод запуска секундомера
+// Возвращает функцию, которая при вызове записывает прошедшее время
+// и возвращает его. Удобно использовать так: defer rec.Start()()
+func (r *Recorder) Start() func() time.Duration {
+	start := time.Now()
+	return func() time.Duration {
+		d := time.Since(start)
+		r.Record(d)
+		return d
+	}
+}
+
+// Count — метод получения количества измерений
+func (r *Recorder) Count() uint64 {
+	return r.total.Load()
+}
+
+// Mean — метод получения среднего значения измерений
+func (r *Recorder) Mean() time.Duration {
+	n := r.total.Load()
+	if n == 0 {
+		return 0
+	}
+	return time.Duration(r.sum.Load() / n)
+}
+
+// Max — метод получения максимального измерения
+func (r *Recorder) Max() time.Duration {
+	return time.Duration(r.max.Load())
+}
+
+// Percentile — метод получения перцентиля распределения
+// p задается в процентах (например, 50, 99, 99.9)
+// Результат приблизительный, с точностью до ширины корзины гистограммы
+func (r *Recorder) Percentile(p float64) time.Duration {
+	if p < 0 {
+		p = 0
+	}
+	if p > 100 {
+		p = 100
+	}
+
+	n := r.total.Load()
+	if n == 0 {
+		return 0
+	}
+
+	// Ранг искомого измерения (от 1 до n) по методу ближайшего ранга
+	rank := uint64(math.Ceil(p / 100 * float64(n)))
+	if rank == 0 {
+		rank = 1
+	}
+
+	var seen uint64
+	for i := range r.counts {
+		seen += r.counts[i].Load()
+		if seen >= rank {
+			v := bucketValue(i)
+			// Оценка по корзине не должна превышать реальный максимум
+			if m := r.max.Load(); v > m {
+				v = m
+			}
+			return time.Duration(v)
+		}
+	}
+	return r.Max()
+}
+
+// Snapshot — снимок основных показателей регистратора
+type Snapshot struct {
+	Count uint64
+	Mean  time.Duration
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// Snapshot — метод получения снимка основных показателей
+// При конкурентной записи показатели снимка могут относиться
+// к немного разным моментам времени
+func (r *Recorder) Snapshot() Snapshot {
+	return Snapshot{
+		Count: r.Count(),
+		Mean:  r.Mean(),
+		P50:   r.Percentile(50),
+		P90:   r.Percentile(90),
+		P99:   r.Percentile(99),
+		Max:   r.Max(),
+	}
+}
+
+// Reset — метод сброса всех накопленных измерений
+func (r *Recorder) Reset() {
+	for i := range r.counts {
+		r.counts[i].Store(0)
+	}
+	r.total.Store(0)
+	r.sum.Store(0)
+	r.max.Store(0)
+}
+
+// bucketIndex — функция вычисления номера корзины для значения
+func bucketIndex(v uint64) int {
+	if v < subBuckets {
+		return int(v)
+	}
+	// Сдвиг, при котором у значения остается subBucketBits+1 старших бит
+	shift := bits.Len64(v) - subBucketBits - 1
+	mantissa := v >> uint(shift)
+	return subBuckets + shift*subBuckets + int(mantissa-subBuckets)
+}
+
+// bucketValue — функция получения представительного значения корзины
+// (середины ее диапазона)
+func bucketValue(i int) uint64 {
+	if i < subBuckets {
+		return uint64(i)
+	}
+	shift := (i - subBuckets) / subBuckets
+	mantissa := uint64((i-subBuckets)%subBuckets + subBuckets)
+	lower := mantissa << uint(shift)
+	width := uint64(1) << uint(shift)
+	return lower + width/2
+}
@@ -0,0 +1,71 @@
+package leaderelection
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"goroutines-example/clock"
+)
+
+// Backend — хранилище аренды лидерства
+// Реализации поверх etcd (lease + транзакция сравнения ключа), Consul
+// (сессия + KV acquire) или базы данных подключаются без изменений Elector
+type Backend interface {
+	// TryAcquire — попытка получить или продлить аренду для id на ttl
+	// Возвращает true, если аренда принадлежит id
+	TryAcquire(ctx context.Context, id string, ttl time.Duration) (bool, error)
+	// Release — досрочное освобождение аренды, если она принадлежит id
+	Release(ctx context.Context, id string) error
+}
+
+// MemoryBackend — хранилище аренды в памяти процесса
+// Подходит для тестов и для выбора лидера среди горутин одного процесса
+type MemoryBackend struct {
+	clock clock.Clock
+
+	// Защита holder и expires
+	mutex   sync.Mutex
+	holder  string
+	expires time.Time
+}
+
+var _ Backend = (*MemoryBackend)(nil)
+
+// NewMemoryBackend — функция создания хранилища аренды в памяти
+func NewMemoryBackend(c clock.Clock) *MemoryBackend {
+	return &MemoryBackend{clock: c}
+}
+
+// TryAcquire — метод попытки получить или продлить аренду
+func (b *MemoryBackend) TryAcquire(_ context.Context, id string, ttl time.Duration) (bool, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	now := b.clock.Now()
+	if b.holder != "" && b.holder != id && now.Before(b.expires) {
+		return false, nil
+	}
+	b.holder = id
+	b.expires = now.Add(ttl)
+	return true, nil
+}
+
+// Release — метод освобождения аренды
+func (b *MemoryBackend) Release(_ context.Context, id string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.holder == id {
+		b.holder = ""
+	}
+	return nil
+}
+
+// Holder — метод получения текущего держателя аренды ("" — нет)
+func (b *MemoryBackend) Holder() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.holder == "" || !b.clock.Now().Before(b.expires) {
+		return ""
+	}
+	return b.holder
+}
@@ -0,0 +1,254 @@
+package leaderelection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"goroutines-example/clock"
+)
+
+// ErrResigned — причина завершения Campaign после Resign
+var ErrResigned = errors.New("экземпляр отказался от лидерства")
+
+// Option — функциональная опция Elector
+type Option func(*Elector)
+
+// OnElected — опция обработчика избрания
+// fn запускается в отдельной горутине с контекстом, который отменяется
+// при потере лидерства, — в нем удобно крутить цикл, который должен
+// выполняться только на одном экземпляре
+func OnElected(fn func(ctx context.Context)) Option {
+	return func(e *Elector) {
+		e.onElected = fn
+	}
+}
+
+// OnDemoted — опция обработчика потери лидерства
+// Вызывается после отмены контекста OnElected и завершения fn
+func OnDemoted(fn func()) Option {
+	return func(e *Elector) {
+		e.onDemoted = fn
+	}
+}
+
+// WithRenewInterval — опция периода продления аренды (по умолчанию ttl/3)
+func WithRenewInterval(d time.Duration) Option {
+	return func(e *Elector) {
+		e.renew = d
+	}
+}
+
+// WithClock — опция задания источника времени (по умолчанию реальное время)
+func WithClock(c clock.Clock) Option {
+	return func(e *Elector) {
+		e.clock = c
+	}
+}
+
+// Elector — участник выбора лидера
+// Campaign периодически пытается получить или продлить аренду в Backend.
+// Получив ее, экземпляр становится лидером и запускает OnElected; не сумев
+// продлить (аренду перехватили или хранилище недоступно дольше ttl),
+// перестает быть лидером и вызывает OnDemoted
+type Elector struct {
+	backend Backend
+	id      string
+	ttl     time.Duration
+	renew   time.Duration
+	clock   clock.Clock
+
+	onElected func(ctx context.Context)
+	onDemoted func()
+
+	// Защита полей ниже
+	mutex  sync.Mutex
+	leader bool
+	// Момент, до которого действует последняя полученная аренда
+	leaseEnd time.Time
+	// Отмена контекста OnElected и сигнал о завершении его обработчика
+	stopLeading context.CancelFunc
+	leading     chan struct{}
+	// Отмена текущего Campaign (nil, если не запущен)
+	stopCampaign context.CancelFunc
+	resigned     bool
+}
+
+// New — функция создания участника выбора лидера
+// id — уникальный идентификатор экземпляра, ttl — срок аренды лидерства
+func New(backend Backend, id string, ttl time.Duration, opts ...Option) (*Elector, error) {
+	if backend == nil {
+		return nil, fmt.Errorf("хранилище аренды не задано")
+	}
+	if id == "" {
+		return nil, fmt.Errorf("идентификатор экземпляра не задан")
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("срок аренды должен быть положительным: %v", ttl)
+	}
+	e := &Elector{backend: backend, id: id, ttl: ttl, renew: ttl / 3, clock: clock.Real()}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if e.renew <= 0 || e.renew >= ttl {
+		return nil, fmt.Errorf("период продления должен быть меньше срока аренды: %v", e.renew)
+	}
+	return e, nil
+}
+
+// Campaign — метод участия в выборах до отмены ctx или Resign
+// Блокируется; при выходе лидерство снимается, а аренда освобождается.
+// Возвращает ErrResigned после Resign, иначе ошибку ctx
+func (e *Elector) Campaign(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	e.mutex.Lock()
+	if e.stopCampaign != nil {
+		e.mutex.Unlock()
+		return errors.New("выборы уже идут")
+	}
+	e.stopCampaign = cancel
+	e.resigned = false
+	e.mutex.Unlock()
+
+	defer func() {
+		e.demote()
+		e.backend.Release(context.Background(), e.id)
+		e.mutex.Lock()
+		e.stopCampaign = nil
+		e.mutex.Unlock()
+	}()
+
+	for {
+		e.attempt(ctx)
+		select {
+		case <-e.clock.After(e.renew):
+		case <-ctx.Done():
+			e.mutex.Lock()
+			resigned := e.resigned
+			e.mutex.Unlock()
+			if resigned {
+				return ErrResigned
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+// attempt — метод одной попытки получить или продлить аренду
+func (e *Elector) attempt(ctx context.Context) {
+	start := e.clock.Now()
+	attemptCtx, cancel := context.WithTimeout(ctx, e.renew)
+	held, err := e.backend.TryAcquire(attemptCtx, e.id, e.ttl)
+	cancel()
+
+	if err != nil {
+		// Хранилище недоступно — лидер остается лидером, только если
+		// аренда переживет следующую попытку продления; иначе уходит сразу,
+		// не дожидаясь, пока ее истечение заметит следующий опрос
+		e.mutex.Lock()
+		expiring := e.leader && !e.clock.Now().Add(e.renew).Before(e.leaseEnd)
+		e.mutex.Unlock()
+		if expiring {
+			e.demote()
+		}
+		return
+	}
+	if !held {
+		e.demote()
+		return
+	}
+
+	e.mutex.Lock()
+	e.leaseEnd = start.Add(e.ttl)
+	if e.leader || ctx.Err() != nil {
+		e.mutex.Unlock()
+		return
+	}
+	e.leader = true
+	leaderCtx, stop := context.WithCancel(ctx)
+	leading := make(chan struct{})
+	e.stopLeading, e.leading = stop, leading
+	onElected := e.onElected
+	e.mutex.Unlock()
+
+	go func() {
+		defer close(leading)
+		if onElected != nil {
+			onElected(leaderCtx)
+		}
+	}()
+	go e.watchLease(leaderCtx, leading)
+}
+
+// watchLease — метод снятия лидерства в момент истечения аренды
+// Попытка продления может зависнуть на хранилище до периода продления,
+// поэтому лидерство снимается по таймеру ровно в leaseEnd, а не при
+// следующем опросе: иначе бывший лидер продолжал бы работать, когда
+// аренду уже может получить другой экземпляр
+// term — сигнал завершения обработчика своего срока лидерства: снимается
+// только он, а не следующий срок
+func (e *Elector) watchLease(ctx context.Context, term chan struct{}) {
+	for {
+		e.mutex.Lock()
+		left := e.leaseEnd.Sub(e.clock.Now())
+		e.mutex.Unlock()
+		if left <= 0 {
+			e.demoteTerm(term)
+			return
+		}
+		select {
+		case <-e.clock.After(left):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// demote — метод снятия лидерства
+// Отменяет контекст OnElected, дожидается завершения обработчика
+// и вызывает OnDemoted
+func (e *Elector) demote() {
+	e.demoteTerm(nil)
+}
+
+// demoteTerm — метод снятия лидерства срока term (nil — любого текущего)
+func (e *Elector) demoteTerm(term chan struct{}) {
+	e.mutex.Lock()
+	if !e.leader || (term != nil && e.leading != term) {
+		e.mutex.Unlock()
+		return
+	}
+	e.leader = false
+	stop, leading := e.stopLeading, e.leading
+	e.stopLeading, e.leading = nil, nil
+	e.mutex.Unlock()
+
+	stop()
+	<-leading
+	if e.onDemoted != nil {
+		e.onDemoted()
+	}
+}
+
+// Resign — метод отказа от лидерства и участия в выборах
+// Завершает Campaign, который снимает лидерство и освобождает аренду,
+// чтобы другой экземпляр мог стать лидером, не дожидаясь ее истечения
+func (e *Elector) Resign() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.stopCampaign != nil {
+		e.resigned = true
+		e.stopCampaign()
+	}
+}
+
+// IsLeader — метод проверки, является ли экземпляр лидером
+func (e *Elector) IsLeader() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.leader
+}
@@ -0,0 +1,115 @@
+package leaderelection
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"goroutines-example/clock"
+	"goroutines-example/testsync"
+)
+
+var errUnavailable = errors.New("хранилище недоступно")
+
+// flakyBackend — хранилище, которое можно сделать недоступным
+type flakyBackend struct {
+	Backend
+	failing atomic.Bool
+}
+
+func (b *flakyBackend) TryAcquire(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	if b.failing.Load() {
+		return false, errUnavailable
+	}
+	return b.Backend.TryAcquire(ctx, id, ttl)
+}
+
+// TestFailover — лидер, потерявший хранилище, уходит до истечения аренды,
+// а другой экземпляр получает лидерство только после ее истечения
+func TestFailover(t *testing.T) {
+	const ttl = 3 * time.Second
+	fake := clock.NewFake(time.Unix(0, 0))
+	start := fake.Now()
+	mem := NewMemoryBackend(fake)
+	flaky := &flakyBackend{Backend: mem}
+
+	elected := make(chan context.Context, 1)
+	demoted := make(chan time.Duration, 1)
+	a, err := New(flaky, "a", ttl, WithClock(fake),
+		OnElected(func(ctx context.Context) { elected <- ctx }),
+		OnDemoted(func() { demoted <- fake.Since(start) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	campaignA := make(chan error, 1)
+	go func() { campaignA <- a.Campaign(ctx) }()
+
+	leaderCtx := <-elected
+	// Таймеры продления (каждую секунду) и истечения аренды
+	fake.BlockUntil(2)
+	flaky.failing.Store(true)
+
+	// t=1s: продление не удалось, но аренда до 3s переживет следующую попытку
+	fake.Advance(time.Second)
+	fake.BlockUntil(2)
+	if !a.IsLeader() {
+		t.Fatal("лидерство снято, хотя аренда еще действует")
+	}
+	// t=2s: аренда истечет раньше следующей попытки — лидер уходит сразу
+	fake.Advance(time.Second)
+	if at := <-demoted; at != 2*time.Second {
+		t.Fatalf("лидерство снято в %v, ожидалось в 2s", at)
+	}
+	if leaderCtx.Err() == nil {
+		t.Fatal("контекст OnElected не отменен при потере лидерства")
+	}
+
+	// Момент избрания b и то, был ли a в этот момент еще лидером
+	type election struct {
+		at      time.Duration
+		overlap bool
+	}
+	electedB := make(chan election, 1)
+	b, err := New(mem, "b", ttl, WithClock(fake), OnElected(func(context.Context) {
+		electedB <- election{at: fake.Since(start), overlap: a.IsLeader()}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	campaignB := make(chan error, 1)
+	go func() { campaignB <- b.Campaign(context.Background()) }()
+
+	// Аренда a, полученная в 0s, принадлежит ему до 3s даже после ухода
+	var got election
+	testsync.AwaitCondition(t, func() bool {
+		select {
+		case got = <-electedB:
+			return true
+		default:
+			fake.Advance(500 * time.Millisecond)
+			return false
+		}
+	}, 5*time.Second)
+	if got.at < ttl {
+		t.Fatalf("b стал лидером в %v, до истечения аренды a в %v", got.at, ttl)
+	}
+	if got.overlap {
+		t.Fatal("b стал лидером, пока a оставался лидером")
+	}
+
+	b.Resign()
+	if err := <-campaignB; !errors.Is(err, ErrResigned) {
+		t.Fatalf("Campaign после Resign вернул %v", err)
+	}
+	if h := mem.Holder(); h != "" {
+		t.Fatalf("после Resign аренду держит %q", h)
+	}
+	cancel()
+	if err := <-campaignA; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Campaign после отмены вернул %v", err)
+	}
+}
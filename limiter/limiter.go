@@ -0,0 +1,184 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"goroutines-example/bulkhead"
+	"goroutines-example/ratelimit"
+	"goroutines-example/semaphore"
+	"goroutines-example/shedder"
+)
+
+// Limiter — общий интерфейс ограничителей
+// Acquire ждет допуска с отменой через ctx и возвращает функцию
+// освобождения, которую нужно вызвать ровно один раз по окончании работы.
+// Ограничители, которым освобождение не нужно (например, по частоте),
+// возвращают пустую функцию. Промежуточные слои и комбинаторы пишутся
+// один раз против этого интерфейса.
+// Отсек и ограничитель нагрузки реализуют интерфейс напрямую; семафоры
+// (включая прерыватель TimeoutPolicy и файловый семафор) подключаются
+// через FromSemaphore, ограничители частоты (включая Redis) — через FromRate
+type Limiter interface {
+	Acquire(ctx context.Context) (release func(), err error)
+}
+
+var (
+	_ Limiter = (*bulkhead.Bulkhead)(nil)
+	_ Limiter = (*shedder.Shedder)(nil)
+	_ Limiter = Func(nil)
+)
+
+// Func — адаптер функции к интерфейсу Limiter
+type Func func(ctx context.Context) (release func(), err error)
+
+// Acquire — метод вызова функции
+func (f Func) Acquire(ctx context.Context) (func(), error) {
+	return f(ctx)
+}
+
+// FromSemaphore — функция представления семафора как Limiter
+// Допуск — одно разрешение, освобождение возвращает его семафору
+func FromSemaphore(sem semaphore.Semaphore) Limiter {
+	return Func(func(ctx context.Context) (func(), error) {
+		if err := sem.AcquireContext(ctx); err != nil {
+			return nil, err
+		}
+		return func() { sem.Release() }, nil
+	})
+}
+
+// FromRate — функция представления ограничителя частоты как Limiter
+// Допуск — один токен; освобождение ничего не делает
+func FromRate(l ratelimit.RateLimiter) Limiter {
+	return Func(func(ctx context.Context) (func(), error) {
+		if err := l.Wait(ctx); err != nil {
+			return nil, err
+		}
+		return func() {}, nil
+	})
+}
+
+// AllOf — функция объединения ограничителей по «и»
+// Допуск получается у всех ограничителей по порядку; если какой-то
+// отказал, уже полученные допуски освобождаются в обратном порядке и
+// возвращается его ошибка. Освобождение тоже идет в обратном порядке.
+// Например, AllOf(FromSemaphore(sem), FromRate(rl)) требует и
+// разрешение на конкурентность, и токен частоты
+func AllOf(limiters ...Limiter) Limiter {
+	return Func(func(ctx context.Context) (func(), error) {
+		releases := make([]func(), 0, len(limiters))
+		releaseAll := func() {
+			for i := len(releases) - 1; i >= 0; i-- {
+				releases[i]()
+			}
+		}
+		for i, l := range limiters {
+			release, err := l.Acquire(ctx)
+			if err != nil {
+				releaseAll()
+				return nil, fmt.Errorf("ограничитель %d: %w", i, err)
+			}
+			releases = append(releases, release)
+		}
+		var once sync.Once
+		return func() { once.Do(releaseAll) }, nil
+	})
+}
+
+// AnyOfError — ошибка AnyOf, когда отказали все ограничители
+type AnyOfError struct {
+	Errors []error
+}
+
+// Error — метод формирования текста ошибки
+func (e *AnyOfError) Error() string {
+	parts := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		parts[i] = fmt.Sprintf("[%d] %v", i, err)
+	}
+	return "все ограничители отказали: " + strings.Join(parts, "; ")
+}
+
+// Unwrap — метод получения вложенных ошибок
+func (e *AnyOfError) Unwrap() []error {
+	return e.Errors
+}
+
+// Is — метод сопоставления с вложенными ошибками для errors.Is
+// errors.Is в go 1.19 не разворачивает Unwrap() []error, поэтому обход ручной
+func (e *AnyOfError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As — метод поиска вложенной ошибки нужного типа для errors.As
+func (e *AnyOfError) As(target any) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// AnyOf — функция объединения ограничителей по «или»
+// Допуск запрашивается у всех ограничителей одновременно, и используется
+// первый полученный; остальные запросы отменяются, а допуски, полученные
+// ими позже, сразу освобождаются. Если отказали все, возвращается
+// *AnyOfError; при отмене ctx — ошибка ctx
+func AnyOf(limiters ...Limiter) Limiter {
+	return Func(func(ctx context.Context) (func(), error) {
+		if len(limiters) == 0 {
+			return nil, fmt.Errorf("не передано ни одного ограничителя")
+		}
+		raceCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		type outcome struct {
+			index   int
+			release func()
+			err     error
+		}
+		// Буфер на все ограничители, чтобы проигравшие не блокировались
+		outcomes := make(chan outcome, len(limiters))
+		for i, l := range limiters {
+			go func(i int, l Limiter) {
+				release, err := l.Acquire(raceCtx)
+				outcomes <- outcome{index: i, release: release, err: err}
+			}(i, l)
+		}
+
+		errs := make([]error, len(limiters))
+		for remaining := len(limiters); remaining > 0; remaining-- {
+			o := <-outcomes
+			if o.err != nil {
+				errs[o.index] = o.err
+				continue
+			}
+			cancel()
+			// Остальные исходы дочитываются в фоне, чтобы не ждать
+			// проигравших, а их допуски освобождаются
+			go func(remaining int) {
+				for ; remaining > 0; remaining-- {
+					if late := <-outcomes; late.err == nil {
+						late.release()
+					}
+				}
+			}(remaining - 1)
+			var once sync.Once
+			return func() { once.Do(o.release) }, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &AnyOfError{Errors: errs}
+	})
+}
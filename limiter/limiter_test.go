@@ -0,0 +1,39 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// errDenied — отказ ограничителя для тестов
+var errDenied = errors.New("отказано")
+
+// deniedError — отказ с дополнительными данными для проверки errors.As
+type deniedError struct{ name string }
+
+func (e *deniedError) Error() string { return "отказал " + e.name }
+
+// TestAnyOfErrorMatches — errors.Is и errors.As находят отказы отдельных
+// ограничителей внутри *AnyOfError
+func TestAnyOfErrorMatches(t *testing.T) {
+	deny := func(err error) Limiter {
+		return Func(func(ctx context.Context) (func(), error) { return nil, err })
+	}
+	_, err := AnyOf(deny(errDenied), deny(&deniedError{name: "b"})).Acquire(context.Background())
+
+	var anyErr *AnyOfError
+	if !errors.As(err, &anyErr) || len(anyErr.Errors) != 2 {
+		t.Fatalf("ожидалась *AnyOfError с двумя ошибками, получено %v", err)
+	}
+	if !errors.Is(err, errDenied) {
+		t.Fatalf("errors.Is не нашел отказ первого ограничителя в %v", err)
+	}
+	var denied *deniedError
+	if !errors.As(err, &denied) || denied.name != "b" {
+		t.Fatalf("errors.As не нашел отказ второго ограничителя в %v", err)
+	}
+	if errors.Is(err, context.Canceled) {
+		t.Fatal("errors.Is нашел ошибку, которой нет")
+	}
+}
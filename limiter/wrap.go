@@ -0,0 +1,59 @@
+package limiter
+
+import "context"
+
+// Wrap — функция оборачивания fn ограничителем
+// Возвращает функцию той же сигнатуры, которая перед каждым вызовом fn
+// получает допуск у l и освобождает его после возврата (в том числе при
+// панике). Ошибка получения допуска возвращается вместо вызова fn вместе
+// с нулевым значением T, так что существующий вызов защищается одной
+// строкой: fetch = limiter.Wrap(l, fetch)
+func Wrap[T any](l Limiter, fn func(ctx context.Context) (T, error)) func(ctx context.Context) (T, error) {
+	return func(ctx context.Context) (T, error) {
+		release, err := l.Acquire(ctx)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		defer release()
+		return fn(ctx)
+	}
+}
+
+// WrapErr — функция оборачивания ограничителем fn, возвращающей только ошибку
+func WrapErr(l Limiter, fn func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		release, err := l.Acquire(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
+		return fn(ctx)
+	}
+}
+
+// Wrap1 — функция оборачивания ограничителем fn с одним аргументом
+func Wrap1[A, T any](l Limiter, fn func(ctx context.Context, a A) (T, error)) func(ctx context.Context, a A) (T, error) {
+	return func(ctx context.Context, a A) (T, error) {
+		release, err := l.Acquire(ctx)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		defer release()
+		return fn(ctx, a)
+	}
+}
+
+// Wrap2 — функция оборачивания ограничителем fn с двумя аргументами
+func Wrap2[A, B, T any](l Limiter, fn func(ctx context.Context, a A, b B) (T, error)) func(ctx context.Context, a A, b B) (T, error) {
+	return func(ctx context.Context, a A, b B) (T, error) {
+		release, err := l.Acquire(ctx)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		defer release()
+		return fn(ctx, a, b)
+	}
+}
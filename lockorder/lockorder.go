@@ -0,0 +1,178 @@
+package lockorder
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"goroutines-example/internal/runtimeinfo"
+)
+
+// CycleError — обнаруженное нарушение порядка захвата блокировок
+// Path — цикл в графе порядка: каждая блокировка в нем когда-то
+// захватывалась при удержании предыдущей, а последняя — при удержании первой.
+// Это потенциальная взаимоблокировка, даже если в этот раз она не случилась
+type CycleError struct {
+	Path []string
+	// Место в коде, где был добавлен замыкающий цикл захват
+	Site string
+}
+
+// Error — метод получения текста ошибки
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("нарушение порядка захвата блокировок: %s (захват в %s)",
+		strings.Join(e.Path, " -> "), e.Site)
+}
+
+// Validator — отладочный проверяющий порядка захвата блокировок
+// Для каждой горутины запоминает удерживаемые блокировки и строит граф:
+// ребро A -> B означает, что B захватывалась при удержании A. Если новое
+// ребро замыкает цикл, порядок захвата где-то нарушен и о нем сообщается
+// через OnCycle (аналог lockdep в ядре Linux). Определение горутины
+// разбирает стек, поэтому проверяющий предназначен только для отладки
+type Validator struct {
+	// Обработчик найденного цикла; по умолчанию сообщение выводится в stderr
+	OnCycle func(err *CycleError)
+
+	// Защита всех полей ниже
+	mutex sync.Mutex
+	// Удерживаемые блокировки по номерам горутин в порядке захвата
+	held map[uint64][]string
+	// Граф порядка: from -> to -> место первого наблюдения
+	edges map[string]map[string]string
+	// Уже сообщенные пары, чтобы не повторять сообщения
+	reported map[[2]string]bool
+}
+
+// NewValidator — функция создания проверяющего
+func NewValidator() *Validator {
+	return &Validator{
+		OnCycle: func(err *CycleError) {
+			fmt.Fprintln(os.Stderr, err)
+		},
+		held:     make(map[uint64][]string),
+		edges:    make(map[string]map[string]string),
+		reported: make(map[[2]string]bool),
+	}
+}
+
+// Acquired — метод учета захвата блокировки name текущей горутиной
+// Вызывается после успешного захвата
+func (v *Validator) Acquired(name string) {
+	v.acquired(name, runtimeinfo.CallerSite(2))
+}
+
+// acquired — метод учета захвата с известным местом вызова
+func (v *Validator) acquired(name, site string) {
+	gid := runtimeinfo.GoroutineID()
+
+	v.mutex.Lock()
+	var cycles []*CycleError
+	for _, h := range v.held[gid] {
+		if h == name {
+			continue
+		}
+		if v.edges[h] == nil {
+			v.edges[h] = make(map[string]string)
+		}
+		if _, ok := v.edges[h][name]; ok {
+			continue
+		}
+		v.edges[h][name] = site
+		// Новое ребро h -> name замыкает цикл, если из name достижима h
+		if path := v.pathLocked(name, h); path != nil && !v.reported[[2]string{h, name}] {
+			v.reported[[2]string{h, name}] = true
+			cycles = append(cycles, &CycleError{Path: append([]string{h}, path...), Site: site})
+		}
+	}
+	v.held[gid] = append(v.held[gid], name)
+	onCycle := v.OnCycle
+	v.mutex.Unlock()
+
+	for _, err := range cycles {
+		onCycle(err)
+	}
+}
+
+// Released — метод учета освобождения блокировки name
+// Разрешение семафора может освобождать другая горутина, поэтому, если
+// текущая горутина не удерживает name, она ищется у остальных
+func (v *Validator) Released(name string) {
+	gid := runtimeinfo.GoroutineID()
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if v.removeLocked(gid, name) {
+		return
+	}
+	for other := range v.held {
+		if v.removeLocked(other, name) {
+			return
+		}
+	}
+}
+
+// removeLocked — метод удаления последнего захвата name горутиной gid
+func (v *Validator) removeLocked(gid uint64, name string) bool {
+	list := v.held[gid]
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i] == name {
+			list = append(list[:i], list[i+1:]...)
+			if len(list) == 0 {
+				delete(v.held, gid)
+			} else {
+				v.held[gid] = list
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// pathLocked — метод поиска пути from -> ... -> to в графе порядка
+func (v *Validator) pathLocked(from, to string) []string {
+	visited := map[string]bool{}
+	var dfs func(n string) []string
+	dfs = func(n string) []string {
+		if n == to {
+			return []string{n}
+		}
+		visited[n] = true
+		for next := range v.edges[n] {
+			if visited[next] {
+				continue
+			}
+			if p := dfs(next); p != nil {
+				return append([]string{n}, p...)
+			}
+		}
+		return nil
+	}
+	return dfs(from)
+}
+
+// locker — блокировка с учетом порядка захвата
+type locker struct {
+	v    *Validator
+	name string
+	l    sync.Locker
+}
+
+// Wrap — метод обертки блокировки для учета порядка захвата
+// Подходит для sync.Mutex и для семафора через CountingSemaphore.AsLocker
+func (v *Validator) Wrap(name string, l sync.Locker) sync.Locker {
+	return &locker{v: v, name: name, l: l}
+}
+
+// Lock — метод захвата блокировки
+func (l *locker) Lock() {
+	l.l.Lock()
+	l.v.acquired(l.name, runtimeinfo.CallerSite(2))
+}
+
+// Unlock — метод освобождения блокировки
+func (l *locker) Unlock() {
+	l.v.Released(l.name)
+	l.l.Unlock()
+}
@@ -0,0 +1,191 @@
+package mapreduce
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"goroutines-example/cause"
+)
+
+// Stats — статистика фаз MapReduce
+type Stats struct {
+	// Количество отображенных элементов
+	Mapped int
+	// Время фазы отображения (от запуска до завершения последнего mapper)
+	MapDuration time.Duration
+	// Количество вызовов reducer
+	Reductions int
+	// Время, затраченное на свертку: суммарное время в reducer при
+	// последовательной свертке или время фазы свертки при древовидной
+	ReduceDuration time.Duration
+}
+
+// options — настройки MapReduce
+type options struct {
+	parallelism int
+	tree        bool
+}
+
+// Option — функциональная опция MapReduce
+type Option func(*options)
+
+// WithParallelism — опция ограничения количества одновременных mapper
+// По умолчанию — количество элементов
+func WithParallelism(n int) Option {
+	return func(o *options) {
+		o.parallelism = n
+	}
+}
+
+// WithTreeReduce — опция древовидной свертки для ассоциативного reducer
+// Результаты отображения сворачиваются попарно параллельно в порядке
+// элементов: за log2(N) уровней вместо N последовательных вызовов
+func WithTreeReduce() Option {
+	return func(o *options) {
+		o.tree = true
+	}
+}
+
+// MapReduce — функция параллельного отображения со сверткой
+// mapper вызывается для каждого элемента, не более чем в parallelism
+// горутинах. По умолчанию результаты сворачиваются последовательно в
+// отдельной горутине по мере готовности, то есть в порядке завершения
+// mapper — reducer должен быть коммутативным и ассоциативным. С опцией
+// WithTreeReduce свертка выполняется попарно параллельно после отображения
+// в порядке элементов, и достаточно ассоциативности. Первая ошибка mapper
+// отменяет остальные и возвращается вместе со статистикой. Для пустого
+// списка возвращается нулевое значение R
+func MapReduce[T, R any](ctx context.Context, items []T, mapper func(ctx context.Context, item T) (R, error), reducer func(a, b R) R, opts ...Option) (R, Stats, error) {
+	var zero R
+	o := options{parallelism: len(items)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.parallelism <= 0 || o.parallelism > len(items) {
+		o.parallelism = len(items)
+	}
+	var stats Stats
+	if len(items) == 0 {
+		return zero, stats, nil
+	}
+
+	ctx, cancel := cause.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	type mapped struct {
+		index int
+		value R
+	}
+	var (
+		errOnce  sync.Once
+		firstErr error
+		values   = make(chan mapped, o.parallelism)
+		indexes  = make(chan int)
+		wg       sync.WaitGroup
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel(err)
+		})
+	}
+
+	mapStart := time.Now()
+	for w := 0; w < o.parallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if ctx.Err() != nil {
+					continue
+				}
+				v, err := mapper(ctx, items[i])
+				if err != nil {
+					fail(err)
+					continue
+				}
+				values <- mapped{index: i, value: v}
+			}
+		}()
+	}
+	go func() {
+		defer close(values)
+		defer wg.Wait()
+		defer close(indexes)
+		for i := range items {
+			select {
+			case indexes <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var result R
+	if o.tree {
+		results := make([]R, len(items))
+		for v := range values {
+			results[v.index] = v.value
+			stats.Mapped++
+		}
+		stats.MapDuration = time.Since(mapStart)
+		if firstErr != nil {
+			return zero, stats, firstErr
+		}
+		if err := ctx.Err(); err != nil {
+			return zero, stats, cause.Err(ctx)
+		}
+
+		reduceStart := time.Now()
+		result, stats.Reductions = treeReduce(results, reducer)
+		stats.ReduceDuration = time.Since(reduceStart)
+		return result, stats, nil
+	}
+
+	// Последовательная свертка в текущей горутине по мере готовности
+	first := true
+	for v := range values {
+		stats.Mapped++
+		if first {
+			result, first = v.value, false
+			continue
+		}
+		reduceStart := time.Now()
+		result = reducer(result, v.value)
+		stats.ReduceDuration += time.Since(reduceStart)
+		stats.Reductions++
+	}
+	stats.MapDuration = time.Since(mapStart)
+	if firstErr != nil {
+		return zero, stats, firstErr
+	}
+	if ctx.Err() != nil {
+		return zero, stats, cause.Err(ctx)
+	}
+	return result, stats, nil
+}
+
+// treeReduce — функция попарной параллельной свертки с сохранением порядка
+// Возвращает результат и количество вызовов reducer
+func treeReduce[R any](values []R, reducer func(a, b R) R) (R, int) {
+	calls := 0
+	for len(values) > 1 {
+		next := make([]R, (len(values)+1)/2)
+		var wg sync.WaitGroup
+		for i := 0; i+1 < len(values); i += 2 {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				next[i/2] = reducer(values[i], values[i+1])
+			}(i)
+			calls++
+		}
+		if len(values)%2 == 1 {
+			next[len(next)-1] = values[len(values)-1]
+		}
+		wg.Wait()
+		values = next
+	}
+	return values[0], calls
+}
@@ -0,0 +1,181 @@
+package membership
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"goroutines-example/clock"
+)
+
+// Store — таблица пульсов экземпляров
+// Реализуется поверх общего хранилища (база данных, Redis, etcd):
+// экземпляр периодически обновляет свою запись, а живыми считаются
+// записи, обновленные не раньше ttl назад
+type Store interface {
+	// Beat — запись пульса экземпляра id в момент at
+	Beat(ctx context.Context, id string, at time.Time) error
+	// Remove — удаление записи экземпляра id
+	Remove(ctx context.Context, id string) error
+	// List — все записи: экземпляр и время последнего пульса
+	List(ctx context.Context) (map[string]time.Time, error)
+}
+
+// MemoryStore — таблица пульсов в памяти процесса (для тестов)
+type MemoryStore struct {
+	// Защита beats
+	mutex sync.Mutex
+	beats map[string]time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore — функция создания таблицы пульсов в памяти
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{beats: make(map[string]time.Time)}
+}
+
+// Beat — метод записи пульса
+func (s *MemoryStore) Beat(_ context.Context, id string, at time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.beats[id] = at
+	return nil
+}
+
+// Remove — метод удаления записи
+func (s *MemoryStore) Remove(_ context.Context, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.beats, id)
+	return nil
+}
+
+// List — метод получения всех записей
+func (s *MemoryStore) List(context.Context) (map[string]time.Time, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	out := make(map[string]time.Time, len(s.beats))
+	for id, at := range s.beats {
+		out[id] = at
+	}
+	return out, nil
+}
+
+// Heartbeat — состав кластера на таблице пульсов
+// Join запускает фоновое обновление пульса экземпляра каждые ttl/3,
+// Leave останавливает его и удаляет запись. Экземпляр, переставший
+// обновлять пульс (например, упавший), выпадает из состава через ttl
+type Heartbeat struct {
+	store Store
+	ttl   time.Duration
+	clock clock.Clock
+
+	// Защита joined
+	mutex sync.Mutex
+	// Остановка фонового обновления пульса по экземплярам
+	joined map[string]context.CancelFunc
+}
+
+var _ Membership = (*Heartbeat)(nil)
+
+// NewHeartbeat — функция создания состава кластера на таблице пульсов
+// ttl — время, через которое экземпляр без пульса считается ушедшим
+func NewHeartbeat(store Store, ttl time.Duration, c clock.Clock) (*Heartbeat, error) {
+	if store == nil {
+		return nil, fmt.Errorf("таблица пульсов не задана")
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("время жизни пульса должно быть положительным: %v", ttl)
+	}
+	return &Heartbeat{store: store, ttl: ttl, clock: c, joined: make(map[string]context.CancelFunc)}, nil
+}
+
+// Join — метод регистрации экземпляра с фоновым обновлением пульса
+// Первый пульс записывается синхронно; его ошибка возвращается
+func (h *Heartbeat) Join(ctx context.Context, id string) error {
+	if err := h.store.Beat(ctx, id, h.clock.Now()); err != nil {
+		return err
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if _, ok := h.joined[id]; ok {
+		return nil
+	}
+	beatCtx, cancel := context.WithCancel(context.Background())
+	h.joined[id] = cancel
+	go func() {
+		for {
+			select {
+			case <-h.clock.After(h.ttl / 3):
+				// Ошибка одного пульса не критична: запись устареет
+				// только после нескольких пропусков подряд
+				h.store.Beat(beatCtx, id, h.clock.Now())
+			case <-beatCtx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Leave — метод выхода экземпляра: пульс останавливается, запись удаляется
+func (h *Heartbeat) Leave(ctx context.Context, id string) error {
+	h.mutex.Lock()
+	if cancel, ok := h.joined[id]; ok {
+		cancel()
+		delete(h.joined, id)
+	}
+	h.mutex.Unlock()
+	return h.store.Remove(ctx, id)
+}
+
+// Members — метод получения живых экземпляров
+func (h *Heartbeat) Members(ctx context.Context) ([]string, error) {
+	beats, err := h.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := h.clock.Now()
+	out := make([]string, 0, len(beats))
+	for id, at := range beats {
+		if now.Sub(at) < h.ttl {
+			out = append(out, id)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// Subscribe — метод подписки на изменения состава
+// Таблица опрашивается каждые ttl/3; ошибки опроса пропускаются.
+// Медленный подписчик получает последний состав, а не все промежуточные
+func (h *Heartbeat) Subscribe(ctx context.Context) <-chan []string {
+	out := make(chan []string, 1)
+	go func() {
+		defer close(out)
+		var last []string
+		first := true
+		for {
+			if members, err := h.Members(ctx); err == nil && (first || !equal(members, last)) {
+				first = false
+				last = members
+				// Заменяем непрочитанное значение свежим
+				select {
+				case <-out:
+				default:
+				}
+				out <- members
+			}
+			select {
+			case <-h.clock.After(h.ttl / 3):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
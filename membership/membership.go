@@ -0,0 +1,75 @@
+package membership
+
+import (
+	"context"
+	"sort"
+
+	"goroutines-example/partition"
+)
+
+// Membership — состав кластера
+// Общая точка зависимости для распределенных вариантов примитивов
+// (распределитель разделов, ограничители), чтобы каждый из них не
+// изобретал свой способ узнавать о живых экземплярах
+type Membership interface {
+	// Join — регистрация экземпляра id в кластере
+	Join(ctx context.Context, id string) error
+	// Leave — выход экземпляра id из кластера
+	Leave(ctx context.Context, id string) error
+	// Members — отсортированный список живых экземпляров
+	Members(ctx context.Context) ([]string, error)
+	// Subscribe — канал со списком экземпляров при каждом изменении
+	// Первым приходит текущий состав; канал закрывается при отмене ctx
+	Subscribe(ctx context.Context) <-chan []string
+}
+
+// Static — неизменный состав кластера из конфигурации
+// Join и Leave ничего не делают
+type Static []string
+
+var (
+	_ Membership           = Static(nil)
+	_ partition.Membership = Static(nil)
+)
+
+// Join — метод регистрации экземпляра (ничего не делает)
+func (s Static) Join(context.Context, string) error {
+	return nil
+}
+
+// Leave — метод выхода экземпляра (ничего не делает)
+func (s Static) Leave(context.Context, string) error {
+	return nil
+}
+
+// Members — метод получения списка экземпляров
+func (s Static) Members(context.Context) ([]string, error) {
+	out := append([]string(nil), s...)
+	sort.Strings(out)
+	return out, nil
+}
+
+// Subscribe — метод подписки на состав: приходит одно значение
+func (s Static) Subscribe(ctx context.Context) <-chan []string {
+	out := make(chan []string, 1)
+	members, _ := s.Members(ctx)
+	out <- members
+	go func() {
+		<-ctx.Done()
+		close(out)
+	}()
+	return out
+}
+
+// equal — функция сравнения отсортированных списков экземпляров
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,166 @@
+package membudget
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"math"
+	"runtime/debug"
+	"sync"
+)
+
+// waiter — ожидающий выделения памяти
+type waiter struct {
+	bytes int64
+	ready chan struct{}
+}
+
+// Limiter — ограничитель по бюджету памяти
+// В отличие от счетного семафора, захват измеряется в байтах: крупное
+// декодирование занимает большую часть бюджета, мелкое — меньшую.
+// Ожидающие обслуживаются по очереди (FIFO), чтобы крупные запросы
+// не голодали из-за постоянного потока мелких
+type Limiter struct {
+	// Защита состояния
+	mutex sync.Mutex
+	// Бюджет в байтах
+	capacity int64
+	// Занято байт
+	used int64
+	// Очередь ожидающих
+	waiters list.List
+}
+
+// NewLimiter — функция создания ограничителя с бюджетом bytes байт
+func NewLimiter(bytes int64) (*Limiter, error) {
+	if bytes <= 0 {
+		return nil, fmt.Errorf("бюджет памяти должен быть положительным: %d", bytes)
+	}
+	return &Limiter{capacity: bytes}, nil
+}
+
+// FromMemoryLimit — функция создания ограничителя с бюджетом,
+// равным доле fraction от лимита памяти процесса (GOMEMLIMIT)
+// Возвращает ошибку, если лимит памяти процессу не задан
+func FromMemoryLimit(fraction float64) (*Limiter, error) {
+	if fraction <= 0 || fraction > 1 {
+		return nil, fmt.Errorf("доля лимита памяти должна быть в диапазоне (0, 1]: %v", fraction)
+	}
+	// Отрицательный аргумент не меняет лимит, а только возвращает текущий
+	limit := debug.SetMemoryLimit(-1)
+	if limit == math.MaxInt64 {
+		return nil, fmt.Errorf("лимит памяти процесса не задан (GOMEMLIMIT)")
+	}
+	return NewLimiter(int64(float64(limit) * fraction))
+}
+
+// Acquire — метод захвата bytes байт бюджета
+// Блокируется, пока бюджета не хватит или пока не отменен ctx
+func (l *Limiter) Acquire(ctx context.Context, bytes int64) error {
+	if bytes <= 0 {
+		return fmt.Errorf("размер должен быть положительным: %d", bytes)
+	}
+
+	l.mutex.Lock()
+	if bytes > l.capacity {
+		l.mutex.Unlock()
+		return fmt.Errorf("запрошено %d байт при бюджете %d", bytes, l.capacity)
+	}
+	if l.capacity-l.used >= bytes && l.waiters.Len() == 0 {
+		l.used += bytes
+		l.mutex.Unlock()
+		return nil
+	}
+
+	w := &waiter{bytes: bytes, ready: make(chan struct{})}
+	elem := l.waiters.PushBack(w)
+	l.mutex.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	select {
+	case <-w.ready:
+		// Бюджет выделен одновременно с отменой — возвращаем его
+		l.used -= bytes
+		l.notifyLocked()
+	default:
+		isFront := l.waiters.Front() == elem
+		l.waiters.Remove(elem)
+		// Если ушел первый в очереди, следующие могут поместиться в бюджет
+		if isFront {
+			l.notifyLocked()
+		}
+	}
+	return ctx.Err()
+}
+
+// TryAcquire — метод захвата bytes байт без ожидания
+func (l *Limiter) TryAcquire(bytes int64) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if bytes <= 0 || l.capacity-l.used < bytes || l.waiters.Len() > 0 {
+		return false
+	}
+	l.used += bytes
+	return true
+}
+
+// Release — метод возврата bytes байт в бюджет
+func (l *Limiter) Release(bytes int64) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if bytes <= 0 || bytes > l.used {
+		return fmt.Errorf("попытка вернуть %d байт при занятых %d", bytes, l.used)
+	}
+	l.used -= bytes
+	l.notifyLocked()
+	return nil
+}
+
+// notifyLocked — метод выдачи бюджета ожидающим по порядку очереди
+// Вызывается при захваченном мьютексе
+func (l *Limiter) notifyLocked() {
+	for {
+		front := l.waiters.Front()
+		if front == nil {
+			return
+		}
+		w := front.Value.(*waiter)
+		if l.capacity-l.used < w.bytes {
+			// Первый в очереди не помещается — остальные ждут его
+			return
+		}
+		l.used += w.bytes
+		l.waiters.Remove(front)
+		close(w.ready)
+	}
+}
+
+// Capacity — метод получения бюджета в байтах
+func (l *Limiter) Capacity() int64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.capacity
+}
+
+// Used — метод получения количества занятых байт
+func (l *Limiter) Used() int64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.used
+}
+
+// Available — метод получения количества свободных байт
+func (l *Limiter) Available() int64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.capacity - l.used
+}
@@ -0,0 +1,300 @@
+package mux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Input — входной канал мультиплексора
+type Input[T any] struct {
+	// Имя входа для метрик
+	Name string
+	// Канал с событиями; мультиплексор только читает из него
+	C <-chan T
+	// Вес входа: при постоянном потоке на всех входах вход получает
+	// долю Weight/(сумма весов) выходного канала
+	Weight int
+}
+
+// InputStats — метрики одного входа
+type InputStats struct {
+	Name   string
+	Weight int
+	// Количество переданных в выходной канал значений
+	Received uint64
+	// Максимальный интервал между значениями, пока во входе были данные
+	MaxWait time.Duration
+	// Количество интервалов, превысивших порог голодания
+	Starvations uint64
+	// Входной канал закрыт
+	Closed bool
+}
+
+// input — состояние входа
+type input[T any] struct {
+	Input[T]
+	// Текущий кредит взвешенного кругового обхода
+	current int
+	closed  bool
+	// Время последнего обслуживания и признак того, что с тех пор
+	// вход не оказывался пустым
+	lastServed time.Time
+	backlogged bool
+	stats      InputStats
+}
+
+// Option — опция мультиплексора
+type Option func(*options)
+
+// options — настройки мультиплексора
+type options struct {
+	buffer    int
+	threshold time.Duration
+	now       func() time.Time
+}
+
+// WithBuffer — опция размера буфера выходного канала
+func WithBuffer(size int) Option {
+	return func(o *options) {
+		o.buffer = size
+	}
+}
+
+// WithStarvationThreshold — опция порога голодания
+// Интервал между значениями входа с данными дольше threshold
+// учитывается в InputStats.Starvations
+func WithStarvationThreshold(threshold time.Duration) Option {
+	return func(o *options) {
+		o.threshold = threshold
+	}
+}
+
+// Multiplexer — взвешенный справедливый мультиплексор каналов
+// Читает из нескольких входов и пишет в один выходной канал. Очередной
+// вход выбирается сглаженным взвешенным круговым обходом: вход с
+// наибольшим накопленным кредитом проверяется первым, поэтому при
+// постоянном потоке на всех входах вход с весом w получает долю w/W
+// выхода, и ни один вход с данными не ждет дольше одного обхода.
+// Вход, оказавшийся пустым, теряет накопленный кредит, чтобы после
+// простоя не вытеснять остальных. Для объединения потоков событий
+// разной важности
+type Multiplexer[T any] struct {
+	inputs []*input[T]
+	out    chan T
+	opts   options
+
+	// Защита метрик входов
+	mutex sync.Mutex
+	// Run уже запущен
+	started bool
+}
+
+// NewMultiplexer — функция создания мультиплексора
+// inputs — входы с положительными весами
+// opts — дополнительные опции (буфер выхода, порог голодания)
+func NewMultiplexer[T any](inputs []Input[T], opts ...Option) (*Multiplexer[T], error) {
+	if len(inputs) == 0 {
+		return nil, errors.New("нужен хотя бы один вход")
+	}
+	o := options{now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.buffer < 0 {
+		return nil, errors.New("размер буфера не может быть отрицательным")
+	}
+
+	m := &Multiplexer[T]{out: make(chan T, o.buffer), opts: o}
+	for _, in := range inputs {
+		if in.Weight <= 0 {
+			return nil, fmt.Errorf("вес входа %q должен быть положительным", in.Name)
+		}
+		if in.C == nil {
+			return nil, fmt.Errorf("канал входа %q не задан", in.Name)
+		}
+		m.inputs = append(m.inputs, &input[T]{
+			Input: in,
+			stats: InputStats{Name: in.Name, Weight: in.Weight},
+		})
+	}
+	return m, nil
+}
+
+// Out — метод получения выходного канала
+// Канал закрывается, когда Run завершается
+func (m *Multiplexer[T]) Out() <-chan T {
+	return m.out
+}
+
+// Run — метод передачи значений из входов в выходной канал
+// Блокируется, пока не закроются все входы или не будет отменен ctx,
+// после чего закрывает выходной канал. Запускается один раз
+func (m *Multiplexer[T]) Run(ctx context.Context) error {
+	m.mutex.Lock()
+	if m.started {
+		m.mutex.Unlock()
+		return errors.New("мультиплексор уже запущен")
+	}
+	m.started = true
+	m.mutex.Unlock()
+	defer close(m.out)
+
+	for {
+		v, ok, err := m.next(ctx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		select {
+		case m.out <- v:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// next — метод получения следующего значения с учетом весов
+// Возвращает ok == false, когда все входы закрыты
+func (m *Multiplexer[T]) next(ctx context.Context) (T, bool, error) {
+	var zero T
+	for {
+		open := m.candidates()
+		if len(open) == 0 {
+			return zero, false, nil
+		}
+
+		// Начисляем кредит и проверяем входы по убыванию кредита.
+		// С обслуженного входа списывается кредит, начисленный входам
+		// с данными, поэтому сумма их кредитов не дрейфует
+		charge := 0
+		for _, in := range open {
+			in.current += in.Weight
+			charge += in.Weight
+		}
+		sort.SliceStable(open, func(i, j int) bool {
+			return open[i].current > open[j].current
+		})
+		for _, in := range open {
+			select {
+			case v, ok := <-in.C:
+				if !ok {
+					charge -= in.Weight
+					m.markClosed(in)
+					continue
+				}
+				m.served(in, charge)
+				return v, true, nil
+			default:
+				charge -= in.Weight
+				m.idle(in)
+			}
+		}
+
+		// Все входы пусты — ждем первое значение на любом из них
+		if len(m.candidates()) == 0 {
+			return zero, false, nil
+		}
+		in, v, ok, err := m.wait(ctx)
+		if err != nil {
+			return zero, false, err
+		}
+		if !ok {
+			m.markClosed(in)
+			continue
+		}
+		m.served(in, 0)
+		return v, true, nil
+	}
+}
+
+// candidates — метод получения незакрытых входов
+func (m *Multiplexer[T]) candidates() []*input[T] {
+	open := make([]*input[T], 0, len(m.inputs))
+	for _, in := range m.inputs {
+		if !in.closed {
+			open = append(open, in)
+		}
+	}
+	return open
+}
+
+// wait — метод блокирующего ожидания значения на любом незакрытом входе
+func (m *Multiplexer[T]) wait(ctx context.Context) (*input[T], T, bool, error) {
+	var zero T
+	open := m.candidates()
+	cases := make([]reflect.SelectCase, 0, len(open)+1)
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+	for _, in := range open {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(in.C)})
+	}
+	chosen, value, ok := reflect.Select(cases)
+	if chosen == 0 {
+		return nil, zero, false, ctx.Err()
+	}
+	in := open[chosen-1]
+	if !ok {
+		return in, zero, false, nil
+	}
+	// Нулевое значение интерфейсного T приходит как nil: утверждение типа
+	// с проверкой не паникует на нем и дает нулевое значение T
+	v, _ := value.Interface().(T)
+	return in, v, true, nil
+}
+
+// served — метод учета переданного значения входа
+// charge — кредит, списываемый со входа
+func (m *Multiplexer[T]) served(in *input[T], charge int) {
+	in.current -= charge
+	now := m.opts.now()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	in.stats.Received++
+	if in.backlogged {
+		gap := now.Sub(in.lastServed)
+		if gap > in.stats.MaxWait {
+			in.stats.MaxWait = gap
+		}
+		if m.opts.threshold > 0 && gap > m.opts.threshold {
+			in.stats.Starvations++
+		}
+	}
+	in.lastServed = now
+	in.backlogged = true
+}
+
+// idle — метод учета пустого входа: кредит и интервал ожидания сбрасываются
+func (m *Multiplexer[T]) idle(in *input[T]) {
+	in.current = 0
+	m.mutex.Lock()
+	in.backlogged = false
+	m.mutex.Unlock()
+}
+
+// markClosed — метод учета закрытого входа
+func (m *Multiplexer[T]) markClosed(in *input[T]) {
+	in.closed = true
+	in.current = 0
+	m.mutex.Lock()
+	in.stats.Closed = true
+	in.backlogged = false
+	m.mutex.Unlock()
+}
+
+// Stats — метод получения метрик входов в порядке их объявления
+func (m *Multiplexer[T]) Stats() []InputStats {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	out := make([]InputStats, len(m.inputs))
+	for i, in := range m.inputs {
+		out[i] = in.stats
+	}
+	return out
+}
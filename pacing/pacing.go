@@ -0,0 +1,131 @@
+package pacing
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync/atomic"
+
+	"goroutines-example/keyed"
+	"goroutines-example/ratelimit"
+)
+
+// Pacer — источник соединений с ограничением скорости для протокольных серверов
+// Каждое обернутое соединение получает собственные ограничители скорости
+// чтения и записи (в байтах в секунду), а обработчики, работающие с одним
+// соединением из разных горутин, выполняются по очереди через мьютекс по ключу
+// обертки
+type Pacer struct {
+	// Мьютексы соединений по ключу
+	locks *keyed.Mutex
+	// Номер последней обертки. Адреса не годятся в ключ: у net.Pipe и
+	// unix-сокетов они совпадают, а порты переиспользуются
+	wrapped atomic.Uint64
+	// Скорость в байтах в секунду и размер всплеска для каждого направления
+	bytesPerSecond float64
+	burst          int
+}
+
+// NewPacer — функция создания источника соединений с ограничением скорости
+// bytesPerSecond — скорость чтения и записи для каждого соединения
+// burst — максимальный объем данных, передаваемый без ожидания
+func NewPacer(bytesPerSecond float64, burst int) (*Pacer, error) {
+	if bytesPerSecond <= 0 || burst <= 0 {
+		return nil, fmt.Errorf("скорость и размер всплеска должны быть положительными: %v, %d", bytesPerSecond, burst)
+	}
+	return &Pacer{
+		locks:          keyed.NewMutex(),
+		bytesPerSecond: bytesPerSecond,
+		burst:          burst,
+	}, nil
+}
+
+// Conn — соединение с ограничением скорости
+type Conn struct {
+	net.Conn
+	pacer *Pacer
+	// Уникальный ключ обертки для мьютекса
+	key string
+	// Ограничители скорости чтения и записи
+	readLimiter  *ratelimit.Limiter
+	writeLimiter *ratelimit.Limiter
+	// Контекст, отмена которого прерывает ожидание ограничителей
+	ctx context.Context
+}
+
+// Wrap — метод оборачивания соединения
+// ctx ограничивает время жизни ожиданий ограничителя (обычно контекст сервера)
+func (p *Pacer) Wrap(ctx context.Context, conn net.Conn) (*Conn, error) {
+	readLimiter, err := ratelimit.NewLimiter(p.bytesPerSecond, p.burst)
+	if err != nil {
+		return nil, err
+	}
+	writeLimiter, err := ratelimit.NewLimiter(p.bytesPerSecond, p.burst)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{
+		Conn:         conn,
+		pacer:        p,
+		key:          strconv.FormatUint(p.wrapped.Add(1), 10),
+		readLimiter:  readLimiter,
+		writeLimiter: writeLimiter,
+		ctx:          ctx,
+	}, nil
+}
+
+// Read — метод чтения с ограничением скорости
+// За один вызов читается не больше размера всплеска; после чтения
+// вызывающая сторона ждет, пока прочитанный объем уложится в скорость
+func (c *Conn) Read(b []byte) (int, error) {
+	if len(b) > c.pacer.burst {
+		b = b[:c.pacer.burst]
+	}
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		if werr := c.readLimiter.WaitN(c.ctx, n); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+// Write — метод записи с ограничением скорости
+// Данные записываются порциями не больше размера всплеска,
+// перед каждой порцией вызывающая сторона ждет ограничитель
+func (c *Conn) Write(b []byte) (int, error) {
+	written := 0
+	for written < len(b) {
+		chunk := len(b) - written
+		if chunk > c.pacer.burst {
+			chunk = c.pacer.burst
+		}
+		if err := c.writeLimiter.WaitN(c.ctx, chunk); err != nil {
+			return written, err
+		}
+		n, err := c.Conn.Write(b[written : written+chunk])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// Exclusive — метод выполнения fn с монопольным доступом к соединению
+// Обработчики одного соединения, вызывающие Exclusive из разных горутин,
+// выполняются по очереди, поэтому их сообщения не перемешиваются
+func (c *Conn) Exclusive(fn func(conn *Conn) error) error {
+	return c.pacer.locks.Do(c.key, func() error {
+		return fn(c)
+	})
+}
+
+// SetRate — метод изменения скорости чтения и записи соединения
+func (c *Conn) SetRate(bytesPerSecond float64) error {
+	if err := c.readLimiter.SetRate(bytesPerSecond); err != nil {
+		return err
+	}
+	return c.writeLimiter.SetRate(bytesPerSecond)
+}
@@ -0,0 +1,55 @@
+package pacing
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestExclusiveIndependentConns — соединения с одинаковыми адресами
+// (net.Pipe) не блокируют друг друга в Exclusive
+func TestExclusiveIndependentConns(t *testing.T) {
+	p, err := NewPacer(1<<20, 1<<10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a1, b1 := net.Pipe()
+	a2, b2 := net.Pipe()
+	defer func() {
+		for _, c := range []net.Conn{a1, b1, a2, b2} {
+			c.Close()
+		}
+	}()
+	first, err := p.Wrap(context.Background(), a1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := p.Wrap(context.Background(), a2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inside := make(chan struct{})
+	release := make(chan struct{})
+	go first.Exclusive(func(*Conn) error {
+		close(inside)
+		<-release
+		return nil
+	})
+	<-inside
+	defer close(release)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- second.Exclusive(func(*Conn) error { return nil })
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Exclusive второго соединения ждет первое")
+	}
+}
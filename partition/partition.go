@@ -0,0 +1,276 @@
+package partition
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"goroutines-example/clock"
+)
+
+// Membership — источник списка живых экземпляров кластера
+// Реализуется составами кластера пакета membership
+type Membership interface {
+	// Members — текущий список идентификаторов экземпляров
+	Members(ctx context.Context) ([]string, error)
+}
+
+// Owner — функция определения владельца раздела среди members
+// Используется рендеву-хэширование (HRW): при появлении или уходе
+// экземпляра переезжают только разделы, которые он получает или отдает.
+// Для пустого списка возвращается ""
+func Owner(partition int, members []string) string {
+	var (
+		owner string
+		best  uint64
+	)
+	for _, m := range members {
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%s/%d", m, partition)
+		score := mix(h.Sum64())
+		if owner == "" || score > best || (score == best && m < owner) {
+			owner, best = m, score
+		}
+	}
+	return owner
+}
+
+// mix — функция перемешивания битов хэша (финализатор MurmurHash3)
+// У FNV на коротких строках, отличающихся последним символом, старшие
+// биты почти совпадают, и без перемешивания все разделы уходили бы
+// одному экземпляру
+func mix(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// Assign — функция распределения разделов 0..partitions-1 между members
+func Assign(members []string, partitions int) map[string][]int {
+	out := make(map[string][]int, len(members))
+	for p := 0; p < partitions; p++ {
+		if owner := Owner(p, members); owner != "" {
+			out[owner] = append(out[owner], p)
+		}
+	}
+	return out
+}
+
+// Handler — обработчик раздела
+// Выполняется, пока раздел принадлежит экземпляру; ctx отменяется при
+// передаче раздела другому экземпляру или остановке Assigner
+type Handler func(ctx context.Context, partition int)
+
+// Option — функциональная опция Assigner
+type Option func(*Assigner)
+
+// WithInterval — опция периода опроса состава кластера (по умолчанию 1 с)
+func WithInterval(d time.Duration) Option {
+	return func(a *Assigner) {
+		a.interval = d
+	}
+}
+
+// WithHandoffDelay — опция задержки запуска полученного раздела
+// Дает прежнему владельцу время заметить изменение состава и завершить
+// обработку раздела, чтобы два экземпляра не работали с ним одновременно.
+// Разумное значение — не меньше периода опроса
+func WithHandoffDelay(d time.Duration) Option {
+	return func(a *Assigner) {
+		a.handoff = d
+	}
+}
+
+// OnRevoked — опция обработчика передачи раздела
+// Вызывается после завершения Handler отданного раздела — например,
+// чтобы сохранить смещение перед тем, как раздел начнет обрабатывать
+// новый владелец
+func OnRevoked(fn func(partition int)) Option {
+	return func(a *Assigner) {
+		a.onRevoked = fn
+	}
+}
+
+// WithClock — опция задания источника времени (по умолчанию реальное время)
+func WithClock(c clock.Clock) Option {
+	return func(a *Assigner) {
+		a.clock = c
+	}
+}
+
+// worker — запущенная обработка раздела
+type worker struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Assigner — распределитель разделов пространства ключей между экземплярами
+// Периодически читает состав кластера из Membership, вычисляет разделы
+// текущего экземпляра и запускает Handler для каждого из них. При изменении
+// состава отданные разделы останавливаются (с вызовом OnRevoked), а
+// полученные запускаются после задержки передачи
+type Assigner struct {
+	self       string
+	partitions int
+	membership Membership
+	handler    Handler
+	interval   time.Duration
+	handoff    time.Duration
+	onRevoked  func(partition int)
+	clock      clock.Clock
+
+	// Защита workers и members
+	mutex   sync.Mutex
+	workers map[int]*worker
+	members []string
+}
+
+// NewAssigner — функция создания распределителя
+// self — идентификатор текущего экземпляра в Membership,
+// partitions — количество разделов пространства ключей
+func NewAssigner(self string, partitions int, membership Membership, handler Handler, opts ...Option) (*Assigner, error) {
+	if self == "" {
+		return nil, fmt.Errorf("идентификатор экземпляра не задан")
+	}
+	if partitions <= 0 {
+		return nil, fmt.Errorf("количество разделов должно быть положительным: %d", partitions)
+	}
+	if membership == nil || handler == nil {
+		return nil, fmt.Errorf("источник состава кластера и обработчик обязательны")
+	}
+	a := &Assigner{
+		self:       self,
+		partitions: partitions,
+		membership: membership,
+		handler:    handler,
+		interval:   time.Second,
+		clock:      clock.Real(),
+		workers:    make(map[int]*worker),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.interval <= 0 {
+		return nil, fmt.Errorf("период опроса должен быть положительным: %v", a.interval)
+	}
+	return a, nil
+}
+
+// Run — метод распределения разделов до отмены ctx
+// Ошибка чтения состава не меняет распределение: экземпляр продолжает
+// обрабатывать свои разделы до следующего успешного опроса. При выходе
+// все разделы останавливаются
+func (a *Assigner) Run(ctx context.Context) error {
+	defer a.rebalance(ctx, nil)
+
+	for {
+		if members, err := a.membership.Members(ctx); err == nil {
+			a.rebalance(ctx, members)
+		}
+		select {
+		case <-a.clock.After(a.interval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// rebalance — метод приведения запущенных разделов к составу members
+func (a *Assigner) rebalance(ctx context.Context, members []string) {
+	members = append([]string(nil), members...)
+	sort.Strings(members)
+
+	owned := make(map[int]bool)
+	for _, p := range Assign(members, a.partitions)[a.self] {
+		owned[p] = true
+	}
+
+	a.mutex.Lock()
+	a.members = members
+	var revoked []int
+	for p := range a.workers {
+		if !owned[p] {
+			revoked = append(revoked, p)
+		}
+	}
+	var gained []int
+	for p := range owned {
+		if _, ok := a.workers[p]; !ok {
+			gained = append(gained, p)
+		}
+	}
+	a.mutex.Unlock()
+
+	sort.Ints(revoked)
+	for _, p := range revoked {
+		a.stop(p)
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	sort.Ints(gained)
+	for _, p := range gained {
+		a.start(ctx, p)
+	}
+}
+
+// start — метод запуска обработки раздела после задержки передачи
+func (a *Assigner) start(ctx context.Context, p int) {
+	ctx, cancel := context.WithCancel(ctx)
+	w := &worker{cancel: cancel, done: make(chan struct{})}
+	a.mutex.Lock()
+	a.workers[p] = w
+	a.mutex.Unlock()
+
+	go func() {
+		defer close(w.done)
+		if a.handoff > 0 {
+			if err := clock.Sleep(ctx, a.clock, a.handoff); err != nil {
+				return
+			}
+		}
+		a.handler(ctx, p)
+	}()
+}
+
+// stop — метод остановки обработки раздела с вызовом OnRevoked
+func (a *Assigner) stop(p int) {
+	a.mutex.Lock()
+	w := a.workers[p]
+	delete(a.workers, p)
+	a.mutex.Unlock()
+	if w == nil {
+		return
+	}
+
+	w.cancel()
+	<-w.done
+	if a.onRevoked != nil {
+		a.onRevoked(p)
+	}
+}
+
+// Owned — метод получения разделов, принадлежащих экземпляру
+func (a *Assigner) Owned() []int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	out := make([]int, 0, len(a.workers))
+	for p := range a.workers {
+		out = append(out, p)
+	}
+	sort.Ints(out)
+	return out
+}
+
+// Members — метод получения состава кластера при последнем распределении
+func (a *Assigner) Members() []string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return append([]string(nil), a.members...)
+}
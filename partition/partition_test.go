@@ -0,0 +1,155 @@
+package partition
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"goroutines-example/clock"
+)
+
+// TestAssignMovesOnlyNewMember — при добавлении экземпляра переезжают
+// только разделы, которые получает он сам
+func TestAssignMovesOnlyNewMember(t *testing.T) {
+	const partitions = 64
+	before := []string{"a", "b", "c"}
+	after := append(append([]string(nil), before...), "d")
+	for p := 0; p < partitions; p++ {
+		was, now := Owner(p, before), Owner(p, after)
+		if was != now && now != "d" {
+			t.Fatalf("раздел %d переехал с %s на %s, а не на новый экземпляр", p, was, now)
+		}
+	}
+	total := 0
+	for _, m := range before {
+		owned := Assign(before, partitions)[m]
+		if len(owned) == 0 {
+			t.Fatalf("экземпляру %s не досталось разделов", m)
+		}
+		total += len(owned)
+	}
+	if total != partitions {
+		t.Fatalf("распределено %d разделов из %d", total, partitions)
+	}
+	if owner := Owner(0, nil); owner != "" {
+		t.Fatalf("владелец при пустом составе: %q", owner)
+	}
+}
+
+// members — изменяемый состав кластера
+type members struct {
+	mutex sync.Mutex
+	ids   []string
+}
+
+func (m *members) set(ids ...string) {
+	m.mutex.Lock()
+	m.ids = ids
+	m.mutex.Unlock()
+}
+
+func (m *members) Members(context.Context) ([]string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]string(nil), m.ids...), nil
+}
+
+// receive — функция получения n номеров разделов из ch в порядке возрастания
+func receive(t *testing.T, ch <-chan int, n int) []int {
+	t.Helper()
+	out := make([]int, 0, n)
+	for len(out) < n {
+		select {
+		case p := <-ch:
+			out = append(out, p)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("получено %d разделов из %d: %v", len(out), n, out)
+		}
+	}
+	sort.Ints(out)
+	return out
+}
+
+// TestAssignerHandoff — разделы ушедшему экземпляру останавливаются
+// с вызовом OnRevoked, а полученные запускаются только после задержки передачи
+func TestAssignerHandoff(t *testing.T) {
+	const partitions = 16
+	fake := clock.NewFake(time.Unix(0, 0))
+	cluster := &members{}
+	cluster.set("a")
+	started := make(chan int, partitions)
+	revoked := make(chan int, partitions)
+	a, err := NewAssigner("a", partitions, cluster, func(ctx context.Context, p int) {
+		started <- p
+		<-ctx.Done()
+	}, WithClock(fake), WithInterval(time.Second), WithHandoffDelay(2*time.Second),
+		OnRevoked(func(p int) { revoked <- p }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Run(ctx) }()
+
+	all := make([]int, partitions)
+	for p := range all {
+		all[p] = p
+	}
+	// Опрос состава и задержки передачи каждого раздела
+	fake.BlockUntil(1 + partitions)
+	fake.Advance(time.Second)
+	select {
+	case p := <-started:
+		t.Fatalf("раздел %d запущен до истечения задержки передачи", p)
+	default:
+	}
+	fake.BlockUntil(1 + partitions)
+	fake.Advance(time.Second)
+	if got := receive(t, started, partitions); !reflect.DeepEqual(got, all) {
+		t.Fatalf("запущены разделы %v", got)
+	}
+
+	// Новый экземпляр b забирает свою часть при следующем опросе
+	moved := Assign([]string{"a", "b"}, partitions)["b"]
+	cluster.set("a", "b")
+	fake.BlockUntil(1)
+	fake.Advance(time.Second)
+	if got := receive(t, revoked, len(moved)); !reflect.DeepEqual(got, moved) {
+		t.Fatalf("отданы разделы %v, ожидались %v", got, moved)
+	}
+	if got, want := a.Owned(), Assign([]string{"a", "b"}, partitions)["a"]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("после прихода b у a разделы %v, ожидались %v", got, want)
+	}
+
+	// После ухода b его разделы возвращаются, но снова с задержкой
+	cluster.set("a")
+	fake.BlockUntil(1)
+	fake.Advance(time.Second)
+	fake.BlockUntil(1 + len(moved))
+	fake.Advance(time.Second)
+	select {
+	case p := <-started:
+		t.Fatalf("возвращенный раздел %d запущен до истечения задержки передачи", p)
+	default:
+	}
+	fake.BlockUntil(1 + len(moved))
+	fake.Advance(time.Second)
+	if got := receive(t, started, len(moved)); !reflect.DeepEqual(got, moved) {
+		t.Fatalf("после ухода b запущены разделы %v, ожидались %v", got, moved)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run вернул %v", err)
+	}
+	if got := receive(t, revoked, partitions); !reflect.DeepEqual(got, all) {
+		t.Fatalf("при остановке отданы разделы %v", got)
+	}
+	if owned := a.Owned(); len(owned) != 0 {
+		t.Fatalf("после остановки остались разделы %v", owned)
+	}
+}
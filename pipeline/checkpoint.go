@@ -0,0 +1,148 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNoCheckpoint — ошибка Resume у конвейера без контрольных точек
+var ErrNoCheckpoint = errors.New("контрольные точки конвейера не настроены")
+
+// Store — хранилище контрольных точек конвейера
+// Смещение — номер первого элемента входа, который еще не обработан:
+// все элементы до него прошли конвейер или ушли в DLQ
+type Store interface {
+	// Load — метод загрузки сохраненного смещения (0, если точки еще нет)
+	Load(ctx context.Context) (uint64, error)
+	// Save — метод сохранения смещения
+	Save(ctx context.Context, offset uint64) error
+}
+
+// WithCheckpoint — опция периодического сохранения прогресса в store
+// Смещение сохраняется раз в interval, если оно изменилось, и еще раз
+// при завершении конвейера (в том числе после отмены ctx). Элементы,
+// обработка которых прервана, после Resume обрабатываются повторно
+func WithCheckpoint[T any](store Store, interval time.Duration) Option[T] {
+	return func(p *Pipeline[T]) {
+		p.store = store
+		p.interval = interval
+	}
+}
+
+// Resume — метод продолжения обработки с сохраненной контрольной точки
+// Загружает смещение из хранилища и открывает вход функцией open с этого
+// смещения; первый элемент канала, возвращенного open, должен иметь
+// загруженное смещение, а остальные идти за ним подряд
+func (p *Pipeline[T]) Resume(ctx context.Context, open func(ctx context.Context, offset uint64) (<-chan T, error)) (<-chan T, error) {
+	if p.store == nil {
+		return nil, ErrNoCheckpoint
+	}
+	offset, err := p.store.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	in, err := open(ctx, offset)
+	if err != nil {
+		return nil, err
+	}
+	p.mutex.Lock()
+	p.saved, p.saveErr = offset, nil
+	p.mutex.Unlock()
+	return p.run(ctx, in, offset), nil
+}
+
+// Checkpoint — метод получения последнего сохраненного смещения
+// и ошибки последней попытки сохранения
+func (p *Pipeline[T]) Checkpoint() (uint64, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.saved, p.saveErr
+}
+
+// checkpointLoop — метод периодического сохранения прогресса cp
+// Работает до закрытия stop, затем сохраняет итоговое смещение и закрывает saved
+func (p *Pipeline[T]) checkpointLoop(ctx context.Context, cp *checkpointer, stop <-chan struct{}, saved chan<- struct{}) {
+	defer close(saved)
+	last := cp.offset()
+	timer := p.clock.NewTimer(p.interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-stop:
+			// ctx к этому моменту может быть отменен, а итоговый прогресс
+			// все равно нужно сохранить
+			if offset := cp.offset(); offset != last {
+				p.save(context.Background(), offset)
+			}
+			return
+		case <-timer.C():
+			if offset := cp.offset(); offset != last {
+				if p.save(ctx, offset) {
+					last = offset
+				}
+			}
+			timer.Reset(p.interval)
+		}
+	}
+}
+
+// save — метод сохранения смещения с учетом результата
+// Возвращает false, если хранилище вернуло ошибку
+func (p *Pipeline[T]) save(ctx context.Context, offset uint64) bool {
+	err := p.store.Save(ctx, offset)
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.saveErr = err
+	if err != nil {
+		return false
+	}
+	p.saved = offset
+	return true
+}
+
+// checkpointer — учет обработанных элементов для контрольных точек
+// Элементы завершаются не по порядку, поэтому смещение продвигается
+// только через непрерывный префикс завершенных
+type checkpointer struct {
+	mutex sync.Mutex
+	// Смещение первого незавершенного элемента
+	next uint64
+	// Завершенные элементы со смещением больше next
+	completed map[uint64]struct{}
+}
+
+// newCheckpointer — функция создания учета, начинающегося со смещения offset
+func newCheckpointer(offset uint64) *checkpointer {
+	return &checkpointer{next: offset, completed: make(map[uint64]struct{})}
+}
+
+// complete — метод отметки элемента со смещением offset как обработанного
+// У конвейера без контрольных точек учет отсутствует (nil), и вызов ничего не делает
+func (c *checkpointer) complete(offset uint64) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if offset != c.next {
+		c.completed[offset] = struct{}{}
+		return
+	}
+	c.next++
+	for {
+		if _, ok := c.completed[c.next]; !ok {
+			return
+		}
+		delete(c.completed, c.next)
+		c.next++
+	}
+}
+
+// offset — метод получения смещения первого незавершенного элемента
+func (c *checkpointer) offset() uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.next
+}
@@ -0,0 +1,147 @@
+package pipeline
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"goroutines-example/clock"
+)
+
+// memStore — хранилище контрольных точек в памяти
+// Каждое сохраненное смещение дублируется в канал saves
+type memStore struct {
+	offset uint64
+	saves  chan uint64
+}
+
+func newMemStore(offset uint64) *memStore {
+	return &memStore{offset: offset, saves: make(chan uint64, 100)}
+}
+
+func (s *memStore) Load(context.Context) (uint64, error) { return s.offset, nil }
+
+func (s *memStore) Save(_ context.Context, offset uint64) error {
+	s.offset = offset
+	s.saves <- offset
+	return nil
+}
+
+// identity — стадия, передающая элемент дальше без изменений
+var identity = Stage[int]{Name: "identity", Fn: func(_ context.Context, v int) (int, error) { return v, nil }}
+
+// TestCheckpointPeriodic — прогресс сохраняется по таймеру и при завершении
+func TestCheckpointPeriodic(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	store := newMemStore(0)
+	p, err := New([]Stage[int]{identity}, WithClock[int](fake), WithCheckpoint[int](store, time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := make(chan int)
+	out := p.Run(context.Background(), in)
+	for i := 0; i < 3; i++ {
+		in <- i
+		<-out
+	}
+
+	// Элемент отмечается обработанным чуть позже, чем его получает
+	// потребитель, поэтому продвигаем часы, пока тик не сохранит все три
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if time.Now().After(deadline) {
+			t.Fatal("смещение 3 не сохранено")
+		}
+		fake.Advance(time.Second)
+		select {
+		case offset := <-store.saves:
+			if offset > 3 {
+				t.Fatalf("сохранено смещение %d, обработано только 3 элемента", offset)
+			}
+			if offset < 3 {
+				continue
+			}
+		case <-time.After(10 * time.Millisecond):
+			continue
+		}
+		break
+	}
+
+	in <- 3
+	close(in)
+	if got := collect(out); !reflect.DeepEqual(got, []int{3}) {
+		t.Fatalf("результаты %v, ожидались [3]", got)
+	}
+	if offset, _ := p.Checkpoint(); offset != 4 {
+		t.Fatalf("после завершения сохранено смещение %d, ожидалось 4", offset)
+	}
+}
+
+// TestResumeAfterCancel — после отмены конвейер продолжает с первого
+// необработанного элемента, а элемент из DLQ считается обработанным
+func TestResumeAfterCancel(t *testing.T) {
+	store := newMemStore(0)
+	items := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	open := func(_ context.Context, offset uint64) (<-chan int, error) {
+		return feed(items[offset:]...), nil
+	}
+	stages := []Stage[int]{{
+		Name: "check",
+		Fn: func(_ context.Context, v int) (int, error) {
+			if v == 7 {
+				return 0, errPoison
+			}
+			return v, nil
+		},
+	}}
+
+	first, err := New(stages, WithCheckpoint[int](store, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	out, err := first.Resume(ctx, open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		<-out
+	}
+	cancel()
+	// Итоговое смещение сохраняется до закрытия out, даже если его не читают
+	if offset := <-store.saves; offset != 5 {
+		t.Fatalf("после отмены сохранено смещение %d, ожидалось 5", offset)
+	}
+	for range out {
+	}
+
+	second, err := New(stages, WithCheckpoint[int](store, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = second.Resume(context.Background(), open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := collect(out), []int{5, 6, 8, 9}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("после возобновления получено %v, ожидалось %v", got, want)
+	}
+	if offset, _ := second.Checkpoint(); offset != 10 {
+		t.Fatalf("после завершения сохранено смещение %d, ожидалось 10", offset)
+	}
+}
+
+// TestCheckpointerOutOfOrder — смещение продвигается только по непрерывному префиксу
+func TestCheckpointerOutOfOrder(t *testing.T) {
+	cp := newCheckpointer(10)
+	for _, offset := range []uint64{12, 11, 14} {
+		cp.complete(offset)
+	}
+	if got := cp.offset(); got != 10 {
+		t.Fatalf("смещение %d до завершения элемента 10", got)
+	}
+	cp.complete(10)
+	if got := cp.offset(); got != 13 {
+		t.Fatalf("смещение %d, ожидалось 13", got)
+	}
+}
@@ -0,0 +1,326 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"goroutines-example/clock"
+)
+
+// ErrNoStages — ошибка создания конвейера без стадий
+var ErrNoStages = errors.New("конвейер без стадий")
+
+// RetryPolicy — политика повторов элемента на стадии
+type RetryPolicy struct {
+	// Количество повторных попыток после первой неудачной (0 — без повторов)
+	Retries int
+	// Пауза перед первой повторной попыткой (дальше удваивается)
+	Backoff time.Duration
+	// Верхняя граница паузы между попытками (0 — без ограничения)
+	MaxBackoff time.Duration
+	// Предикат повторяемости ошибки (nil — повторяются все ошибки)
+	Retryable func(error) bool
+}
+
+// Stage — стадия конвейера
+type Stage[T any] struct {
+	// Имя стадии (уникальное в конвейере) для счетчиков и DLQ
+	Name string
+	// Количество одновременно обрабатываемых элементов (по умолчанию 1)
+	Workers int
+	// Функция обработки элемента
+	Fn func(ctx context.Context, item T) (T, error)
+	// Политика повторов неудачных попыток
+	Retry RetryPolicy
+}
+
+// DeadLetter — элемент, исчерпавший попытки на стадии
+type DeadLetter[T any] struct {
+	// Имя стадии, на которой элемент не прошел обработку
+	Stage string
+	// Элемент в том виде, в котором он пришел на стадию
+	Item T
+	// Количество выполненных попыток
+	Attempts int
+	// Ошибка последней попытки
+	Err error
+}
+
+// StageStats — счетчики стадии
+type StageStats struct {
+	// Успешно обработанные элементы
+	Processed uint64
+	// Повторные попытки
+	Retries uint64
+	// Элементы, отправленные в DLQ
+	DeadLettered uint64
+}
+
+// Option — функция настройки конвейера
+type Option[T any] func(*Pipeline[T])
+
+// WithDeadLetter — опция приемника элементов, исчерпавших попытки
+// Вызывается конкурентно из обработчиков разных стадий; без приемника
+// такие элементы отбрасываются и учитываются только в счетчиках
+func WithDeadLetter[T any](sink func(DeadLetter[T])) Option[T] {
+	return func(p *Pipeline[T]) { p.deadLetter = sink }
+}
+
+// WithClock — опция источника времени для пауз между попытками
+func WithClock[T any](c clock.Clock) Option[T] {
+	return func(p *Pipeline[T]) { p.clock = c }
+}
+
+// item — элемент вместе с его смещением во входе конвейера
+type item[T any] struct {
+	offset uint64
+	value  T
+}
+
+// stage — стадия вместе с ее счетчиками
+type stage[T any] struct {
+	Stage[T]
+	processed    atomic.Uint64
+	retries      atomic.Uint64
+	deadLettered atomic.Uint64
+}
+
+// Pipeline — конвейер последовательных стадий обработки
+// Элемент, исчерпавший попытки на стадии, уходит в DLQ, и стадия
+// продолжает работу со следующими, поэтому одно «ядовитое» сообщение
+// не останавливает конвейер
+type Pipeline[T any] struct {
+	stages     []*stage[T]
+	deadLetter func(DeadLetter[T])
+	clock      clock.Clock
+
+	// Хранилище контрольных точек (nil — без контрольных точек)
+	store Store
+	// Период сохранения контрольных точек
+	interval time.Duration
+
+	// Защита результата последнего сохранения
+	mutex sync.Mutex
+	// Последнее сохраненное смещение
+	saved uint64
+	// Ошибка последнего сохранения
+	saveErr error
+}
+
+// New — функция создания конвейера из стадий stages
+func New[T any](stages []Stage[T], opts ...Option[T]) (*Pipeline[T], error) {
+	if len(stages) == 0 {
+		return nil, ErrNoStages
+	}
+	p := &Pipeline[T]{clock: clock.Real()}
+	names := make(map[string]bool, len(stages))
+	for i, s := range stages {
+		if s.Name == "" {
+			s.Name = fmt.Sprintf("stage-%d", i)
+		}
+		if names[s.Name] {
+			return nil, fmt.Errorf("повторяющееся имя стадии %q", s.Name)
+		}
+		names[s.Name] = true
+		if s.Fn == nil {
+			return nil, fmt.Errorf("стадия %q без функции обработки", s.Name)
+		}
+		if s.Retry.Retries < 0 {
+			return nil, fmt.Errorf("количество повторов стадии %q не может быть отрицательным: %d", s.Name, s.Retry.Retries)
+		}
+		if s.Workers <= 0 {
+			s.Workers = 1
+		}
+		p.stages = append(p.stages, &stage[T]{Stage: s})
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.store != nil && p.interval <= 0 {
+		return nil, fmt.Errorf("период контрольных точек должен быть положительным: %v", p.interval)
+	}
+	return p, nil
+}
+
+// Run — метод запуска конвейера над элементами канала in
+// Возвращает канал результатов последней стадии; он закрывается, когда
+// in закрыт и все элементы прошли стадии, или после отмены ctx. При
+// нескольких обработчиках на стадии порядок элементов не сохраняется.
+// С WithCheckpoint элементы нумеруются с нуля, и контрольные точки
+// перезаписывают сохраненные ранее; продолжить с них позволяет Resume
+func (p *Pipeline[T]) Run(ctx context.Context, in <-chan T) <-chan T {
+	return p.run(ctx, in, 0)
+}
+
+// run — метод запуска конвейера над входом, первый элемент которого
+// имеет смещение offset
+func (p *Pipeline[T]) run(ctx context.Context, in <-chan T, offset uint64) <-chan T {
+	var cp *checkpointer
+	if p.store != nil {
+		cp = newCheckpointer(offset)
+	}
+	items := number(ctx, in, offset)
+	for _, s := range p.stages {
+		items = p.runStage(ctx, s, items, cp)
+	}
+	return p.deliver(ctx, items, cp)
+}
+
+// number — функция нумерации элементов входа начиная со смещения offset
+func number[T any](ctx context.Context, in <-chan T, offset uint64) <-chan item[T] {
+	out := make(chan item[T])
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case out <- item[T]{offset: offset, value: v}:
+					offset++
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// deliver — метод передачи результатов последней стадии потребителю
+// Элемент считается обработанным, когда потребитель его получил
+func (p *Pipeline[T]) deliver(ctx context.Context, in <-chan item[T], cp *checkpointer) <-chan T {
+	out := make(chan T)
+	var stop chan struct{}
+	var saved chan struct{}
+	if cp != nil {
+		stop = make(chan struct{})
+		saved = make(chan struct{})
+		go p.checkpointLoop(ctx, cp, stop, saved)
+	}
+	go func() {
+		defer close(out)
+		if cp != nil {
+			// Последняя контрольная точка сохраняется до закрытия out,
+			// чтобы потребитель, дочитавший результаты, мог на нее опереться
+			defer func() {
+				close(stop)
+				<-saved
+			}()
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case it, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case out <- it.value:
+					cp.complete(it.offset)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// runStage — метод запуска обработчиков стадии s над каналом in
+func (p *Pipeline[T]) runStage(ctx context.Context, s *stage[T], in <-chan item[T], cp *checkpointer) <-chan item[T] {
+	out := make(chan item[T])
+	var wg sync.WaitGroup
+	for i := 0; i < s.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var it item[T]
+				var ok bool
+				select {
+				case <-ctx.Done():
+					return
+				case it, ok = <-in:
+					if !ok {
+						return
+					}
+				}
+				result, ok := p.process(ctx, s, it, cp)
+				if !ok {
+					continue
+				}
+				select {
+				case out <- item[T]{offset: it.offset, value: result}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// process — метод обработки элемента стадией с повторами по ее политике
+// Возвращает false, если элемент ушел в DLQ или обработку прервала отмена ctx
+// Элемент, ушедший в DLQ, считается обработанным для контрольных точек
+func (p *Pipeline[T]) process(ctx context.Context, s *stage[T], it item[T], cp *checkpointer) (T, bool) {
+	backoff := s.Retry.Backoff
+	for attempt := 1; ; attempt++ {
+		result, err := s.Fn(ctx, it.value)
+		if err == nil {
+			s.processed.Add(1)
+			return result, true
+		}
+		if ctx.Err() != nil {
+			return result, false
+		}
+		if attempt > s.Retry.Retries || (s.Retry.Retryable != nil && !s.Retry.Retryable(err)) {
+			s.deadLettered.Add(1)
+			if p.deadLetter != nil {
+				p.deadLetter(DeadLetter[T]{Stage: s.Name, Item: it.value, Attempts: attempt, Err: err})
+			}
+			cp.complete(it.offset)
+			return result, false
+		}
+
+		s.retries.Add(1)
+		timer := p.clock.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, false
+		case <-timer.C():
+		}
+		backoff *= 2
+		if s.Retry.MaxBackoff > 0 && backoff > s.Retry.MaxBackoff {
+			backoff = s.Retry.MaxBackoff
+		}
+	}
+}
+
+// Stats — метод получения счетчиков стадий по их именам
+func (p *Pipeline[T]) Stats() map[string]StageStats {
+	stats := make(map[string]StageStats, len(p.stages))
+	for _, s := range p.stages {
+		stats[s.Name] = StageStats{
+			Processed:    s.processed.Load(),
+			Retries:      s.retries.Load(),
+			DeadLettered: s.deadLettered.Load(),
+		}
+	}
+	return stats
+}
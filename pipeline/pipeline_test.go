@@ -0,0 +1,114 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"goroutines-example/clock"
+)
+
+var errPoison = errors.New("ядовитое сообщение")
+
+// collect — функция чтения всех результатов конвейера в отсортированный срез
+func collect(out <-chan int) []int {
+	var got []int
+	for v := range out {
+		got = append(got, v)
+	}
+	sort.Ints(got)
+	return got
+}
+
+// feed — функция создания закрытого канала с элементами items
+func feed(items ...int) <-chan int {
+	in := make(chan int, len(items))
+	for _, v := range items {
+		in <- v
+	}
+	close(in)
+	return in
+}
+
+// TestPoisonGoesToDeadLetter — элемент, исчерпавший повторы, уходит в DLQ,
+// а остальные проходят все стадии
+func TestPoisonGoesToDeadLetter(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	var mutex sync.Mutex
+	var dead []DeadLetter[int]
+	p, err := New([]Stage[int]{
+		{
+			Name: "check",
+			Fn: func(_ context.Context, v int) (int, error) {
+				if v == 3 {
+					return 0, errPoison
+				}
+				return v, nil
+			},
+			Retry: RetryPolicy{Retries: 2, Backoff: 10 * time.Millisecond},
+		},
+		{
+			Name: "double",
+			Fn:   func(_ context.Context, v int) (int, error) { return v * 2, nil },
+		},
+	}, WithClock[int](fake), WithDeadLetter(func(d DeadLetter[int]) {
+		mutex.Lock()
+		dead = append(dead, d)
+		mutex.Unlock()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := make(chan []int, 1)
+	go func() { results <- collect(p.Run(context.Background(), feed(1, 2, 3, 4, 5))) }()
+	// Пауза перед повтором удваивается: 10ms, затем 20ms
+	fake.BlockUntil(1)
+	fake.Advance(10 * time.Millisecond)
+	fake.BlockUntil(1)
+	fake.Advance(20 * time.Millisecond)
+
+	if got, want := <-results, []int{2, 4, 8, 10}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("результаты %v, ожидались %v", got, want)
+	}
+	if len(dead) != 1 || dead[0].Stage != "check" || dead[0].Item != 3 || dead[0].Attempts != 3 || !errors.Is(dead[0].Err, errPoison) {
+		t.Fatalf("неожиданное содержимое DLQ: %+v", dead)
+	}
+	want := map[string]StageStats{
+		"check":  {Processed: 4, Retries: 2, DeadLettered: 1},
+		"double": {Processed: 4},
+	}
+	if got := p.Stats(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("счетчики %+v, ожидались %+v", got, want)
+	}
+}
+
+// TestNotRetryable — ошибка, не проходящая предикат, не повторяется
+func TestNotRetryable(t *testing.T) {
+	p, err := New([]Stage[int]{{
+		Fn: func(_ context.Context, v int) (int, error) {
+			if v%2 == 0 {
+				return 0, errPoison
+			}
+			return v, nil
+		},
+		Workers: 3,
+		Retry: RetryPolicy{
+			Retries:   5,
+			Retryable: func(err error) bool { return !errors.Is(err, errPoison) },
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := collect(p.Run(context.Background(), feed(1, 2, 3, 4, 5))), []int{1, 3, 5}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("результаты %v, ожидались %v", got, want)
+	}
+	if got, want := p.Stats()["stage-0"], (StageStats{Processed: 3, DeadLettered: 2}); got != want {
+		t.Fatalf("счетчики %+v, ожидались %+v", got, want)
+	}
+}
@@ -0,0 +1,208 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"goroutines-example/semaphore"
+)
+
+// Producer — производитель работ с учетом обратного давления
+// Очередной элемент запрашивается у генератора только после того, как
+// у семафора получено разрешение на его обработку, поэтому между
+// производителем и обработчиками не накапливается неограниченный буфер
+type Producer[T any] struct {
+	// Семафор, ограничивающий количество одновременно обрабатываемых элементов
+	sem *semaphore.CountingSemaphore
+	// Генератор элементов: ok=false означает, что элементы закончились
+	generate func(ctx context.Context) (item T, ok bool, err error)
+	// Обработчик элемента (выполняется в отдельной горутине)
+	handle func(ctx context.Context, item T) error
+
+	// Защита состояния паузы и остановки
+	mutex sync.Mutex
+	// Закрыт, когда производитель не на паузе; при паузе заменяется открытым каналом
+	running chan struct{}
+	// Закрывается при вызове Drain
+	draining  chan struct{}
+	drainOnce sync.Once
+
+	// Первая ошибка генератора или обработчика
+	errOnce  sync.Once
+	firstErr error
+}
+
+// New — функция создания производителя
+func New[T any](sem *semaphore.CountingSemaphore, generate func(ctx context.Context) (T, bool, error), handle func(ctx context.Context, item T) error) *Producer[T] {
+	running := make(chan struct{})
+	close(running)
+	return &Producer[T]{
+		sem:      sem,
+		generate: generate,
+		handle:   handle,
+		running:  running,
+		draining: make(chan struct{}),
+	}
+}
+
+// Run — метод запуска производства
+// Работает, пока генератор не исчерпан, не вызван Drain, не отменен ctx
+// или не произошла ошибка; затем дожидается завершения всех обработчиков.
+// Возвращает первую ошибку генератора или обработчика, либо ошибку ctx
+func (p *Producer[T]) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		if err := p.waitRunning(ctx); err != nil {
+			return p.finish(&wg, err)
+		}
+
+		// Сначала место у обработчиков, потом элемент
+		if _, err := p.sem.AcquireAtLeast(ctx, 1, 1); err != nil {
+			if ctx.Err() != nil {
+				return p.finish(&wg, ctx.Err())
+			}
+			// Таймаут или отказ семафора — пробуем снова после паузы,
+			// чтобы мгновенные отказы не крутили цикл вхолостую
+			if err := p.sem.SleepRetryAfter(ctx, err, retryPause); err != nil {
+				return p.finish(&wg, err)
+			}
+			continue
+		}
+
+		// Пока ждали разрешения, могли вызвать Drain или Pause
+		select {
+		case <-p.draining:
+			p.sem.Release()
+			return p.finish(&wg, errDrained)
+		default:
+		}
+		if p.Paused() {
+			// Холостого цикла нет: waitRunning ждет Resume
+			p.sem.Release()
+			continue
+		}
+
+		item, ok, err := p.generate(ctx)
+		if err != nil || !ok {
+			p.sem.Release()
+			return p.finish(&wg, err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer p.sem.Release()
+			if err := p.handle(ctx, item); err != nil {
+				p.setErr(err)
+				// Ошибка обработчика останавливает производство
+				cancel()
+			}
+		}()
+	}
+}
+
+// waitRunning — метод ожидания снятия паузы
+// Возвращает errDrained после вызова Drain или ошибку ctx
+func (p *Producer[T]) waitRunning(ctx context.Context) error {
+	p.mutex.Lock()
+	running := p.running
+	p.mutex.Unlock()
+
+	select {
+	case <-p.draining:
+		return errDrained
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	select {
+	case <-running:
+		return nil
+	case <-p.draining:
+		return errDrained
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryPause — пауза после отказа семафора без подсказки о повторе
+const retryPause = 10 * time.Millisecond
+
+// errDrained — внутренний признак остановки через Drain
+var errDrained = errors.New("производство остановлено")
+
+// finish — метод завершения Run: ожидание обработчиков и выбор возвращаемой ошибки
+func (p *Producer[T]) finish(wg *sync.WaitGroup, err error) error {
+	wg.Wait()
+	if p.firstErr != nil {
+		return p.firstErr
+	}
+	if errors.Is(err, errDrained) {
+		return nil
+	}
+	return err
+}
+
+// setErr — метод сохранения первой ошибки
+func (p *Producer[T]) setErr(err error) {
+	p.errOnce.Do(func() {
+		p.firstErr = err
+	})
+}
+
+// Pause — метод приостановки производства
+// Уже запущенные обработчики продолжают работу, новые элементы не запрашиваются
+func (p *Producer[T]) Pause() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	select {
+	case <-p.running:
+		p.running = make(chan struct{})
+	default:
+		// Уже на паузе
+	}
+}
+
+// Resume — метод возобновления производства после паузы
+func (p *Producer[T]) Resume() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	select {
+	case <-p.running:
+		// Не на паузе
+	default:
+		close(p.running)
+	}
+}
+
+// Paused — метод проверки, находится ли производитель на паузе
+func (p *Producer[T]) Paused() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	select {
+	case <-p.running:
+		return false
+	default:
+		return true
+	}
+}
+
+// Drain — метод плавной остановки производства
+// Новые элементы больше не запрашиваются; Run возвращается после
+// завершения уже запущенных обработчиков
+func (p *Producer[T]) Drain() {
+	p.drainOnce.Do(func() {
+		close(p.draining)
+	})
+}
@@ -0,0 +1,190 @@
+package progress
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"goroutines-example/clock"
+)
+
+// Snapshot — снимок прогресса задания
+type Snapshot struct {
+	Name string
+	// Выполнено и всего единиц работы
+	Done  int64
+	Total int64
+	// Процент выполнения (0–100)
+	Percent float64
+	// Средняя скорость в единицах в секунду с начала задания
+	Rate float64
+	// Оценка оставшегося времени (0, если скорость еще неизвестна)
+	ETA time.Duration
+	// Время с начала задания
+	Elapsed time.Duration
+	// Задание завершено (выполнены все единицы или вызван Finish)
+	Finished bool
+}
+
+// String — метод форматирования снимка для логов
+func (s Snapshot) String() string {
+	return fmt.Sprintf("%s: %d/%d (%.1f%%), %.1f/с, осталось %v",
+		s.Name, s.Done, s.Total, s.Percent, s.Rate, s.ETA.Round(time.Second))
+}
+
+// Job — отслеживаемое задание с известным объемом работы
+// Add безопасен для вызова из любого количества горутин-исполнителей
+type Job struct {
+	name  string
+	total int64
+	done  atomic.Int64
+	// Признак явного завершения
+	finished atomic.Bool
+	start    time.Time
+	clock    clock.Clock
+}
+
+// Add — метод учета n выполненных единиц работы
+func (j *Job) Add(n int64) {
+	j.done.Add(n)
+}
+
+// Finish — метод явного завершения задания (например, при ошибке)
+func (j *Job) Finish() {
+	j.finished.Store(true)
+}
+
+// Task — метод обертки задачи, учитывающей одну единицу работы при завершении
+// Позволяет подключить учет прогресса к scope.Go или errgroup без изменения
+// самих задач. Единица учитывается и при ошибке задачи: она обработана
+func (j *Job) Task(fn func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		defer j.Add(1)
+		return fn(ctx)
+	}
+}
+
+// Snapshot — метод получения текущего прогресса
+func (j *Job) Snapshot() Snapshot {
+	done := j.done.Load()
+	elapsed := j.clock.Since(j.start)
+	s := Snapshot{
+		Name:     j.name,
+		Done:     done,
+		Total:    j.total,
+		Elapsed:  elapsed,
+		Finished: j.finished.Load() || done >= j.total,
+	}
+	if j.total > 0 {
+		s.Percent = float64(done) / float64(j.total) * 100
+	}
+	if elapsed > 0 {
+		s.Rate = float64(done) / elapsed.Seconds()
+	}
+	if s.Rate > 0 && done < j.total {
+		s.ETA = time.Duration(float64(j.total-done) / s.Rate * float64(time.Second))
+	}
+	return s
+}
+
+// Subscribe — метод подписки на прогресс не чаще одного раза в interval
+// Снимок отправляется, только если прогресс изменился; последний снимок
+// завершенного задания отправляется всегда, после чего канал закрывается.
+// Канал также закрывается при отмене ctx. Медленный читатель пропускает
+// промежуточные снимки, а не задерживает исполнителей
+func (j *Job) Subscribe(ctx context.Context, interval time.Duration) <-chan Snapshot {
+	ch := make(chan Snapshot, 1)
+	go func() {
+		defer close(ch)
+		last := int64(-1)
+		for {
+			s := j.Snapshot()
+			if s.Done != last || s.Finished {
+				last = s.Done
+				// Заменяем неполученный снимок свежим
+				select {
+				case <-ch:
+				default:
+				}
+				ch <- s
+			}
+			if s.Finished {
+				return
+			}
+			select {
+			case <-j.clock.After(interval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// Option — функциональная опция для настройки трекера
+type Option func(*Tracker)
+
+// WithClock — опция задания источника времени для расчета скорости и ETA
+func WithClock(c clock.Clock) Option {
+	return func(t *Tracker) {
+		t.clock = c
+	}
+}
+
+// Tracker — реестр отслеживаемых заданий
+type Tracker struct {
+	// Защита jobs
+	mutex sync.Mutex
+	jobs  map[string]*Job
+	clock clock.Clock
+}
+
+// NewTracker — функция создания трекера заданий
+func NewTracker(opts ...Option) *Tracker {
+	t := &Tracker{jobs: make(map[string]*Job), clock: clock.Real()}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Start — метод регистрации задания с total единицами работы
+// Задание с тем же именем заменяет предыдущее
+func (t *Tracker) Start(name string, total int64) (*Job, error) {
+	if total <= 0 {
+		return nil, fmt.Errorf("объем работы должен быть положительным: %d", total)
+	}
+	j := &Job{name: name, total: total, start: t.clock.Now(), clock: t.clock}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.jobs[name] = j
+	return j, nil
+}
+
+// Remove — метод удаления задания из реестра
+func (t *Tracker) Remove(name string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.jobs, name)
+}
+
+// Snapshots — метод получения прогресса всех заданий, упорядоченного по имени
+func (t *Tracker) Snapshots() []Snapshot {
+	t.mutex.Lock()
+	jobs := make([]*Job, 0, len(t.jobs))
+	for _, j := range t.jobs {
+		jobs = append(jobs, j)
+	}
+	t.mutex.Unlock()
+
+	out := make([]Snapshot, len(jobs))
+	for i, j := range jobs {
+		out[i] = j.Snapshot()
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Name < out[b].Name })
+	return out
+}
@@ -0,0 +1,68 @@
+package queue
+
+import (
+	"sync/atomic"
+
+	"goroutines-example/internal/cpu"
+)
+
+// node — узел очереди MPSC
+type node[T any] struct {
+	next  atomic.Pointer[node[T]]
+	value T
+}
+
+// MPSC — неограниченная очередь для многих производителей и одного потребителя
+// Реализация по схеме Вьюкова: производитель добавляет узел одной атомарной
+// заменой головы (без циклов CAS), потребитель двигает хвост без атомарных
+// операций записи. Производители никогда не ждут друг друга и потребителя.
+// Сразу после Push значение может быть на мгновение не видно в TryPop,
+// пока производитель не связал свой узел с предыдущим
+type MPSC[T any] struct {
+	// Последний добавленный узел (меняют производители)
+	head atomic.Pointer[node[T]]
+
+	_ [cpu.CacheLineSize]byte
+	// Последний извлеченный узел-заглушка (меняет только потребитель)
+	tail *node[T]
+	// Количество значений в очереди
+	length atomic.Int64
+}
+
+// NewMPSC — функция создания очереди для многих производителей и одного потребителя
+func NewMPSC[T any]() *MPSC[T] {
+	stub := &node[T]{}
+	q := &MPSC[T]{tail: stub}
+	q.head.Store(stub)
+	return q
+}
+
+// Push — метод добавления значения
+// Безопасен для вызова из любого количества горутин
+func (q *MPSC[T]) Push(v T) {
+	n := &node[T]{value: v}
+	q.length.Add(1)
+	prev := q.head.Swap(n)
+	prev.next.Store(n)
+}
+
+// TryPop — метод извлечения значения без блокировки
+// Возвращает false, если очередь пуста. Вызывается только потребителем
+func (q *MPSC[T]) TryPop() (T, bool) {
+	var zero T
+	next := q.tail.next.Load()
+	if next == nil {
+		return zero, false
+	}
+	// Извлеченный узел становится новой заглушкой
+	q.tail = next
+	v := next.value
+	next.value = zero
+	q.length.Add(-1)
+	return v, true
+}
+
+// Len — метод получения приблизительного количества значений в очереди
+func (q *MPSC[T]) Len() int {
+	return int(q.length.Load())
+}
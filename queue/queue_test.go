@@ -0,0 +1,177 @@
+package queue
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// benchCapacity — емкость ограниченных очередей в бенчмарках
+const benchCapacity = 1024
+
+// mutexQueue — эталонная очередь на срезе под мьютексом для сравнения
+type mutexQueue struct {
+	mutex sync.Mutex
+	items []int
+}
+
+// push — метод добавления значения
+func (q *mutexQueue) push(v int) {
+	q.mutex.Lock()
+	q.items = append(q.items, v)
+	q.mutex.Unlock()
+}
+
+// tryPop — метод извлечения значения без ожидания
+func (q *mutexQueue) tryPop() (int, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.items) == 0 {
+		return 0, false
+	}
+	v := q.items[0]
+	q.items = q.items[1:]
+	return v, true
+}
+
+// consume — функция извлечения n значений с уступкой процессора на пустой очереди
+func consume(n int, tryPop func() (int, bool)) {
+	for i := 0; i < n; {
+		if _, ok := tryPop(); ok {
+			i++
+			continue
+		}
+		runtime.Gosched()
+	}
+}
+
+// TestSPSCOrder — значения извлекаются в порядке добавления
+func TestSPSCOrder(t *testing.T) {
+	q, err := NewSPSC[int](4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const n = 10000
+	go func() {
+		for i := 0; i < n; i++ {
+			for !q.TryPush(i) {
+				runtime.Gosched()
+			}
+		}
+	}()
+	for want := 0; want < n; {
+		v, ok := q.TryPop()
+		if !ok {
+			runtime.Gosched()
+			continue
+		}
+		if v != want {
+			t.Fatalf("извлечено %d, ожидалось %d", v, want)
+		}
+		want++
+	}
+}
+
+// TestMPSCAll — все значения нескольких производителей извлекаются ровно один раз
+func TestMPSCAll(t *testing.T) {
+	q := NewMPSC[int]()
+	const producers, perProducer = 4, 2500
+	for p := 0; p < producers; p++ {
+		go func(base int) {
+			for i := 0; i < perProducer; i++ {
+				q.Push(base + i)
+			}
+		}(p * perProducer)
+	}
+	seen := make([]bool, producers*perProducer)
+	for got := 0; got < len(seen); {
+		v, ok := q.TryPop()
+		if !ok {
+			runtime.Gosched()
+			continue
+		}
+		if seen[v] {
+			t.Fatalf("значение %d извлечено дважды", v)
+		}
+		seen[v] = true
+		got++
+	}
+	if q.Len() != 0 {
+		t.Fatalf("в очереди осталось %d значений", q.Len())
+	}
+}
+
+// BenchmarkSPSC — передача значений от одного производителя одному потребителю
+func BenchmarkSPSC(b *testing.B) {
+	b.Run("spsc", func(b *testing.B) {
+		q, err := NewSPSC[int](benchCapacity)
+		if err != nil {
+			b.Fatal(err)
+		}
+		go func() {
+			for i := 0; i < b.N; i++ {
+				for !q.TryPush(i) {
+					runtime.Gosched()
+				}
+			}
+		}()
+		consume(b.N, q.TryPop)
+	})
+	b.Run("mutex", func(b *testing.B) {
+		q := &mutexQueue{}
+		go func() {
+			for i := 0; i < b.N; i++ {
+				q.push(i)
+			}
+		}()
+		consume(b.N, q.tryPop)
+	})
+	b.Run("channel", func(b *testing.B) {
+		ch := make(chan int, benchCapacity)
+		go func() {
+			for i := 0; i < b.N; i++ {
+				ch <- i
+			}
+		}()
+		for i := 0; i < b.N; i++ {
+			<-ch
+		}
+	})
+}
+
+// BenchmarkMPSC — передача значений от GOMAXPROCS производителей одному потребителю
+func BenchmarkMPSC(b *testing.B) {
+	b.Run("mpsc", func(b *testing.B) {
+		q := NewMPSC[int]()
+		produce(b.N, q.Push)
+		consume(b.N, q.TryPop)
+	})
+	b.Run("mutex", func(b *testing.B) {
+		q := &mutexQueue{}
+		produce(b.N, q.push)
+		consume(b.N, q.tryPop)
+	})
+	b.Run("channel", func(b *testing.B) {
+		ch := make(chan int, benchCapacity)
+		produce(b.N, func(v int) { ch <- v })
+		for i := 0; i < b.N; i++ {
+			<-ch
+		}
+	})
+}
+
+// produce — функция запуска GOMAXPROCS производителей, добавляющих вместе n значений
+func produce(n int, push func(int)) {
+	producers := runtime.GOMAXPROCS(0)
+	for p := 0; p < producers; p++ {
+		count := n / producers
+		if p < n%producers {
+			count++
+		}
+		go func(count int) {
+			for i := 0; i < count; i++ {
+				push(i)
+			}
+		}(count)
+	}
+}
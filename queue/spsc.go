@@ -0,0 +1,90 @@
+package queue
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"goroutines-example/internal/cpu"
+)
+
+// SPSC — ограниченная очередь для одного производителя и одного потребителя
+// Кольцевой буфер без блокировок: производитель пишет только tail, потребитель —
+// только head, поэтому хватает атомарных загрузок и сохранений без CAS.
+// Каждая сторона кеширует последний прочитанный индекс другой стороны и
+// перечитывает его, только когда буфер кажется полным (пустым).
+// Вызывать Push из нескольких горутин одновременно (или Pop) нельзя —
+// для нескольких производителей используйте MPSC
+type SPSC[T any] struct {
+	buf  []T
+	mask uint64
+
+	_ [cpu.CacheLineSize]byte
+	// Индекс следующего чтения (пишет только потребитель)
+	head atomic.Uint64
+	// Последнее прочитанное потребителем значение tail
+	cachedTail uint64
+
+	_ [cpu.CacheLineSize]byte
+	// Индекс следующей записи (пишет только производитель)
+	tail atomic.Uint64
+	// Последнее прочитанное производителем значение head
+	cachedHead uint64
+
+	_ [cpu.CacheLineSize]byte
+}
+
+// NewSPSC — функция создания очереди для одного производителя и одного потребителя
+// capacity округляется вверх до степени двойки
+func NewSPSC[T any](capacity int) (*SPSC[T], error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("емкость очереди должна быть положительной: %d", capacity)
+	}
+	size := uint64(1)
+	for size < uint64(capacity) {
+		size <<= 1
+	}
+	return &SPSC[T]{buf: make([]T, size), mask: size - 1}, nil
+}
+
+// TryPush — метод добавления значения без блокировки
+// Возвращает false, если очередь заполнена. Вызывается только производителем
+func (q *SPSC[T]) TryPush(v T) bool {
+	tail := q.tail.Load()
+	if tail-q.cachedHead == uint64(len(q.buf)) {
+		q.cachedHead = q.head.Load()
+		if tail-q.cachedHead == uint64(len(q.buf)) {
+			return false
+		}
+	}
+	q.buf[tail&q.mask] = v
+	q.tail.Store(tail + 1)
+	return true
+}
+
+// TryPop — метод извлечения значения без блокировки
+// Возвращает false, если очередь пуста. Вызывается только потребителем
+func (q *SPSC[T]) TryPop() (T, bool) {
+	var zero T
+	head := q.head.Load()
+	if head == q.cachedTail {
+		q.cachedTail = q.tail.Load()
+		if head == q.cachedTail {
+			return zero, false
+		}
+	}
+	v := q.buf[head&q.mask]
+	// Обнуляем ячейку, чтобы не удерживать значение от сборщика мусора
+	q.buf[head&q.mask] = zero
+	q.head.Store(head + 1)
+	return v, true
+}
+
+// Len — метод получения приблизительного количества значений в очереди
+func (q *SPSC[T]) Len() int {
+	return int(q.tail.Load() - q.head.Load())
+}
+
+// Cap — метод получения емкости очереди
+func (q *SPSC[T]) Cap() int {
+	return len(q.buf)
+}
@@ -0,0 +1,316 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrExhausted — ошибка нехватки бюджета при захвате без ожидания
+var ErrExhausted = errors.New("бюджет исчерпан")
+
+// tree — общее состояние дерева бюджетов
+// Все узлы одного дерева защищены одним мьютексом: захват в дочернем
+// бюджете меняет занятость всех предков, и делать это нужно атомарно
+type tree struct {
+	mutex sync.Mutex
+	// Закрывается и заменяется новым при освобождении единиц в любом узле
+	changed chan struct{}
+}
+
+// notifyLocked — метод пробуждения всех ожидающих в дереве
+// Вызывается при захваченном мьютексе
+func (t *tree) notifyLocked() {
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
+
+// Budget — узел иерархического бюджета
+// Корень задает общий бюджет (например, 1000 единиц памяти). Дочерний бюджет
+// получает гарантированную долю родителя (reserved), которая учитывается как
+// занятая у родителя всегда, и может занимать сверх нее свободные единицы
+// родителя вплоть до собственного предела (limit)
+type Budget struct {
+	tree   *tree
+	name   string
+	parent *Budget
+	// Гарантированная доля, выделенная из бюджета родителя
+	reserved int64
+	// Максимальное количество единиц, которое может занять узел вместе с потомками
+	limit int64
+	// Единицы, захваченные непосредственно этим узлом
+	direct int64
+	// Занятость, которую потомки создают в этом узле: сумма max(reserved, occupied) детей
+	children int64
+}
+
+// NewRoot — функция создания корневого бюджета на capacity единиц
+func NewRoot(name string, capacity int64) (*Budget, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("бюджет %q: емкость должна быть положительной: %d", name, capacity)
+	}
+	return &Budget{
+		tree:     &tree{changed: make(chan struct{})},
+		name:     name,
+		reserved: capacity,
+		limit:    capacity,
+	}, nil
+}
+
+// NewChild — метод создания дочернего бюджета
+// reserved — гарантированная доля, которая сразу занимается у родителя
+// limit — предел, до которого дочерний бюджет может занимать единицы
+// (сверх reserved — за счет свободных единиц родителя)
+func (b *Budget) NewChild(name string, reserved, limit int64) (*Budget, error) {
+	if reserved < 0 || limit <= 0 || reserved > limit {
+		return nil, fmt.Errorf("бюджет %q: некорректные параметры: гарантировано %d, предел %d", name, reserved, limit)
+	}
+
+	b.tree.mutex.Lock()
+	defer b.tree.mutex.Unlock()
+
+	// Гарантированная доля ребенка сразу занимает место у родителя и выше
+	if !b.applyLocked(reserved, true) {
+		return nil, fmt.Errorf("бюджет %q: у родителя %q недостаточно единиц для гарантированной доли %d", name, b.name, reserved)
+	}
+	return &Budget{
+		tree:     b.tree,
+		name:     name,
+		parent:   b,
+		reserved: reserved,
+		limit:    limit,
+	}, nil
+}
+
+// Name — метод получения имени бюджета
+func (b *Budget) Name() string {
+	return b.name
+}
+
+// occupied — метод получения занятости узла (свои единицы плюс вклад потомков)
+func (b *Budget) occupied() int64 {
+	return b.direct + b.children
+}
+
+// commitment — метод получения вклада узла в занятость родителя
+func commitment(reserved, occupied int64) int64 {
+	if occupied > reserved {
+		return occupied
+	}
+	return reserved
+}
+
+// fitsLocked — метод проверки, можно ли увеличить занятость узла на delta,
+// не превысив предел ни одного узла на пути к корню
+// Вызывается при захваченном мьютексе дерева
+func (b *Budget) fitsLocked(delta int64) bool {
+	for node, d := b, delta; node != nil && d > 0; node = node.parent {
+		before := node.occupied()
+		after := before + d
+		if after > node.limit {
+			return false
+		}
+		// Рост занятости в пределах гарантированной доли родителю ничего не стоит
+		d = commitment(node.reserved, after) - commitment(node.reserved, before)
+	}
+	return true
+}
+
+// applyLocked — метод изменения занятости узла на delta с учетом всех предков
+// Если viaChildren — изменение относится к вкладу потомков (гарантированная доля
+// нового ребенка), иначе к единицам, захваченным самим узлом.
+// Возвращает false и ничего не меняет, если изменение превысило бы предел
+// Вызывается при захваченном мьютексе дерева
+func (b *Budget) applyLocked(delta int64, viaChildren bool) bool {
+	if !b.fitsLocked(delta) {
+		return false
+	}
+	for node, d, first := b, delta, true; node != nil && d != 0; node, first = node.parent, false {
+		before := node.occupied()
+		if first && !viaChildren {
+			node.direct += d
+		} else {
+			node.children += d
+		}
+		d = commitment(node.reserved, node.occupied()) - commitment(node.reserved, before)
+	}
+	return true
+}
+
+// Lease — аренда захваченных единиц бюджета
+// Единицы возвращаются вызовом Release или автоматически по истечении срока аренды
+type Lease struct {
+	budget *Budget
+	units  int64
+	// Защищено мьютексом дерева
+	released bool
+	timer    *time.Timer
+	// Поколение таймера: растет при каждом Renew, чтобы уже запущенный
+	// обработчик прежнего срока не завершил продленную аренду
+	generation uint64
+}
+
+// Units — метод получения количества арендованных единиц
+func (l *Lease) Units() int64 {
+	return l.units
+}
+
+// Release — метод возврата арендованных единиц
+// Повторный вызов ничего не делает
+func (l *Lease) Release() {
+	t := l.budget.tree
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	l.releaseLocked()
+}
+
+// releaseLocked — метод возврата единиц при захваченном мьютексе дерева
+func (l *Lease) releaseLocked() {
+	if l.released {
+		return
+	}
+	l.released = true
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+	l.budget.applyLocked(-l.units, false)
+	l.budget.tree.notifyLocked()
+}
+
+// Renew — метод продления аренды на ttl от текущего момента
+// Возвращает false, если аренда уже завершена
+func (l *Lease) Renew(ttl time.Duration) bool {
+	t := l.budget.tree
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if l.released {
+		return false
+	}
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+	l.generation++
+	l.timer = l.expireAfter(ttl)
+	return true
+}
+
+// expireAfter — метод запуска таймера истечения текущего поколения аренды
+// Вызывается при захваченном мьютексе дерева. Stop не останавливает уже
+// запущенный обработчик, поэтому обработчик сверяет поколение
+func (l *Lease) expireAfter(ttl time.Duration) *time.Timer {
+	generation := l.generation
+	return time.AfterFunc(ttl, func() {
+		t := l.budget.tree
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+		if l.generation == generation {
+			l.releaseLocked()
+		}
+	})
+}
+
+// TryAcquire — метод захвата n единиц без ожидания
+// ttl — срок аренды (0 — бессрочно, до вызова Release)
+func (b *Budget) TryAcquire(n int64, ttl time.Duration) (*Lease, error) {
+	if err := b.validate(n); err != nil {
+		return nil, err
+	}
+
+	b.tree.mutex.Lock()
+	defer b.tree.mutex.Unlock()
+	if !b.applyLocked(n, false) {
+		return nil, ErrExhausted
+	}
+	return b.newLeaseLocked(n, ttl), nil
+}
+
+// Acquire — метод захвата n единиц с ожиданием
+// Блокируется, пока единицы не освободятся или пока не отменен ctx
+// ttl — срок аренды (0 — бессрочно, до вызова Release)
+func (b *Budget) Acquire(ctx context.Context, n int64, ttl time.Duration) (*Lease, error) {
+	if err := b.validate(n); err != nil {
+		return nil, err
+	}
+
+	for {
+		b.tree.mutex.Lock()
+		if b.applyLocked(n, false) {
+			lease := b.newLeaseLocked(n, ttl)
+			b.tree.mutex.Unlock()
+			return lease, nil
+		}
+		changed := b.tree.changed
+		b.tree.mutex.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// validate — метод проверки запрошенного количества единиц
+func (b *Budget) validate(n int64) error {
+	if n <= 0 {
+		return fmt.Errorf("бюджет %q: количество единиц должно быть положительным: %d", b.name, n)
+	}
+	if n > b.limit {
+		return fmt.Errorf("бюджет %q: запрошено %d единиц при пределе %d", b.name, n, b.limit)
+	}
+	return nil
+}
+
+// newLeaseLocked — метод оформления аренды
+// Вызывается при захваченном мьютексе дерева
+func (b *Budget) newLeaseLocked(n int64, ttl time.Duration) *Lease {
+	l := &Lease{budget: b, units: n}
+	if ttl > 0 {
+		l.timer = l.expireAfter(ttl)
+	}
+	return l
+}
+
+// Usage — снимок использования бюджета
+type Usage struct {
+	Name     string
+	Reserved int64
+	Limit    int64
+	// Единицы, захваченные самим узлом
+	Direct int64
+	// Занятость, создаваемая потомками (с учетом их гарантированных долей)
+	Children int64
+	// Сколько еще единиц можно захватить в этом узле прямо сейчас
+	Available int64
+}
+
+// Usage — метод получения снимка использования бюджета
+func (b *Budget) Usage() Usage {
+	b.tree.mutex.Lock()
+	defer b.tree.mutex.Unlock()
+
+	// Занятость монотонно ограничена пределами предков, поэтому
+	// максимально возможный захват находим двоичным поиском
+	lo, hi := int64(0), b.limit-b.occupied()
+	for lo < hi {
+		mid := lo + (hi-lo+1)/2
+		if b.fitsLocked(mid) {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	available := lo
+
+	return Usage{
+		Name:      b.name,
+		Reserved:  b.reserved,
+		Limit:     b.limit,
+		Direct:    b.direct,
+		Children:  b.children,
+		Available: available,
+	}
+}
@@ -0,0 +1,19 @@
+package ratelimit
+
+import "context"
+
+// RateLimiter — интерфейс ограничителя частоты
+// Реализуется локальным Limiter и распределенным RedisLimiter, чтобы код
+// мог переключаться между ними без изменений
+type RateLimiter interface {
+	// Проверка, можно ли выполнить одно действие прямо сейчас
+	Allow() bool
+	// Проверка, можно ли выполнить n действий прямо сейчас
+	AllowN(n int) bool
+	// Ожидание одного токена с отменой через ctx
+	Wait(ctx context.Context) error
+	// Ожидание n токенов с отменой через ctx
+	WaitN(ctx context.Context, n int) error
+}
+
+var _ RateLimiter = (*Limiter)(nil)
@@ -0,0 +1,17 @@
+package ratelimit
+
+import "encoding/json"
+
+// MarshalJSON — метод выгрузки параметров и текущего состояния в JSON
+func (l *Limiter) MarshalJSON() ([]byte, error) {
+	l.mutex.Lock()
+	l.advanceLocked(l.clock.Now())
+	st := struct {
+		Rate   float64 `json:"rate"`
+		Burst  int     `json:"burst"`
+		Tokens float64 `json:"tokens"`
+	}{l.rate, l.burst, l.tokens}
+	l.mutex.Unlock()
+
+	return json.Marshal(st)
+}
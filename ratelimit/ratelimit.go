@@ -0,0 +1,329 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"goroutines-example/clock"
+)
+
+// Limiter — ограничитель частоты по алгоритму «корзина токенов»
+// Токены пополняются со скоростью rate в секунду, в корзине помещается
+// не более burst токенов. Скорость и размер корзины можно менять на лету
+// (например, при перечитывании конфигурации)
+type Limiter struct {
+	// Защита состояния ограничителя
+	mutex sync.Mutex
+	// Скорость пополнения (токенов в секунду)
+	rate float64
+	// Емкость корзины
+	burst int
+	// Количество токенов на момент last (может быть отрицательным
+	// из-за резервирований в долг)
+	tokens float64
+	// Момент последнего пересчета количества токенов
+	last time.Time
+	// Источник времени
+	clock clock.Clock
+	// Подписчики на изменение параметров
+	listeners map[int]func(rate float64, burst int)
+	// Номер следующего подписчика
+	nextListener int
+}
+
+// Option — функциональная опция ограничителя
+type Option func(*Limiter)
+
+// WithClock — опция задания источника времени (по умолчанию реальное время)
+func WithClock(c clock.Clock) Option {
+	return func(l *Limiter) {
+		l.clock = c
+	}
+}
+
+// NewLimiter — функция создания ограничителя
+// rate — количество токенов в секунду, burst — емкость корзины
+// Изначально корзина заполнена
+func NewLimiter(rate float64, burst int, opts ...Option) (*Limiter, error) {
+	if rate <= 0 {
+		return nil, fmt.Errorf("скорость должна быть положительной: %v", rate)
+	}
+	if burst <= 0 {
+		return nil, fmt.Errorf("емкость корзины должна быть положительной: %d", burst)
+	}
+	l := &Limiter{
+		rate:      rate,
+		burst:     burst,
+		tokens:    float64(burst),
+		clock:     clock.Real(),
+		listeners: make(map[int]func(float64, int)),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.last = l.clock.Now()
+	return l, nil
+}
+
+// advanceLocked — метод пополнения корзины до момента now
+// Вызывается при захваченном мьютексе
+func (l *Limiter) advanceLocked(now time.Time) {
+	if now.Before(l.last) {
+		return
+	}
+	elapsed := now.Sub(l.last).Seconds()
+	l.tokens = math.Min(l.tokens+elapsed*l.rate, float64(l.burst))
+	l.last = now
+}
+
+// durationFor — функция получения времени, за которое накопится tokens токенов
+func durationFor(tokens, rate float64) time.Duration {
+	return time.Duration(tokens / rate * float64(time.Second))
+}
+
+// Allow — метод проверки, можно ли выполнить одно действие прямо сейчас
+func (l *Limiter) Allow() bool {
+	return l.AllowN(1)
+}
+
+// AllowN — метод проверки, можно ли выполнить n действий прямо сейчас
+// Если можно, токены списываются
+func (l *Limiter) AllowN(n int) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.advanceLocked(l.clock.Now())
+	if l.tokens < float64(n) {
+		return false
+	}
+	l.tokens -= float64(n)
+	return true
+}
+
+// Reservation — резервирование токенов на будущее
+// Держатель резервирования должен подождать Delay перед выполнением действия
+// или вызвать Cancel, если действие не понадобилось
+type Reservation struct {
+	limiter *Limiter
+	// Количество зарезервированных токенов
+	tokens int
+	// Момент, с которого действие разрешено
+	timeToAct time.Time
+	// Удалось ли зарезервировать токены
+	ok bool
+	// Защита от повторной отмены
+	mutex    sync.Mutex
+	canceled bool
+}
+
+// OK — метод проверки успешности резервирования
+// Резервирование невозможно, если запрошено больше токенов, чем емкость корзины
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay — метод получения времени, которое нужно подождать перед действием
+func (r *Reservation) Delay() time.Duration {
+	if !r.ok {
+		return time.Duration(math.MaxInt64)
+	}
+	d := r.timeToAct.Sub(r.limiter.clock.Now())
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// Cancel — метод отмены резервирования
+// Если момент действия еще не наступил, токены возвращаются в корзину
+func (r *Reservation) Cancel() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.ok || r.canceled {
+		return
+	}
+	r.canceled = true
+
+	l := r.limiter
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.clock.Now()
+	if !now.Before(r.timeToAct) {
+		return
+	}
+	l.advanceLocked(now)
+	l.tokens = math.Min(l.tokens+float64(r.tokens), float64(l.burst))
+}
+
+// ReserveN — метод резервирования n токенов
+// Токены списываются сразу (корзина может уйти в долг), а время,
+// которое нужно подождать, возвращается в Reservation
+func (l *Limiter) ReserveN(n int) *Reservation {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.reserveLocked(l.clock.Now(), n, time.Duration(math.MaxInt64))
+}
+
+// reserveLocked — метод резервирования n токенов с ожиданием не дольше maxWait
+// Вызывается при захваченном мьютексе
+func (l *Limiter) reserveLocked(now time.Time, n int, maxWait time.Duration) *Reservation {
+	r := &Reservation{limiter: l, tokens: n}
+	if n > l.burst {
+		return r
+	}
+
+	l.advanceLocked(now)
+	remaining := l.tokens - float64(n)
+	var wait time.Duration
+	if remaining < 0 {
+		wait = durationFor(-remaining, l.rate)
+	}
+	if wait > maxWait {
+		return r
+	}
+
+	l.tokens = remaining
+	r.ok = true
+	r.timeToAct = now.Add(wait)
+	return r
+}
+
+// Wait — метод ожидания одного токена
+func (l *Limiter) Wait(ctx context.Context) error {
+	return l.WaitN(ctx, 1)
+}
+
+// WaitN — метод ожидания n токенов
+// Возвращает ошибку, если n больше емкости корзины, если ctx отменен или
+// если ожидание заведомо не успеет завершиться до дедлайна ctx
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	now := l.clock.Now()
+	maxWait := time.Duration(math.MaxInt64)
+	if deadline, ok := ctx.Deadline(); ok {
+		maxWait = deadline.Sub(now)
+	}
+	burst := l.burst
+	r := l.reserveLocked(now, n, maxWait)
+	l.mutex.Unlock()
+
+	if !r.ok {
+		if n > burst {
+			return fmt.Errorf("запрошено %d токенов при емкости корзины %d", n, burst)
+		}
+		return fmt.Errorf("ожидание %d токенов превысит дедлайн контекста", n)
+	}
+
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+	timer := l.clock.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// SetRate — метод изменения скорости пополнения
+func (l *Limiter) SetRate(rate float64) error {
+	if rate <= 0 {
+		return fmt.Errorf("скорость должна быть положительной: %v", rate)
+	}
+	l.mutex.Lock()
+	l.advanceLocked(l.clock.Now())
+	l.rate = rate
+	listeners, burst := l.listenersLocked(), l.burst
+	l.mutex.Unlock()
+
+	notify(listeners, rate, burst)
+	return nil
+}
+
+// SetBurst — метод изменения емкости корзины
+func (l *Limiter) SetBurst(burst int) error {
+	if burst <= 0 {
+		return fmt.Errorf("емкость корзины должна быть положительной: %d", burst)
+	}
+	l.mutex.Lock()
+	l.advanceLocked(l.clock.Now())
+	l.burst = burst
+	if l.tokens > float64(burst) {
+		l.tokens = float64(burst)
+	}
+	listeners, rate := l.listenersLocked(), l.rate
+	l.mutex.Unlock()
+
+	notify(listeners, rate, burst)
+	return nil
+}
+
+// Rate — метод получения текущей скорости пополнения
+func (l *Limiter) Rate() float64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.rate
+}
+
+// Burst — метод получения текущей емкости корзины
+func (l *Limiter) Burst() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.burst
+}
+
+// Tokens — метод получения текущего количества токенов в корзине
+func (l *Limiter) Tokens() float64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.advanceLocked(l.clock.Now())
+	return l.tokens
+}
+
+// OnChange — метод подписки на изменение скорости или емкости корзины
+// Возвращает функцию отмены подписки
+func (l *Limiter) OnChange(listener func(rate float64, burst int)) (unsubscribe func()) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	id := l.nextListener
+	l.nextListener++
+	l.listeners[id] = listener
+
+	return func() {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		delete(l.listeners, id)
+	}
+}
+
+// listenersLocked — метод получения копии списка подписчиков
+// Вызывается при захваченном мьютексе
+func (l *Limiter) listenersLocked() []func(float64, int) {
+	result := make([]func(float64, int), 0, len(l.listeners))
+	for _, fn := range l.listeners {
+		result = append(result, fn)
+	}
+	return result
+}
+
+// notify — функция уведомления подписчиков (вызывается без захваченного мьютекса,
+// чтобы подписчик мог обращаться к ограничителю)
+func notify(listeners []func(float64, int), rate float64, burst int) {
+	for _, fn := range listeners {
+		fn(rate, burst)
+	}
+}
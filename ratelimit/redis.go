@@ -0,0 +1,241 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"goroutines-example/clock"
+)
+
+// tokenBucketScript — Lua-скрипт корзины токенов в Redis
+// Состояние хранится в хэше KEYS[1] (tokens, ts). Время берется из Redis
+// (TIME), чтобы часы экземпляров не влияли на результат. Возвращает
+// {1, 0}, если токены списаны, или {0, ожидание в мс}
+const tokenBucketScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+
+local allowed = 0
+local wait = 0
+if tokens >= n then
+	tokens = tokens - n
+	allowed = 1
+else
+	wait = math.ceil((n - tokens) * 1000 / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return {allowed, wait}
+`
+
+// RedisScripter — минимальный клиент Redis, нужный RedisLimiter
+// Пакет не зависит от конкретного клиента: достаточно обертки над EVAL,
+// например над (*redis.Client).Eval(ctx, script, keys, args...).Result()
+// из go-redis. Ответ — массив из двух целых чисел (int64)
+type RedisScripter interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// FailMode — поведение RedisLimiter при недоступности Redis без локального резерва
+type FailMode int
+
+const (
+	// FailClosed — запрещать действия, пока Redis недоступен
+	FailClosed FailMode = iota
+	// FailOpen — разрешать действия, пока Redis недоступен
+	FailOpen
+)
+
+// RedisOption — функциональная опция RedisLimiter
+type RedisOption func(*RedisLimiter)
+
+// WithFallback — опция локального ограничителя на время недоступности Redis
+// Обычно ему задают долю общей скорости, приходящуюся на один экземпляр.
+// Если резерв задан, FailMode не используется
+func WithFallback(l *Limiter) RedisOption {
+	return func(r *RedisLimiter) {
+		r.fallback = l
+	}
+}
+
+// WithFailMode — опция поведения без резерва при недоступности Redis
+// (по умолчанию FailClosed)
+func WithFailMode(mode FailMode) RedisOption {
+	return func(r *RedisLimiter) {
+		r.failMode = mode
+	}
+}
+
+// WithRedisTimeout — опция таймаута одного обращения к Redis (по умолчанию 100 мс)
+func WithRedisTimeout(d time.Duration) RedisOption {
+	return func(r *RedisLimiter) {
+		r.timeout = d
+	}
+}
+
+// WithRedisClock — опция задания источника времени для ожидания
+// (по умолчанию реальное время); сама корзина живет по часам Redis
+func WithRedisClock(c clock.Clock) RedisOption {
+	return func(r *RedisLimiter) {
+		r.clock = c
+	}
+}
+
+// RedisLimiter — распределенный ограничитель частоты на Redis
+// Корзина токенов хранится в Redis и атомарно обновляется Lua-скриптом,
+// поэтому все экземпляры сервиса с одним ключом делят общую скорость.
+// Если Redis недоступен, решение принимает локальный резервный
+// ограничитель или, без него, политика FailMode
+type RedisLimiter struct {
+	client   RedisScripter
+	key      string
+	rate     float64
+	burst    int
+	fallback *Limiter
+	failMode FailMode
+	timeout  time.Duration
+	clock    clock.Clock
+
+	// Защита lastErr и failures
+	mutex sync.Mutex
+	// Последняя ошибка обращения к Redis
+	lastErr error
+	// Количество неудачных обращений к Redis
+	failures uint64
+}
+
+var _ RateLimiter = (*RedisLimiter)(nil)
+
+// NewRedisLimiter — функция создания распределенного ограничителя
+// key — ключ корзины в Redis, общий для всех экземпляров;
+// rate — количество токенов в секунду, burst — емкость корзины
+func NewRedisLimiter(client RedisScripter, key string, rate float64, burst int, opts ...RedisOption) (*RedisLimiter, error) {
+	if client == nil {
+		return nil, fmt.Errorf("клиент Redis не задан")
+	}
+	if rate <= 0 {
+		return nil, fmt.Errorf("скорость должна быть положительной: %v", rate)
+	}
+	if burst <= 0 {
+		return nil, fmt.Errorf("емкость корзины должна быть положительной: %d", burst)
+	}
+	r := &RedisLimiter{
+		client:  client,
+		key:     key,
+		rate:    rate,
+		burst:   burst,
+		timeout: 100 * time.Millisecond,
+		clock:   clock.Real(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r, nil
+}
+
+// take — метод попытки списать n токенов в Redis
+// Возвращает, списаны ли токены, и сколько ждать до следующей попытки.
+// Отмена ctx вызывающим возвращается как ctx.Err() и не считается сбоем Redis
+func (r *RedisLimiter) take(ctx context.Context, n int) (bool, time.Duration, error) {
+	evalCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	reply, err := r.client.Eval(evalCtx, tokenBucketScript, []string{r.key}, r.rate, r.burst, n)
+	if err == nil {
+		values, ok := reply.([]interface{})
+		if ok && len(values) == 2 {
+			allowed, ok1 := values[0].(int64)
+			wait, ok2 := values[1].(int64)
+			if ok1 && ok2 {
+				return allowed == 1, time.Duration(wait) * time.Millisecond, nil
+			}
+		}
+		err = fmt.Errorf("неожиданный ответ Redis: %v", reply)
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return false, 0, ctxErr
+	}
+
+	r.mutex.Lock()
+	r.lastErr = err
+	r.failures++
+	r.mutex.Unlock()
+	return false, 0, err
+}
+
+// Allow — метод проверки, можно ли выполнить одно действие прямо сейчас
+func (r *RedisLimiter) Allow() bool {
+	return r.AllowN(1)
+}
+
+// AllowN — метод проверки, можно ли выполнить n действий прямо сейчас
+func (r *RedisLimiter) AllowN(n int) bool {
+	allowed, _, err := r.take(context.Background(), n)
+	if err != nil {
+		if r.fallback != nil {
+			return r.fallback.AllowN(n)
+		}
+		return r.failMode == FailOpen
+	}
+	return allowed
+}
+
+// Wait — метод ожидания одного токена
+func (r *RedisLimiter) Wait(ctx context.Context) error {
+	return r.WaitN(ctx, 1)
+}
+
+// WaitN — метод ожидания n токенов
+// Повторяет попытку через время, подсказанное Redis. Очередности между
+// экземплярами нет: при высокой конкуренции ожидание может затянуться
+func (r *RedisLimiter) WaitN(ctx context.Context, n int) error {
+	if n > r.burst {
+		return fmt.Errorf("запрошено %d токенов при емкости корзины %d", n, r.burst)
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		allowed, wait, err := r.take(ctx, n)
+		if err != nil {
+			// Отмена вызывающим — не сбой Redis: ни резерва, ни режима отказа
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if r.fallback != nil {
+				return r.fallback.WaitN(ctx, n)
+			}
+			if r.failMode == FailOpen {
+				return nil
+			}
+			return fmt.Errorf("Redis недоступен: %w", err)
+		}
+		if allowed {
+			return nil
+		}
+		if err := clock.Sleep(ctx, r.clock, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// Failures — метод получения количества неудачных обращений к Redis и последней ошибки
+func (r *RedisLimiter) Failures() (uint64, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.failures, r.lastErr
+}
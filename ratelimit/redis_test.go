@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingScripter — клиент Redis, отвечающий только по отмене ctx
+type blockingScripter struct {
+	started chan struct{}
+}
+
+func (b *blockingScripter) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	close(b.started)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// TestRedisWaitNCanceled — отмена ctx во время обращения к Redis возвращается
+// вызывающему и не превращается в разрешение по FailOpen или в сбой Redis
+func TestRedisWaitNCanceled(t *testing.T) {
+	client := &blockingScripter{started: make(chan struct{})}
+	r, err := NewRedisLimiter(client, "k", 10, 1, WithFailMode(FailOpen), WithRedisTimeout(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-client.started
+		cancel()
+	}()
+	if err := r.WaitN(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Fatalf("WaitN вернул %v, ожидалась отмена контекста", err)
+	}
+	if failures, lastErr := r.Failures(); failures != 0 || lastErr != nil {
+		t.Fatalf("отмена учтена как сбой Redis: %d, %v", failures, lastErr)
+	}
+}
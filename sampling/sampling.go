@@ -0,0 +1,125 @@
+package sampling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"goroutines-example/clock"
+	"goroutines-example/semaphore"
+)
+
+// ErrSkip — ошибка вызова, не прошедшего выборку
+// Это не сбой: вызывающий код просто пропускает дорогую операцию
+var ErrSkip = errors.New("вызов пропущен выборкой")
+
+// Gate — шлюз выборки дорогих операций
+// Пропускает только часть вызовов (каждый N-й или не чаще X в секунду),
+// остальные получают ErrSkip. Для дорогой диагностики или обновления
+// кэша, которые запускаются из горячих путей
+type Gate struct {
+	// Решение о пропуске очередного вызова
+	admit func() bool
+
+	admitted atomic.Uint64
+	skipped  atomic.Uint64
+}
+
+// Option — функциональная опция шлюза
+type Option func(*options)
+
+// options — настройки шлюза
+type options struct {
+	clock clock.Clock
+}
+
+// WithClock — опция задания источника времени (по умолчанию реальное время)
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// EveryN — функция создания шлюза, пропускающего каждый n-й вызов
+// Первый вызов всегда проходит
+func EveryN(n uint64) (*Gate, error) {
+	if n == 0 {
+		return nil, errors.New("n должно быть положительным")
+	}
+	var counter atomic.Uint64
+	return &Gate{admit: func() bool {
+		return (counter.Add(1)-1)%n == 0
+	}}, nil
+}
+
+// PerSecond — функция создания шлюза, пропускающего не более rate вызовов в секунду
+// Вызовы пропускаются не чаще одного раза в 1/rate секунды, без накопления:
+// после простоя проходит один вызов, а не пачка
+func PerSecond(rate float64, opts ...Option) (*Gate, error) {
+	if rate <= 0 {
+		return nil, fmt.Errorf("частота должна быть положительной: %v", rate)
+	}
+	o := options{clock: clock.Real()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	interval := time.Duration(float64(time.Second) / rate)
+
+	var (
+		mutex sync.Mutex
+		last  time.Time
+		first = true
+	)
+	return &Gate{admit: func() bool {
+		now := o.clock.Now()
+		mutex.Lock()
+		defer mutex.Unlock()
+		if !first && now.Sub(last) < interval {
+			return false
+		}
+		first = false
+		last = now
+		return true
+	}}, nil
+}
+
+// Allow — метод проверки, проходит ли текущий вызов
+func (g *Gate) Allow() bool {
+	if g.admit() {
+		g.admitted.Add(1)
+		return true
+	}
+	g.skipped.Add(1)
+	return false
+}
+
+// Do — метод выполнения fn, если вызов прошел выборку
+// Иначе fn не вызывается и возвращается ErrSkip
+func (g *Gate) Do(fn func() error) error {
+	if !g.Allow() {
+		return ErrSkip
+	}
+	return fn()
+}
+
+// DoWith — метод выполнения fn под разрешением семафора, если вызов прошел выборку
+// Сначала проверяется выборка, поэтому пропущенные вызовы не занимают
+// и не ждут разрешения. Прошедший вызов ждет разрешение с отменой через ctx
+func (g *Gate) DoWith(ctx context.Context, sem semaphore.Semaphore, fn func(ctx context.Context) error) error {
+	if !g.Allow() {
+		return ErrSkip
+	}
+	if err := sem.AcquireContext(ctx); err != nil {
+		return err
+	}
+	defer sem.Release()
+	return fn(ctx)
+}
+
+// Stats — метод получения количества прошедших и отброшенных выборкой вызовов
+func (g *Gate) Stats() (admitted, skipped uint64) {
+	return g.admitted.Load(), g.skipped.Load()
+}
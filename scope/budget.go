@@ -0,0 +1,71 @@
+package scope
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SplitDeadline — функция разбиения оставшегося до дедлайна ctx времени на доли
+// Возвращает длительности, пропорциональные весам; чтобы разделить время
+// поровну, передайте одинаковые веса. Если у ctx нет дедлайна, ok=false
+func SplitDeadline(ctx context.Context, weights ...float64) (budgets []time.Duration, ok bool, err error) {
+	total := 0.0
+	for i, w := range weights {
+		if w <= 0 {
+			return nil, false, fmt.Errorf("вес доли %d должен быть положительным: %v", i, w)
+		}
+		total += w
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil, false, nil
+	}
+	remaining := time.Until(deadline)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	budgets = make([]time.Duration, len(weights))
+	for i, w := range weights {
+		budgets[i] = time.Duration(float64(remaining) * w / total)
+	}
+	return budgets, true, nil
+}
+
+// GoSplit — метод запуска дочерних горутин с разделением оставшегося времени области
+// Каждая fns[i] получает контекст с таймаутом, равным доле weights[i] от времени,
+// оставшегося до дедлайна области, поэтому даже выполненные одна за другой
+// функции в сумме не выйдут за бюджет вызывающей стороны.
+// Если weights равен nil, время делится поровну. Если у области нет дедлайна,
+// функции запускаются без дополнительного ограничения
+func (s *Scope) GoSplit(weights []float64, fns ...func(ctx context.Context) error) error {
+	if weights == nil {
+		weights = make([]float64, len(fns))
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	if len(weights) != len(fns) {
+		return fmt.Errorf("количество весов (%d) не совпадает с количеством функций (%d)", len(weights), len(fns))
+	}
+
+	budgets, ok, err := SplitDeadline(s.ctx, weights...)
+	if err != nil {
+		return err
+	}
+	for i, fn := range fns {
+		if !ok {
+			s.Go(fn)
+			continue
+		}
+		budget, fn := budgets[i], fn
+		s.Go(func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, budget)
+			defer cancel()
+			return fn(ctx)
+		})
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package scope
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+
+	"goroutines-example/cause"
+)
+
+// LeakHandler — обработчик утечки области видимости
+// Вызывается, если область была собрана сборщиком мусора без вызова Wait или Close.
+// По умолчанию сообщение выводится в stderr
+var LeakHandler = func(err error) {
+	fmt.Fprintln(os.Stderr, err)
+}
+
+// state — общее состояние области, на которое ссылаются дочерние горутины
+// Вынесено отдельно от Scope, чтобы работающие горутины не мешали
+// сборщику мусора обнаружить брошенную область
+type state struct {
+	ctx context.Context
+	// Отмена с причиной: первая ошибка, завершение или утечка области
+	cancel cause.CancelCauseFunc
+	wg     sync.WaitGroup
+
+	// Защита первой ошибки, признака закрытия и вложенных областей
+	mutex    sync.Mutex
+	firstErr error
+	closed   bool
+	// Область вложенная: ее закроет и дождется родительская
+	owned bool
+	// Состояния вложенных областей (не *Scope, чтобы брошенная вложенная
+	// область могла быть собрана, пока родительская жива)
+	children []*state
+}
+
+// Scope — область структурированной конкурентности
+// Горутины, запущенные через Go, привязаны ко времени жизни области:
+// Wait дожидается их всех (включая вложенные области), а Close отменяет
+// их контекст и тоже дожидается завершения. Ни одна горутина не переживает
+// свою область. Если область потеряна без Wait/Close, об этом сообщается
+// через LeakHandler
+type Scope struct {
+	*state
+	// Место создания области для сообщения об утечке
+	createdAt string
+}
+
+// New — функция создания области, дочерней по отношению к ctx
+// Отмена ctx отменяет контекст всех горутин области
+func New(ctx context.Context) *Scope {
+	return newScope(ctx, 2)
+}
+
+// newScope — функция создания области с запоминанием места создания
+// skip — количество кадров стека до кода, создающего область
+func newScope(ctx context.Context, skip int) *Scope {
+	ctx, cancel := cause.WithCancelCause(ctx)
+	s := &Scope{state: &state{ctx: ctx, cancel: cancel}}
+
+	if _, file, line, ok := runtime.Caller(skip); ok {
+		s.createdAt = fmt.Sprintf("%s:%d", file, line)
+	}
+	runtime.SetFinalizer(s, func(s *Scope) {
+		s.mutex.Lock()
+		closed, owned := s.closed, s.owned
+		s.mutex.Unlock()
+		// Вложенную область без Wait/Close закроет родительская, это не утечка
+		if !closed && !owned {
+			err := fmt.Errorf("область, созданная в %s, потеряна без вызова Wait или Close", s.createdAt)
+			LeakHandler(err)
+			s.cancel(err)
+		}
+	})
+	return s
+}
+
+// Context — метод получения контекста области
+func (s *Scope) Context() context.Context {
+	return s.ctx
+}
+
+// Go — метод запуска дочерней горутины в области
+// Первая ошибка, возвращенная горутиной, отменяет контекст области
+// (остальные горутины должны завершиться) и возвращается из Wait.
+// Она же становится причиной отмены, доступной через cause.Cause(ctx)
+func (s *Scope) Go(fn func(ctx context.Context) error) {
+	st := s.state
+	st.mutex.Lock()
+	if st.closed {
+		st.mutex.Unlock()
+		panic("scope: Go вызван после Wait или Close")
+	}
+	st.wg.Add(1)
+	st.mutex.Unlock()
+
+	go func() {
+		defer st.wg.Done()
+		if err := fn(st.ctx); err != nil {
+			st.fail(err)
+		}
+	}()
+}
+
+// Child — метод создания вложенной области
+// Вложенная область отменяется вместе с родительской. Родительская область
+// при Wait/Close после завершения своих горутин закрывает вложенные и
+// дожидается их горутин, даже если у вложенной не вызывали Wait или Close.
+// Ошибки вложенной области возвращает только ее собственный Wait.
+// В отличие от Go, Child можно вызывать из горутин области, пока
+// родительская ждет их в Wait: горутины таких областей она тоже дождется
+func (s *Scope) Child() *Scope {
+	st := s.state
+	child := newScope(st.ctx, 2)
+	child.owned = true
+	st.mutex.Lock()
+	st.children = append(st.children, child.state)
+	st.mutex.Unlock()
+	return child
+}
+
+// fail — метод сохранения первой ошибки и отмены области
+func (st *state) fail(err error) {
+	st.mutex.Lock()
+	if st.firstErr == nil {
+		st.firstErr = err
+	}
+	st.mutex.Unlock()
+	st.cancel(err)
+}
+
+// Wait — метод ожидания завершения всех горутин области
+// После возврата область закрыта, а ее контекст отменен.
+// Возвращает первую ошибку горутин области
+func (s *Scope) Wait() error {
+	s.closeAndWait()
+	s.cancel(nil)
+	return s.err()
+}
+
+// Close — метод отмены области и ожидания завершения всех ее горутин
+// Причина отмены для горутин области — cause.ErrShutdown
+func (s *Scope) Close() error {
+	s.markClosed()
+	s.cancel(cause.ErrShutdown)
+	s.closeAndWait()
+	return s.err()
+}
+
+// markClosed — метод закрытия области для новых горутин и вложенных областей
+func (st *state) markClosed() {
+	st.mutex.Lock()
+	st.closed = true
+	st.mutex.Unlock()
+}
+
+// closeAndWait — метод закрытия области и ожидания ее горутин,
+// а затем горутин вложенных областей
+// Вложенные области создаются горутинами области, поэтому их список
+// берется после завершения этих горутин
+func (st *state) closeAndWait() {
+	st.markClosed()
+	st.wg.Wait()
+	st.mutex.Lock()
+	children := st.children
+	st.mutex.Unlock()
+	for _, child := range children {
+		child.closeAndWait()
+	}
+}
+
+// err — метод получения первой ошибки области
+func (s *Scope) err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.firstErr
+}
@@ -0,0 +1,57 @@
+package scope
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestWaitClosesUnwaitedChild — Wait родительской области не зависает из-за
+// вложенной области без Wait/Close и дожидается ее горутин
+func TestWaitClosesUnwaitedChild(t *testing.T) {
+	s := New(context.Background())
+	var finished atomic.Bool
+	s.Go(func(ctx context.Context) error {
+		child := s.Child()
+		child.Go(func(ctx context.Context) error {
+			time.Sleep(20 * time.Millisecond)
+			finished.Store(true)
+			return errors.New("ошибка вложенной области")
+		})
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- s.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("ошибка вложенной области попала в родительскую: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Wait родительской области завис")
+	}
+	if !finished.Load() {
+		t.Fatal("Wait вернулся раньше горутины вложенной области")
+	}
+}
+
+// TestCloseCancelsChild — Close родительской области отменяет вложенную
+func TestCloseCancelsChild(t *testing.T) {
+	s := New(context.Background())
+	child := s.Child()
+	child.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- s.Close() }()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close родительской области завис")
+	}
+}
@@ -0,0 +1,71 @@
+package semaphore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WithOwnershipAssertions — опция включения проверок AssertHeld и AssertNotHeld
+// Включает учет разрешений по владельцам (см. AcquireOwner). Без опции
+// проверки ничего не делают, поэтому их можно оставлять в коде
+func WithOwnershipAssertions() Option {
+	return func(cs *CountingSemaphore) {
+		cs.ownersOf()
+		cs.assertions = true
+	}
+}
+
+// AssertHeld — метод проверки, что владелец удерживает хотя бы одно разрешение
+// Паникует с описанием текущих владельцев, если это не так — аналог
+// проверки удержания мьютекса для путей кода, которые должны выполняться
+// только под разрешением. owner "" — текущая горутина. Работает только
+// с опцией WithOwnershipAssertions и учитывает захваты через AcquireOwner
+func (cs *CountingSemaphore) AssertHeld(owner string) {
+	if !cs.assertions {
+		return
+	}
+	if owner == "" {
+		owner = ownerName(nil)
+	}
+	if cs.HeldBy(owner) == 0 {
+		panic(cs.ownershipViolation(owner, "не удерживает разрешение"))
+	}
+}
+
+// AssertNotHeld — метод проверки, что владелец не удерживает разрешений
+// Например, перед повторным захватом, который привел бы к самоблокировке.
+// owner "" — текущая горутина. Работает только с опцией WithOwnershipAssertions
+func (cs *CountingSemaphore) AssertNotHeld(owner string) {
+	if !cs.assertions {
+		return
+	}
+	if owner == "" {
+		owner = ownerName(nil)
+	}
+	if held := cs.HeldBy(owner); held > 0 {
+		panic(cs.ownershipViolation(owner, fmt.Sprintf("удерживает %d разрешений", held)))
+	}
+}
+
+// ownershipViolation — метод формирования диагностики нарушенной проверки
+func (cs *CountingSemaphore) ownershipViolation(owner, problem string) string {
+	o := cs.owners
+	o.mutex.Lock()
+	holders := make([]string, 0, len(o.held))
+	for name, n := range o.held {
+		holders = append(holders, fmt.Sprintf("%s=%d", name, n))
+	}
+	o.mutex.Unlock()
+	sort.Strings(holders)
+
+	name := cs.name
+	if name == "" {
+		name = "без имени"
+	}
+	if len(holders) == 0 {
+		holders = append(holders, "нет")
+	}
+	return fmt.Sprintf("semaphore %s: %s %s (свободно %d из %d; владельцы: %s)",
+		name, owner, problem, cs.AvailablePermits(), cs.MaxPermits(), strings.Join(holders, ", "))
+}
@@ -0,0 +1,189 @@
+package semaphore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"goroutines-example/internal/runtimeinfo"
+)
+
+// AuditRecord — запись журнала аудита об одном захвате разрешения
+type AuditRecord struct {
+	// Имя семафора
+	Semaphore string `json:"semaphore,omitempty"`
+	// Кто захватил разрешение (передается в AcquireAs)
+	Who string `json:"who"`
+	// Момент захвата (или отказа)
+	AcquiredAt time.Time `json:"acquired_at"`
+	// Время ожидания разрешения
+	Wait time.Duration `json:"wait_ns"`
+	// Время удержания разрешения (0 для неудачного захвата)
+	Hold time.Duration `json:"hold_ns,omitempty"`
+	// Места захвата и освобождения в коде (файл:строка)
+	AcquireSite string `json:"acquire_site"`
+	ReleaseSite string `json:"release_site,omitempty"`
+	// Ошибка захвата (пусто при успехе)
+	Err string `json:"err,omitempty"`
+}
+
+// AuditSink — получатель записей журнала аудита
+// Вызывается синхронно в горутине, освобождающей разрешение, поэтому
+// реализация не должна надолго блокироваться
+type AuditSink interface {
+	Audit(rec AuditRecord)
+}
+
+// AuditOptions — параметры выборки записей аудита
+type AuditOptions struct {
+	// Доля захватов, попадающих в журнал (0–1)
+	SampleRate float64
+	// Захваты, ожидавшие не меньше SlowWait, записываются всегда (0 — выключено)
+	SlowWait time.Duration
+	// Разрешения, удерживавшиеся не меньше SlowHold, записываются всегда (0 — выключено)
+	SlowHold time.Duration
+}
+
+// audit — подключенный журнал аудита
+type audit struct {
+	sink AuditSink
+	opts AuditOptions
+}
+
+// WithAudit — опция подключения журнала аудита захватов
+// Записываются захваты через AcquireAs: неудачные — всегда, успешные —
+// по правилам выборки opts. Захваты через Acquire не аудируются, так как
+// у анонимного разрешения нельзя измерить время удержания
+func WithAudit(sink AuditSink, opts AuditOptions) Option {
+	return func(cs *CountingSemaphore) {
+		cs.audit = &audit{sink: sink, opts: opts}
+	}
+}
+
+// sampled — метод проверки, попадает ли запись в журнал
+func (a *audit) sampled(rec AuditRecord) bool {
+	if rec.Err != "" {
+		return true
+	}
+	if a.opts.SlowWait > 0 && rec.Wait >= a.opts.SlowWait {
+		return true
+	}
+	if a.opts.SlowHold > 0 && rec.Hold >= a.opts.SlowHold {
+		return true
+	}
+	return rand.Float64() < a.opts.SampleRate
+}
+
+// Permit — захваченное разрешение с информацией для аудита
+type Permit struct {
+	cs       *CountingSemaphore
+	record   AuditRecord
+	released atomic.Bool
+}
+
+// AcquireAs — метод захвата разрешения от имени who с записью в журнал аудита
+// Разрешение освобождается через Permit.Release. Без подключенного журнала
+// работает как AcquireContext
+func (cs *CountingSemaphore) AcquireAs(ctx context.Context, who string) (*Permit, error) {
+	start := cs.clock.Now()
+	err := cs.AcquireContext(ctx)
+
+	p := &Permit{cs: cs, record: AuditRecord{
+		Semaphore:  cs.name,
+		Who:        who,
+		AcquiredAt: cs.clock.Now(),
+		Wait:       cs.clock.Since(start),
+	}}
+	if cs.audit != nil {
+		p.record.AcquireSite = runtimeinfo.CallerSite(2)
+	}
+	if err != nil {
+		if cs.audit != nil {
+			p.record.Err = err.Error()
+			cs.audit.sink.Audit(p.record)
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// Release — метод освобождения разрешения с записью в журнал аудита
+// Повторное освобождение возвращает ошибку
+func (p *Permit) Release() error {
+	if !p.released.CompareAndSwap(false, true) {
+		return errors.New("разрешение уже освобождено")
+	}
+	if err := p.cs.Release(); err != nil {
+		return err
+	}
+	if a := p.cs.audit; a != nil {
+		rec := p.record
+		rec.Hold = p.cs.clock.Since(rec.AcquiredAt)
+		rec.ReleaseSite = runtimeinfo.CallerSite(2)
+		if a.sampled(rec) {
+			a.sink.Audit(rec)
+		}
+	}
+	return nil
+}
+
+// JSONLinesSink — журнал аудита в формате JSON Lines (одна запись на строку)
+// Безопасен для конкурентного использования; ошибка записи запоминается
+// и возвращается из Err и Close
+type JSONLinesSink struct {
+	// Защита enc и err
+	mutex sync.Mutex
+	enc   *json.Encoder
+	w     io.Writer
+	err   error
+}
+
+// NewJSONLinesSink — функция создания журнала, пишущего в w
+func NewJSONLinesSink(w io.Writer) *JSONLinesSink {
+	return &JSONLinesSink{enc: json.NewEncoder(w), w: w}
+}
+
+// OpenJSONLinesFile — функция открытия файла журнала для дозаписи
+func OpenJSONLinesFile(path string) (*JSONLinesSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть журнал аудита: %w", err)
+	}
+	return NewJSONLinesSink(f), nil
+}
+
+// Audit — метод записи одной записи
+func (s *JSONLinesSink) Audit(rec AuditRecord) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.err != nil {
+		return
+	}
+	s.err = s.enc.Encode(rec)
+}
+
+// Err — метод получения первой ошибки записи
+func (s *JSONLinesSink) Err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.err
+}
+
+// Close — метод закрытия журнала (если писатель поддерживает закрытие)
+func (s *JSONLinesSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if c, ok := s.w.(io.Closer); ok {
+		if err := c.Close(); err != nil && s.err == nil {
+			s.err = err
+		}
+	}
+	return s.err
+}
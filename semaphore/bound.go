@@ -0,0 +1,63 @@
+package semaphore
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// BoundPermit — разрешение, привязанное ко времени жизни контекста
+type BoundPermit struct {
+	cs *CountingSemaphore
+	// Разрешение уже возвращено (явно или по завершении контекста)
+	released atomic.Bool
+	// Разрешение вернул наблюдатель контекста, а не Release
+	auto atomic.Bool
+	// Закрывается при явном Release, чтобы остановить наблюдателя
+	stop chan struct{}
+}
+
+// AcquireBound — метод захвата разрешения, привязанного к ctx
+// Если вызывающий код не освободит разрешение до отмены или дедлайна ctx,
+// оно будет освобождено автоматически. Защищает от утечки разрешений
+// брошенными обработчиками запросов. Контекст без Done (например,
+// context.Background) не ограничивает разрешение
+func (cs *CountingSemaphore) AcquireBound(ctx context.Context) (*BoundPermit, error) {
+	if err := cs.AcquireContext(ctx); err != nil {
+		return nil, err
+	}
+
+	p := &BoundPermit{cs: cs, stop: make(chan struct{})}
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				if p.released.CompareAndSwap(false, true) {
+					p.auto.Store(true)
+					cs.Release()
+				}
+			case <-p.stop:
+			}
+		}()
+	}
+	return p, nil
+}
+
+// Release — метод освобождения разрешения
+// Если разрешение уже освобождено автоматически, ничего не делает;
+// повторный явный вызов возвращает ошибку
+func (p *BoundPermit) Release() error {
+	if !p.released.CompareAndSwap(false, true) {
+		if p.auto.Load() {
+			return nil
+		}
+		return errors.New("разрешение уже освобождено")
+	}
+	close(p.stop)
+	return p.cs.Release()
+}
+
+// AutoReleased — метод проверки, было ли разрешение освобождено по завершении контекста
+func (p *BoundPermit) AutoReleased() bool {
+	return p.auto.Load()
+}
@@ -0,0 +1,59 @@
+package semaphore
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"goroutines-example/clock"
+)
+
+// chaos — генератор случайных сбоев для режима хаоса
+// Используется в тестах, чтобы проверить, что код не полагается на то,
+// что семафор всегда отвечает мгновенно и никогда не возвращает таймаут
+type chaos struct {
+	// Защита генератора случайных чисел (rand.Rand не потокобезопасен)
+	mutex sync.Mutex
+	rnd   *rand.Rand
+	// Максимальная случайная задержка перед операцией
+	maxDelay time.Duration
+	// Вероятность ложного таймаута при захвате (от 0 до 1)
+	timeoutRate float64
+}
+
+// WithChaos — опция включения режима хаоса
+// seed — начальное значение генератора, чтобы сбой можно было воспроизвести
+// maxDelay — максимальная случайная задержка перед Acquire и Release
+// timeoutRate — вероятность (от 0 до 1), с которой захват завершится
+// ложным таймаутом, даже если разрешения есть
+// Не предназначена для использования в рабочем окружении
+func WithChaos(seed int64, maxDelay time.Duration, timeoutRate float64) Option {
+	return func(cs *CountingSemaphore) {
+		cs.chaos = &chaos{
+			rnd:         rand.New(rand.NewSource(seed)),
+			maxDelay:    maxDelay,
+			timeoutRate: timeoutRate,
+		}
+	}
+}
+
+// delay — метод случайной задержки текущей горутины
+func (c *chaos) delay(clk clock.Clock) {
+	if c.maxDelay <= 0 {
+		return
+	}
+	c.mutex.Lock()
+	d := time.Duration(c.rnd.Int63n(int64(c.maxDelay)))
+	c.mutex.Unlock()
+	clk.Sleep(d)
+}
+
+// spuriousTimeout — метод принятия решения о ложном таймауте
+func (c *chaos) spuriousTimeout() bool {
+	if c.timeoutRate <= 0 {
+		return false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.rnd.Float64() < c.timeoutRate
+}
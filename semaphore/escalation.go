@@ -0,0 +1,131 @@
+package semaphore
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBreakerOpen — ошибка захвата при разомкнутом выключателе политики таймаутов
+var ErrBreakerOpen = errors.New("семафор перегружен: захват временно отклоняется")
+
+// TimeoutPolicy — политика эскалации таймаутов захвата
+// Пока семафор перегружен (разрешений нет уже при входе в захват), первые
+// InitialCount таких захватов ждут Initial, а последующие — Escalated
+// (обычно меньше, чтобы при затяжной перегрузке вызывающие быстрее
+// получали отказ). Захват, заставший свободное разрешение, сбрасывает
+// счетчик. После OpenAfter таймаутов подряд выключатель размыкается на
+// Cooldown: захваты сразу возвращают ErrBreakerOpen. По истечении Cooldown
+// захваты снова пропускаются, но первый же таймаут размыкает выключатель
+// опять, а успешный захват возвращает политику в исходное состояние
+type TimeoutPolicy struct {
+	Initial      time.Duration
+	InitialCount int
+	Escalated    time.Duration
+	// Количество таймаутов подряд до размыкания (0 — выключатель не используется)
+	OpenAfter int
+	Cooldown  time.Duration
+}
+
+// WithTimeoutPolicy — опция эскалации таймаутов захвата
+// Заменяет таймаут, переданный в NewCountingSemaphore, для Acquire,
+// AcquireContext, AcquireNContext и AcquireAtLeast
+func WithTimeoutPolicy(p TimeoutPolicy) Option {
+	return func(cs *CountingSemaphore) {
+		cs.escalation = &escalation{policy: p}
+	}
+}
+
+// escalation — состояние политики эскалации таймаутов
+type escalation struct {
+	policy TimeoutPolicy
+
+	// Защита полей ниже
+	mutex sync.Mutex
+	// Захваты подряд, заставшие семафор без свободных разрешений
+	contended int
+	// Таймауты подряд
+	timeouts int
+	// Момент, до которого выключатель разомкнут
+	openUntil time.Time
+}
+
+// begin — метод выбора таймаута для очередного захвата
+// contended — свободных разрешений при входе в захват не было
+func (e *escalation) begin(now time.Time, contended bool) (time.Duration, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if now.Before(e.openUntil) {
+		return 0, ErrBreakerOpen
+	}
+	if !contended {
+		e.contended = 0
+		return e.policy.Initial, nil
+	}
+	e.contended++
+	if e.contended <= e.policy.InitialCount {
+		return e.policy.Initial, nil
+	}
+	return e.policy.Escalated, nil
+}
+
+// open — метод проверки, разомкнут ли выключатель
+func (e *escalation) open(now time.Time) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return now.Before(e.openUntil)
+}
+
+// remaining — метод получения оставшегося времени размыкания выключателя
+func (e *escalation) remaining(now time.Time) time.Duration {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.openUntil.Sub(now)
+}
+
+// calm — метод учета захвата, получившего разрешения без ожидания
+func (e *escalation) calm() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.contended = 0
+	e.timeouts = 0
+}
+
+// done — метод учета исхода захвата
+func (e *escalation) done(now time.Time, timedOut bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if !timedOut {
+		e.timeouts = 0
+		return
+	}
+	e.timeouts++
+	if e.policy.OpenAfter > 0 && e.timeouts >= e.policy.OpenAfter {
+		e.openUntil = now.Add(e.policy.Cooldown)
+		// После остывания для повторного размыкания хватит одного таймаута
+		e.timeouts = e.policy.OpenAfter - 1
+	}
+}
+
+// acquireTimeout — метод получения таймаута очередного захвата
+// Без политики эскалации возвращает таймаут семафора
+func (cs *CountingSemaphore) acquireTimeout(contended bool) (time.Duration, error) {
+	if cs.escalation == nil {
+		return cs.timeout, nil
+	}
+	wait, err := cs.escalation.begin(cs.clock.Now(), contended)
+	if err != nil {
+		return 0, cs.saturated(err)
+	}
+	return wait, nil
+}
+
+// acquireDone — метод учета исхода захвата в политике эскалации
+// Отмена контекста не считается ни успехом, ни таймаутом
+func (cs *CountingSemaphore) acquireDone(timedOut bool) {
+	if cs.escalation != nil {
+		cs.escalation.done(cs.clock.Now(), timedOut)
+	}
+}
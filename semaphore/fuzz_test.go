@@ -0,0 +1,139 @@
+package semaphore
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Операции, которые разбираются из входных байтов FuzzSemaphoreOps
+const (
+	opTryAcquire = iota
+	opAcquire
+	opAcquireN
+	opRelease
+	opReleaseN
+	opResize
+	opCount
+)
+
+// fuzzMaxOps — предельное количество операций одного входа,
+// чтобы входы с ожиданиями по таймауту не растягивались надолго
+const fuzzMaxOps = 256
+
+// checkInvariants — функция проверки внутренних инвариантов семафора
+func checkInvariants(t *testing.T, cs *CountingSemaphore) {
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+	if len(cs.sem) > cs.maxPermits {
+		t.Errorf("свободных разрешений %d больше максимума %d", len(cs.sem), cs.maxPermits)
+	}
+	if cap(cs.sem) != cs.maxPermits {
+		t.Errorf("емкость канала %d не равна максимуму %d", cap(cs.sem), cs.maxPermits)
+	}
+	if d := cs.debt.Load(); d < 0 {
+		t.Errorf("отрицательный долг: %d", d)
+	}
+	if h := cs.heldPermits(); h < 0 {
+		t.Errorf("отрицательное количество захваченных разрешений: %d", h)
+	}
+}
+
+// FuzzSemaphoreOps — конкурентные последовательности захватов, освобождений
+// и изменений размера не нарушают инварианты и не теряют разрешения
+// Первый байт задает количество горутин, второй — начальный размер,
+// остальные распределяются между горутинами по кругу: младшие биты
+// выбирают операцию, старшие — ее аргумент
+// Горутины делают цель недетерминированной, поэтому при фаззинге стоит
+// ограничить минимизацию: go test -fuzz FuzzSemaphoreOps -fuzzminimizetime 10x
+func FuzzSemaphoreOps(f *testing.F) {
+	f.Add([]byte{1, 1, opAcquire, opRelease})
+	f.Add([]byte{2, 3, opAcquireN | 2<<3, opTryAcquire, opResize, opReleaseN | 3<<3, opRelease})
+	f.Add([]byte{4, 4, opAcquireN | 3<<3, opAcquireN | 3<<3, opResize | 1<<3, opAcquire, opReleaseN | 3<<3, opReleaseN | 3<<3, opResize | 3<<3})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) < 2 {
+			return
+		}
+		workers := int(data[0])%4 + 1
+		cs := NewCountingSemaphore(int(data[1])%8+1, 2*time.Millisecond)
+		ops := data[2:]
+		if len(ops) > fuzzMaxOps {
+			ops = ops[:fuzzMaxOps]
+		}
+
+		held := make([]int, workers)
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := w; i < len(ops); i += workers {
+					arg := int(ops[i]>>3)%4 + 1
+					switch int(ops[i]&7) % opCount {
+					case opTryAcquire:
+						if cs.TryAcquire() {
+							held[w]++
+						}
+					case opAcquire:
+						if cs.Acquire() == nil {
+							held[w]++
+						}
+					case opAcquireN:
+						if cs.AcquireN(arg) == nil {
+							held[w] += arg
+						}
+					case opRelease:
+						if held[w] == 0 {
+							break
+						}
+						if err := cs.Release(); err != nil {
+							t.Errorf("освобождение удерживаемого разрешения: %v", err)
+							return
+						}
+						held[w]--
+					case opReleaseN:
+						if arg > held[w] {
+							arg = held[w]
+						}
+						if err := cs.ReleaseN(arg); err != nil {
+							t.Errorf("освобождение %d удерживаемых разрешений: %v", arg, err)
+							return
+						}
+						held[w] -= arg
+					case opResize:
+						if err := cs.Resize(arg * 2); err != nil {
+							t.Errorf("изменение размера до %d: %v", arg*2, err)
+							return
+						}
+					}
+					checkInvariants(t, cs)
+				}
+			}(w)
+		}
+		wg.Wait()
+		if t.Failed() {
+			return
+		}
+
+		total := 0
+		for _, h := range held {
+			total += h
+		}
+		cs.mutex.RLock()
+		inUse := cs.heldPermits()
+		cs.mutex.RUnlock()
+		if inUse != total {
+			t.Fatalf("семафор считает захваченными %d разрешений, горутины удерживают %d", inUse, total)
+		}
+		if err := cs.ReleaseN(total); err != nil {
+			t.Fatalf("освобождение оставшихся %d разрешений: %v", total, err)
+		}
+		checkInvariants(t, cs)
+		if cs.AvailablePermits() != cs.MaxPermits() {
+			t.Fatalf("после освобождения всех разрешений доступно %d из %d", cs.AvailablePermits(), cs.MaxPermits())
+		}
+		if d := cs.debt.Load(); d != 0 {
+			t.Fatalf("после освобождения всех разрешений остался долг %d", d)
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package semaphore
+
+import "context"
+
+// Semaphore — интерфейс семафора для внедрения зависимостей
+// Код, принимающий Semaphore вместо *CountingSemaphore, можно тестировать
+// с semaphoretest.Fake или подключать к другим реализациям (например, xsync.Counting)
+type Semaphore interface {
+	// Захват одного разрешения с таймаутом семафора
+	Acquire() error
+	// Захват одного разрешения с таймаутом семафора и отменой через ctx
+	AcquireContext(ctx context.Context) error
+	// Захват n разрешений с таймаутом семафора и отменой через ctx
+	AcquireNContext(ctx context.Context, n int) error
+	// Попытка захвата одного разрешения без блокировки
+	TryAcquire() bool
+	// Освобождение одного разрешения
+	Release() error
+	// Количество свободных разрешений
+	AvailablePermits() int
+}
+
+var _ Semaphore = (*CountingSemaphore)(nil)
+
+// AcquireContext — метод захвата одного разрешения с отменой через ctx
+// Ожидание прерывается таймаутом семафора или отменой ctx, смотря что наступит раньше
+func (cs *CountingSemaphore) AcquireContext(ctx context.Context) error {
+	return cs.AcquireNContext(ctx, 1)
+}
+
+// AcquireNContext — метод захвата n разрешений с отменой через ctx
+// Захватывает либо все n разрешений, либо ни одного; конкурирующие
+// вызовы не держат части разрешений друг друга (см. AcquireAtLeast)
+func (cs *CountingSemaphore) AcquireNContext(ctx context.Context, n int) error {
+	_, err := cs.AcquireAtLeast(ctx, n, n)
+	return err
+}
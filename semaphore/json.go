@@ -0,0 +1,56 @@
+package semaphore
+
+import "encoding/json"
+
+// jsonState — конфигурация и состояние семафора для отладочной выгрузки
+// Длительности выводятся строками (например, "1.5s") для удобства чтения
+type jsonState struct {
+	Name          string             `json:"name,omitempty"`
+	MaxPermits    int                `json:"max_permits"`
+	Available     int                `json:"available"`
+	Held          int                `json:"held"`
+	Debt          int64              `json:"debt,omitempty"`
+	Timeout       string             `json:"timeout"`
+	HighWaterMark int64              `json:"high_water_mark"`
+	Waits         jsonWaits          `json:"waits"`
+	Utilization   map[string]float64 `json:"utilization,omitempty"`
+}
+
+// jsonWaits — распределение времени ожидания разрешений
+type jsonWaits struct {
+	Count uint64 `json:"count"`
+	P50   string `json:"p50"`
+	P99   string `json:"p99"`
+	Max   string `json:"max"`
+}
+
+// MarshalJSON — метод выгрузки конфигурации и текущего состояния в JSON
+// Предназначен для отладочных страниц (см. пакет debugstate)
+func (cs *CountingSemaphore) MarshalJSON() ([]byte, error) {
+	cs.mutex.RLock()
+	st := jsonState{
+		Name:       cs.name,
+		MaxPermits: cs.maxPermits,
+		Available:  len(cs.sem),
+		Held:       cs.heldPermits(),
+		Debt:       cs.debt.Load(),
+		Timeout:    cs.timeout.String(),
+	}
+	cs.mutex.RUnlock()
+
+	st.HighWaterMark = cs.inflight.HighWaterMark()
+	waits := cs.waits.Snapshot()
+	st.Waits = jsonWaits{
+		Count: waits.Count,
+		P50:   waits.P50.String(),
+		P99:   waits.P99.String(),
+		Max:   waits.Max.String(),
+	}
+	if util := cs.Utilization(); util != nil {
+		st.Utilization = make(map[string]float64, len(util))
+		for _, u := range util {
+			st.Utilization[u.Window.String()] = u.Value
+		}
+	}
+	return json.Marshal(st)
+}
@@ -0,0 +1,50 @@
+package semaphore
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// lockRetryPause — пауза перед повтором Lock после отказа без подсказки о повторе
+const lockRetryPause = 10 * time.Millisecond
+
+// locker — представление одного разрешения семафора как sync.Locker
+type locker struct {
+	cs *CountingSemaphore
+	// Паниковать при таймауте захвата вместо повторной попытки
+	panicOnTimeout bool
+}
+
+// AsLocker — метод получения представления семафора в виде sync.Locker
+// Lock захватывает одно разрешение, Unlock освобождает его, поэтому
+// семафор можно передавать в API, принимающие sync.Locker (например, sync.NewCond).
+// Так как у sync.Locker нет возврата ошибки, при таймауте захвата Lock
+// либо паникует (panicOnTimeout=true), либо повторяет попытку, пока не получит
+// разрешение; перед повтором выдерживается пауза по SleepRetryAfter, чтобы
+// мгновенные отказы (ErrQueueFull, ErrBreakerOpen) не крутили цикл вхолостую. Unlock без захваченного разрешения паникует, как и у sync.Mutex
+func (cs *CountingSemaphore) AsLocker(panicOnTimeout bool) sync.Locker {
+	return &locker{cs: cs, panicOnTimeout: panicOnTimeout}
+}
+
+// Lock — метод захвата разрешения
+func (l *locker) Lock() {
+	for {
+		err := l.cs.Acquire()
+		if err == nil {
+			return
+		}
+		if l.panicOnTimeout {
+			panic(err)
+		}
+		// Без отмены ctx ошибки быть не может
+		_ = l.cs.SleepRetryAfter(context.Background(), err, lockRetryPause)
+	}
+}
+
+// Unlock — метод освобождения разрешения
+func (l *locker) Unlock() {
+	if err := l.cs.Release(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,51 @@
+package semaphore
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+)
+
+// nextID — счетчик для выдачи семафорам глобального порядкового номера
+var nextID atomic.Uint64
+
+// ordered — функция получения семафоров в глобальном порядке захвата
+func ordered(sems []*CountingSemaphore) []*CountingSemaphore {
+	out := append([]*CountingSemaphore(nil), sems...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].id < out[j].id })
+	return out
+}
+
+// AcquireAll — функция захвата по одному разрешению у нескольких семафоров
+// Семафоры захватываются в глобальном порядке (по порядку создания), а не
+// в порядке аргументов, поэтому AcquireAll(ctx, a, b) и AcquireAll(ctx, b, a)
+// в разных горутинах не могут взаимно заблокироваться. Если какой-то захват
+// не удался (таймаут или отмена ctx), уже захваченные разрешения
+// освобождаются и возвращается ошибка: захватываются либо все, либо ни одного.
+// Один семафор, переданный несколько раз, отдает несколько разрешений
+func AcquireAll(ctx context.Context, sems ...*CountingSemaphore) error {
+	sorted := ordered(sems)
+	for i, cs := range sorted {
+		if err := cs.AcquireContext(ctx); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				sorted[j].Release()
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// ReleaseAll — функция освобождения разрешений, захваченных AcquireAll
+// Освобождает все семафоры, даже если некоторые освобождения завершились
+// ошибкой; возвращается первая ошибка
+func ReleaseAll(sems ...*CountingSemaphore) error {
+	var firstErr error
+	sorted := ordered(sems)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		if err := sorted[i].Release(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
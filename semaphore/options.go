@@ -0,0 +1,45 @@
+package semaphore
+
+import (
+	"goroutines-example/clock"
+	"goroutines-example/flightrec"
+)
+
+// Option — функциональная опция для настройки семафора при создании
+type Option func(*CountingSemaphore)
+
+// WithName — опция задания имени семафора
+// Имя используется в диагностике (например, в событиях бортового самописца)
+func WithName(name string) Option {
+	return func(cs *CountingSemaphore) {
+		cs.name = name
+	}
+}
+
+// WithFlightRecorder — опция подключения бортового самописца
+// В самописец записываются события захвата, освобождения и таймаута
+func WithFlightRecorder(r *flightrec.Recorder) Option {
+	return func(cs *CountingSemaphore) {
+		cs.recorder = r
+	}
+}
+
+// WithClock — опция задания источника времени
+// По умолчанию используется реальное время; в тестах можно передать
+// clock.NewFake, чтобы таймауты срабатывали детерминированно
+func WithClock(c clock.Clock) Option {
+	return func(cs *CountingSemaphore) {
+		cs.clock = c
+	}
+}
+
+// WithYieldHook — опция функции, вызываемой в точках переключения
+// В начале Acquire, TryAcquire, TryAcquireN и Release вызывается fn;
+// обычно это clock.Scheduler.Yield, чтобы планировщик перебирал
+// чередования горутин вокруг операций с семафором. Блокирующий захват
+// под планировщиком оборачивается в Scheduler.Block
+func WithYieldHook(fn func()) Option {
+	return func(cs *CountingSemaphore) {
+		cs.yield = fn
+	}
+}
@@ -0,0 +1,139 @@
+package semaphore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"goroutines-example/internal/runtimeinfo"
+)
+
+// ErrOwnerLimit — ошибка захвата сверх лимита разрешений одного владельца
+var ErrOwnerLimit = errors.New("владелец превысил лимит удерживаемых разрешений")
+
+// ownerKey — ключ контекста для метки владельца
+type ownerKey struct{}
+
+// WithOwner — функция получения контекста с меткой владельца разрешений
+// Захваты через AcquireOwner с этим контекстом учитываются на owner
+func WithOwner(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, ownerKey{}, owner)
+}
+
+// OwnerFromContext — функция получения метки владельца из контекста
+func OwnerFromContext(ctx context.Context) (string, bool) {
+	owner, ok := ctx.Value(ownerKey{}).(string)
+	return owner, ok
+}
+
+// owners — учет разрешений по владельцам
+type owners struct {
+	// Лимит разрешений одного владельца (0 — без лимита)
+	limit int
+
+	// Защита held
+	mutex sync.Mutex
+	held  map[string]int
+}
+
+// ownersOf — метод получения учета владельцев с созданием при необходимости
+// Вызывается только из опций, до начала работы с семафором
+func (cs *CountingSemaphore) ownersOf() *owners {
+	if cs.owners == nil {
+		cs.owners = &owners{held: make(map[string]int)}
+	}
+	return cs.owners
+}
+
+// WithOwnerLimit — опция лимита разрешений, одновременно удерживаемых одним владельцем
+// Владелец — метка из контекста (WithOwner) или, без нее, текущая
+// горутина. Лимит действует на AcquireOwner; захват сверх лимита сразу
+// возвращает ErrOwnerLimit, не дожидаясь разрешений. Не дает одному
+// неисправному вызывающему коду забрать весь бюджет
+func WithOwnerLimit(n int) Option {
+	return func(cs *CountingSemaphore) {
+		cs.ownersOf().limit = n
+	}
+}
+
+// ownerName — функция определения владельца: метка из ctx или текущая горутина
+func ownerName(ctx context.Context) string {
+	if ctx != nil {
+		if owner, ok := OwnerFromContext(ctx); ok {
+			return owner
+		}
+	}
+	return fmt.Sprintf("goroutine-%d", runtimeinfo.GoroutineID())
+}
+
+// AcquireOwner — метод захвата n разрешений с учетом владельца
+// Владелец берется из ctx (WithOwner), иначе — текущая горутина.
+// Освобождать разрешения нужно через ReleaseOwner с тем же владельцем
+func (cs *CountingSemaphore) AcquireOwner(ctx context.Context, n int) error {
+	owner := ownerName(ctx)
+	if o := cs.owners; o != nil {
+		o.mutex.Lock()
+		if o.limit > 0 && o.held[owner]+n > o.limit {
+			held := o.held[owner]
+			o.mutex.Unlock()
+			return fmt.Errorf("%w: %s удерживает %d из %d, запрошено еще %d", ErrOwnerLimit, owner, held, o.limit, n)
+		}
+		// Резервируем лимит до ожидания, чтобы параллельные захваты того же
+		// владельца не превысили его вместе
+		o.held[owner] += n
+		o.mutex.Unlock()
+	}
+
+	if err := cs.AcquireNContext(ctx, n); err != nil {
+		cs.forgetOwner(owner, n)
+		return err
+	}
+	return nil
+}
+
+// ReleaseOwner — метод освобождения n разрешений владельца
+// Владелец берется из ctx так же, как в AcquireOwner
+func (cs *CountingSemaphore) ReleaseOwner(ctx context.Context, n int) error {
+	owner := ownerName(ctx)
+	if o := cs.owners; o != nil {
+		o.mutex.Lock()
+		if o.held[owner] < n {
+			held := o.held[owner]
+			o.mutex.Unlock()
+			return fmt.Errorf("%s освобождает %d разрешений, а удерживает %d", owner, n, held)
+		}
+		o.mutex.Unlock()
+	}
+	if err := cs.ReleaseN(n); err != nil {
+		return err
+	}
+	cs.forgetOwner(owner, n)
+	return nil
+}
+
+// forgetOwner — метод снятия n разрешений с учета владельца
+func (cs *CountingSemaphore) forgetOwner(owner string, n int) {
+	o := cs.owners
+	if o == nil {
+		return
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.held[owner] -= n
+	if o.held[owner] <= 0 {
+		delete(o.held, owner)
+	}
+}
+
+// HeldBy — метод получения количества разрешений, удерживаемых владельцем
+// Учитываются только захваты через AcquireOwner при включенном учете владельцев
+func (cs *CountingSemaphore) HeldBy(owner string) int {
+	o := cs.owners
+	if o == nil {
+		return 0
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.held[owner]
+}
@@ -0,0 +1,436 @@
+package semaphore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"goroutines-example/clock"
+)
+
+// ErrLeaseNotFound — ошибка обращения к неизвестной или уже завершенной аренде
+var ErrLeaseNotFound = errors.New("аренда разрешений не найдена или уже завершена")
+
+// LeaseRecord — сохраняемая запись об аренде разрешений
+type LeaseRecord struct {
+	ID      string    `json:"id"`
+	Holder  string    `json:"holder"`
+	Permits int       `json:"permits"`
+	Expires time.Time `json:"expires"`
+}
+
+// PersistedState — сохраняемое состояние именованного семафора
+type PersistedState struct {
+	Name       string        `json:"name"`
+	MaxPermits int           `json:"max_permits"`
+	Timeout    time.Duration `json:"timeout"`
+	Leases     []LeaseRecord `json:"leases,omitempty"`
+}
+
+// Store — хранилище состояния именованных семафоров
+// Load возвращает false, если состояние семафора еще не сохранялось
+type Store interface {
+	Load(name string) (PersistedState, bool, error)
+	Save(state PersistedState) error
+}
+
+// MemoryStore — хранилище состояния в памяти процесса
+// Годится для тестов и для аренд, которые не должны переживать процесс
+type MemoryStore struct {
+	mutex  sync.Mutex
+	states map[string]PersistedState
+}
+
+// NewMemoryStore — функция создания хранилища в памяти
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: make(map[string]PersistedState)}
+}
+
+// Load — метод загрузки состояния семафора
+func (s *MemoryStore) Load(name string) (PersistedState, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	st, ok := s.states[name]
+	st.Leases = append([]LeaseRecord(nil), st.Leases...)
+	return st, ok, nil
+}
+
+// Save — метод сохранения состояния семафора
+func (s *MemoryStore) Save(state PersistedState) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	state.Leases = append([]LeaseRecord(nil), state.Leases...)
+	s.states[state.Name] = state
+	return nil
+}
+
+// FileStore — хранилище состояния в JSON-файлах каталога
+// Каждый семафор хранится в своем файле; запись идет через временный
+// файл и переименование, поэтому сбой во время записи не портит состояние
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore — функция создания файлового хранилища в каталоге dir
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("создание каталога состояния: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// path — метод получения пути к файлу состояния семафора
+func (s *FileStore) path(name string) string {
+	return filepath.Join(s.dir, url.PathEscape(name)+".json")
+}
+
+// Load — метод загрузки состояния семафора
+func (s *FileStore) Load(name string) (PersistedState, bool, error) {
+	data, err := os.ReadFile(s.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return PersistedState{}, false, nil
+	}
+	if err != nil {
+		return PersistedState{}, false, err
+	}
+	var st PersistedState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return PersistedState{}, false, fmt.Errorf("состояние семафора %q: %w", name, err)
+	}
+	return st, true, nil
+}
+
+// Save — метод сохранения состояния семафора
+func (s *FileStore) Save(state PersistedState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".state-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(state.Name))
+}
+
+// persistence — сохранение конфигурации и аренд семафора
+type persistence struct {
+	store Store
+
+	// Защита leases и порядок записей в хранилище
+	mutex  sync.Mutex
+	leases map[string]*Lease
+	// Префикс идентификаторов аренд (уникален для каждого запуска)
+	// и номер последней аренды
+	prefix string
+	seq    uint64
+}
+
+// WithPersistence — опция сохранения состояния семафора в store
+// Семафор должен иметь имя (WithName): по нему состояние находится после
+// перезапуска. Сохраняются конфигурация (в том числе после Resize) и
+// аренды AcquireLease; восстанавливает состояние функция Restore
+func WithPersistence(store Store) Option {
+	return func(cs *CountingSemaphore) {
+		cs.persistence = &persistence{
+			store:  store,
+			leases: make(map[string]*Lease),
+		}
+	}
+}
+
+// saveLocked — метод сохранения текущего состояния семафора
+// Вызывается с захваченным p.mutex и без блокировки семафора
+func (p *persistence) saveLocked(cs *CountingSemaphore) error {
+	st := PersistedState{
+		Name:       cs.name,
+		MaxPermits: cs.MaxPermits(),
+		Timeout:    cs.timeout,
+	}
+	for _, l := range p.leases {
+		st.Leases = append(st.Leases, l.record())
+	}
+	sort.Slice(st.Leases, func(i, j int) bool { return st.Leases[i].ID < st.Leases[j].ID })
+	return p.store.Save(st)
+}
+
+// persist — метод сохранения состояния семафора, если сохранение включено
+func (cs *CountingSemaphore) persist() error {
+	p := cs.persistence
+	if p == nil {
+		return nil
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.saveLocked(cs)
+}
+
+// Restore — функция создания именованного семафора с восстановлением состояния
+// Если store уже хранит состояние семафора name, конфигурация берется
+// из него, а maxPermits и timeout используются только при первом запуске.
+// Аренды, срок которых истек до перезапуска, отбрасываются; действующие
+// снова занимают свои разрешения до истечения срока, и держатель может
+// вернуть их себе через RecoverLease. Так после сбоя лимиты не
+// сбрасываются мгновенно, а аренды истекают детерминированно
+func Restore(store Store, name string, maxPermits int, timeout time.Duration, opts ...Option) (*CountingSemaphore, error) {
+	if name == "" {
+		return nil, fmt.Errorf("для сохранения состояния семафору нужно имя")
+	}
+	st, found, err := store.Load(name)
+	if err != nil {
+		return nil, fmt.Errorf("загрузка состояния семафора %q: %w", name, err)
+	}
+	if found {
+		maxPermits, timeout = st.MaxPermits, st.Timeout
+	}
+	if maxPermits <= 0 {
+		return nil, fmt.Errorf("максимальное количество разрешений должно быть положительным: %d", maxPermits)
+	}
+
+	opts = append(opts, WithName(name), WithPersistence(store))
+	cs := NewCountingSemaphore(maxPermits, timeout, opts...)
+	p := cs.persistence
+	now := cs.clock.Now()
+	for _, rec := range st.Leases {
+		if !rec.Expires.After(now) || !cs.TryAcquireN(rec.Permits) {
+			// Истекшая аренда (или не помещающаяся в лимит) не восстанавливается
+			continue
+		}
+		p.leases[rec.ID] = cs.startLease(rec.ID, rec.Holder, rec.Permits, rec.Expires)
+	}
+	if err := cs.persist(); err != nil {
+		return nil, fmt.Errorf("сохранение состояния семафора %q: %w", name, err)
+	}
+	return cs, nil
+}
+
+// Lease — долгосрочная аренда разрешений с сохранением в хранилище
+// Аренда действует до Release или до истечения срока; срок продлевается
+// через Renew. Истекшая аренда возвращает разрешения автоматически
+type Lease struct {
+	cs      *CountingSemaphore
+	id      string
+	holder  string
+	permits int
+
+	// Защита expires и released
+	mutex    sync.Mutex
+	expires  time.Time
+	released bool
+	// Закрывается при завершении аренды, чтобы остановить таймер
+	stop  chan struct{}
+	timer clock.Timer
+}
+
+// AcquireLease — метод аренды n разрешений держателем holder на ttl
+// Ждет разрешения так же, как AcquireNContext. Аренда сохраняется в
+// хранилище до возврата; если сохранить не удалось, разрешения
+// возвращаются и возвращается ошибка. Требует WithPersistence
+func (cs *CountingSemaphore) AcquireLease(ctx context.Context, holder string, n int, ttl time.Duration) (*Lease, error) {
+	p := cs.persistence
+	if p == nil {
+		return nil, fmt.Errorf("сохранение состояния семафора не включено")
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("срок аренды должен быть положительным: %v", ttl)
+	}
+	if err := cs.AcquireNContext(ctx, n); err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.prefix == "" {
+		p.prefix = fmt.Sprintf("%x", cs.clock.Now().UnixNano())
+	}
+	p.seq++
+	id := fmt.Sprintf("%s-%d", p.prefix, p.seq)
+	l := cs.startLease(id, holder, n, cs.clock.Now().Add(ttl))
+	p.leases[id] = l
+	if err := p.saveLocked(cs); err != nil {
+		delete(p.leases, id)
+		l.finish()
+		return nil, fmt.Errorf("сохранение аренды: %w", err)
+	}
+	return l, nil
+}
+
+// RecoverLease — метод получения аренды, восстановленной Restore, по идентификатору
+func (cs *CountingSemaphore) RecoverLease(id string) (*Lease, error) {
+	p := cs.persistence
+	if p == nil {
+		return nil, ErrLeaseNotFound
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	l, ok := p.leases[id]
+	if !ok {
+		return nil, ErrLeaseNotFound
+	}
+	return l, nil
+}
+
+// Leases — метод получения записей действующих аренд
+func (cs *CountingSemaphore) Leases() []LeaseRecord {
+	p := cs.persistence
+	if p == nil {
+		return nil
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	records := make([]LeaseRecord, 0, len(p.leases))
+	for _, l := range p.leases {
+		records = append(records, l.record())
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return records
+}
+
+// startLease — метод запуска таймера аренды с уже захваченными разрешениями
+func (cs *CountingSemaphore) startLease(id, holder string, permits int, expires time.Time) *Lease {
+	l := &Lease{
+		cs:      cs,
+		id:      id,
+		holder:  holder,
+		permits: permits,
+		expires: expires,
+		stop:    make(chan struct{}),
+		timer:   cs.clock.NewTimer(expires.Sub(cs.clock.Now())),
+	}
+	go func() {
+		for {
+			select {
+			case <-l.timer.C():
+				if l.expireIfDue() {
+					return
+				}
+			case <-l.stop:
+				l.timer.Stop()
+				return
+			}
+		}
+	}()
+	return l
+}
+
+// expireIfDue — метод завершения аренды, если ее срок истек
+// Возвращает false, если аренду успели продлить (таймер перезапущен)
+func (l *Lease) expireIfDue() bool {
+	l.mutex.Lock()
+	if remaining := l.expires.Sub(l.cs.clock.Now()); remaining > 0 && !l.released {
+		l.timer.Reset(remaining)
+		l.mutex.Unlock()
+		return false
+	}
+	l.mutex.Unlock()
+
+	// Ошибку сохранения некому вернуть: аренда все равно истекла,
+	// а при восстановлении истекшая запись будет отброшена
+	l.end()
+	return true
+}
+
+// ID — метод получения идентификатора аренды (для RecoverLease после перезапуска)
+func (l *Lease) ID() string {
+	return l.id
+}
+
+// Holder — метод получения держателя аренды
+func (l *Lease) Holder() string {
+	return l.holder
+}
+
+// Permits — метод получения количества арендованных разрешений
+func (l *Lease) Permits() int {
+	return l.permits
+}
+
+// Expires — метод получения момента истечения аренды
+func (l *Lease) Expires() time.Time {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.expires
+}
+
+// Renew — метод продления аренды на ttl от текущего момента
+// Возвращает ErrLeaseNotFound, если аренда уже завершена или истекла
+func (l *Lease) Renew(ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("срок аренды должен быть положительным: %v", ttl)
+	}
+	p := l.cs.persistence
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	l.mutex.Lock()
+	if l.released {
+		l.mutex.Unlock()
+		return ErrLeaseNotFound
+	}
+	l.expires = l.cs.clock.Now().Add(ttl)
+	l.mutex.Unlock()
+	// Таймер перезапустится сам: сработав, он увидит новый срок
+	return p.saveLocked(l.cs)
+}
+
+// Release — метод досрочного завершения аренды с возвратом разрешений
+// Разрешения возвращаются, даже если удалить аренду из хранилища не
+// удалось (тогда возвращается ошибка сохранения)
+func (l *Lease) Release() error {
+	return l.end()
+}
+
+// end — метод завершения аренды: удаление из хранилища и возврат разрешений
+// Возвращает ErrLeaseNotFound, если аренда уже была завершена
+func (l *Lease) end() error {
+	p := l.cs.persistence
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if !l.finish() {
+		return ErrLeaseNotFound
+	}
+	delete(p.leases, l.id)
+	return p.saveLocked(l.cs)
+}
+
+// finish — метод остановки таймера и возврата разрешений
+// Возвращает false, если аренда уже была завершена
+func (l *Lease) finish() bool {
+	l.mutex.Lock()
+	if l.released {
+		l.mutex.Unlock()
+		return false
+	}
+	l.released = true
+	l.mutex.Unlock()
+
+	close(l.stop)
+	l.cs.ReleaseUpTo(l.permits)
+	return true
+}
+
+// record — метод получения сохраняемой записи аренды
+func (l *Lease) record() LeaseRecord {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return LeaseRecord{ID: l.id, Holder: l.holder, Permits: l.permits, Expires: l.expires}
+}
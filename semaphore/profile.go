@@ -0,0 +1,69 @@
+package semaphore
+
+import (
+	"fmt"
+	"io"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+)
+
+// profilesMutex — защита создания профилей: pprof.NewProfile паникует
+// при повторной регистрации имени, а у разных семафоров имена могут совпадать
+var profilesMutex sync.Mutex
+
+// contention — профилировщик ожидающих вызовов захвата
+type contention struct {
+	profile *pprof.Profile
+	// Записывается каждый rate-й захват
+	rate    uint64
+	counter atomic.Uint64
+}
+
+// WithContentionProfile — опция профилирования ожидания разрешений
+// Каждый rate-й вызов Acquire/AcquireAtLeast на время ожидания попадает
+// в пользовательский профиль pprof с именем "semaphore.waiters.<имя>" вместе
+// со стеком вызова. Снимок профиля показывает, из каких мест кода сейчас
+// ждут разрешения этого семафора. Профиль доступен через WriteContentionProfile,
+// pprof.Lookup и net/http/pprof (/debug/pprof/semaphore.waiters.<имя>)
+// и читается go tool pprof. Опцию следует передавать после WithName
+func WithContentionProfile(rate int) Option {
+	if rate <= 0 {
+		rate = 1
+	}
+	return func(cs *CountingSemaphore) {
+		name := "semaphore.waiters." + cs.name
+		if cs.name == "" {
+			name = "semaphore.waiters"
+		}
+
+		profilesMutex.Lock()
+		defer profilesMutex.Unlock()
+		p := pprof.Lookup(name)
+		if p == nil {
+			p = pprof.NewProfile(name)
+		}
+		cs.contention = &contention{profile: p, rate: uint64(rate)}
+	}
+}
+
+// track — метод добавления текущего стека в профиль, если вызов попал в выборку
+// skip — количество пропускаемых кадров над вызывающей функцией (0 — стек
+// начинается с нее). Возвращает функцию удаления записи по окончании ожидания
+func (c *contention) track(skip int) func() {
+	if c.counter.Add(1)%c.rate != 0 {
+		return func() {}
+	}
+	key := new(byte)
+	c.profile.Add(key, skip+2)
+	return func() { c.profile.Remove(key) }
+}
+
+// WriteContentionProfile — метод записи профиля ожидания в формате pprof
+// debug=0 — сжатый protobuf для go tool pprof, debug=1 — текстовый вид
+func (cs *CountingSemaphore) WriteContentionProfile(w io.Writer, debug int) error {
+	if cs.contention == nil {
+		return fmt.Errorf("профилирование ожидания не включено")
+	}
+	return cs.contention.profile.WriteTo(w, debug)
+}
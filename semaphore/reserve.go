@@ -0,0 +1,107 @@
+package semaphore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrReservationExpired — ошибка подтверждения просроченной или отмененной брони
+var ErrReservationExpired = errors.New("бронь разрешения истекла или отменена")
+
+// Состояния брони
+const (
+	reservationPending int32 = iota
+	reservationCommitted
+	reservationReturned
+	reservationReleased
+)
+
+// Reservation — предварительно захваченное разрешение
+// Разрешение удерживается до Commit, но если бронь не подтверждена
+// за ttl, оно автоматически возвращается семафору. Так решение о допуске
+// принимается до начала работы, а брошенная бронь не держит разрешение
+type Reservation struct {
+	cs       *CountingSemaphore
+	deadline time.Time
+	state    atomic.Int32
+	// Закрывается при Commit или Cancel, чтобы остановить таймер брони
+	stop chan struct{}
+}
+
+// Reserve — метод бронирования разрешения на ttl
+// Ждет разрешение так же, как AcquireContext. Бронь нужно подтвердить
+// через Commit или отменить через Cancel; после подтверждения разрешение
+// освобождается через Reservation.Release
+func (cs *CountingSemaphore) Reserve(ctx context.Context, ttl time.Duration) (*Reservation, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("время брони должно быть положительным: %v", ttl)
+	}
+	if err := cs.AcquireContext(ctx); err != nil {
+		return nil, err
+	}
+
+	r := &Reservation{
+		cs:       cs,
+		deadline: cs.clock.Now().Add(ttl),
+		stop:     make(chan struct{}),
+	}
+	timer := cs.clock.NewTimer(ttl)
+	go func() {
+		select {
+		case <-timer.C():
+			r.giveBack()
+		case <-r.stop:
+			timer.Stop()
+		}
+	}()
+	return r, nil
+}
+
+// Deadline — метод получения момента, до которого бронь нужно подтвердить
+func (r *Reservation) Deadline() time.Time {
+	return r.deadline
+}
+
+// Commit — метод подтверждения брони
+// Возвращает ErrReservationExpired, если бронь уже истекла или отменена —
+// разрешение тогда не удерживается, и работу начинать нельзя
+func (r *Reservation) Commit() error {
+	if !r.state.CompareAndSwap(reservationPending, reservationCommitted) {
+		if r.state.Load() == reservationCommitted {
+			return errors.New("бронь уже подтверждена")
+		}
+		return ErrReservationExpired
+	}
+	close(r.stop)
+	return nil
+}
+
+// Cancel — метод отмены неподтвержденной брони
+// Возвращает разрешение семафору. Отмена истекшей, подтвержденной
+// или уже отмененной брони ничего не делает
+func (r *Reservation) Cancel() {
+	if r.giveBack() {
+		close(r.stop)
+	}
+}
+
+// Release — метод освобождения разрешения подтвержденной брони
+func (r *Reservation) Release() error {
+	if !r.state.CompareAndSwap(reservationCommitted, reservationReleased) {
+		return errors.New("бронь не подтверждена или разрешение уже освобождено")
+	}
+	return r.cs.Release()
+}
+
+// giveBack — метод возврата разрешения неподтвержденной брони
+// Возвращает true, если разрешение возвращено этим вызовом
+func (r *Reservation) giveBack() bool {
+	if !r.state.CompareAndSwap(reservationPending, reservationReturned) {
+		return false
+	}
+	r.cs.Release()
+	return true
+}
@@ -0,0 +1,106 @@
+package semaphore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"goroutines-example/idalloc"
+)
+
+// ResourcePool — семафор, привязывающий каждое разрешение к конкретному ресурсу
+// Для «N одинаковых соединений или устройств» важно не только количество
+// свободных мест, но и какой именно ресурс достался: Acquire возвращает
+// указатель на один из N ресурсов, а Release возвращает его в пул.
+// Один ресурс никогда не выдается двум владельцам одновременно
+type ResourcePool[T any] struct {
+	sem *CountingSemaphore
+	// Собственная копия ресурсов; выдаются указатели на ее элементы
+	resources []T
+	// Номера свободных ресурсов
+	ids *idalloc.IDAllocator
+	// Обратное отображение указателя на номер ресурса
+	index map[*T]int
+}
+
+// NewResourcePool — функция создания пула из переданных ресурсов
+// Количество разрешений равно количеству ресурсов; timeout и opts
+// передаются внутреннему семафору
+func NewResourcePool[T any](resources []T, timeout time.Duration, opts ...Option) (*ResourcePool[T], error) {
+	if len(resources) == 0 {
+		return nil, errors.New("пул ресурсов не может быть пустым")
+	}
+	ids, err := idalloc.NewIDAllocator(len(resources))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &ResourcePool[T]{
+		sem:       NewCountingSemaphore(len(resources), timeout, opts...),
+		resources: append([]T(nil), resources...),
+		ids:       ids,
+		index:     make(map[*T]int, len(resources)),
+	}
+	for i := range p.resources {
+		p.index[&p.resources[i]] = i
+	}
+	return p, nil
+}
+
+// Acquire — метод захвата ресурса с таймаутом семафора и отменой через ctx
+func (p *ResourcePool[T]) Acquire(ctx context.Context) (*T, error) {
+	if err := p.sem.AcquireContext(ctx); err != nil {
+		return nil, err
+	}
+	return p.take(), nil
+}
+
+// TryAcquire — метод захвата ресурса без блокировки
+// Возвращает nil, если свободных ресурсов нет
+func (p *ResourcePool[T]) TryAcquire() *T {
+	if !p.sem.TryAcquire() {
+		return nil
+	}
+	return p.take()
+}
+
+// take — метод выбора свободного ресурса после захвата разрешения
+// Разрешений столько же, сколько ресурсов, поэтому после захвата
+// разрешения свободный номер обязательно есть
+func (p *ResourcePool[T]) take() *T {
+	id, ok := p.ids.Alloc()
+	if !ok {
+		panic("пул ресурсов: разрешение получено, но свободного ресурса нет")
+	}
+	return &p.resources[id]
+}
+
+// Release — метод возврата ресурса в пул
+// Возвращает ошибку, если r не принадлежит пулу или уже возвращен
+func (p *ResourcePool[T]) Release(r *T) error {
+	id, ok := p.index[r]
+	if !ok {
+		return errors.New("ресурс не принадлежит пулу")
+	}
+	// Номер освобождается до разрешения, чтобы следующий владелец
+	// разрешения гарантированно нашел свободный ресурс
+	if err := p.ids.Free(id); err != nil {
+		return fmt.Errorf("ресурс %d уже возвращен в пул", id)
+	}
+	return p.sem.Release()
+}
+
+// Index — метод получения номера ресурса в исходном срезе
+// Возвращает -1, если r не принадлежит пулу
+func (p *ResourcePool[T]) Index(r *T) int {
+	if id, ok := p.index[r]; ok {
+		return id
+	}
+	return -1
+}
+
+// AvailablePermits — метод получения количества свободных ресурсов
+func (p *ResourcePool[T]) AvailablePermits() int {
+	return p.sem.AvailablePermits()
+}
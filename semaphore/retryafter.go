@@ -0,0 +1,159 @@
+package semaphore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"goroutines-example/clock"
+)
+
+// RetryAfterError — ошибка захвата из-за перегрузки с подсказкой, когда повторить
+// Оборачивает ErrQueueFull или ErrBreakerOpen, поэтому errors.Is с ними
+// по-прежнему работает
+type RetryAfterError struct {
+	Err error
+	// Через сколько повтор имеет шанс на успех
+	RetryAfter time.Duration
+}
+
+// Error — метод формирования текста ошибки
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%v (повторить через %v)", e.Err, e.RetryAfter)
+}
+
+// Unwrap — метод получения исходной ошибки
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfter — функция извлечения подсказки о повторе из ошибки захвата
+// Понимает *RetryAfterError и *ErrTimeoutInfo; возвращает false, если
+// подсказки нет (например, скорость освобождений не учитывается)
+func RetryAfter(err error) (time.Duration, bool) {
+	var saturated *RetryAfterError
+	if errors.As(err, &saturated) {
+		return saturated.RetryAfter, true
+	}
+	var timeout *ErrTimeoutInfo
+	if errors.As(err, &timeout) && timeout.RetryAfter > 0 {
+		return timeout.RetryAfter, true
+	}
+	return 0, false
+}
+
+// SleepRetryAfter — метод паузы перед повтором захвата после ошибки err
+// Ждет подсказку из err, а без нее — fallback, чтобы вызывающие отступали
+// соразмерно перегрузке, а не повторяли захват в цикле. Возвращает ошибку
+// ctx, если он отменен раньше
+func (cs *CountingSemaphore) SleepRetryAfter(ctx context.Context, err error, fallback time.Duration) error {
+	d, ok := RetryAfter(err)
+	if !ok {
+		d = fallback
+	}
+	return clock.Sleep(ctx, cs.clock, d)
+}
+
+// releaseRate — экспоненциально затухающий счетчик освобождений
+// При постоянной скорости r счетчик стремится к r*window, поэтому
+// count/window — сглаженная скорость освобождений в секунду
+type releaseRate struct {
+	// Защита всех полей
+	mutex   sync.Mutex
+	window  time.Duration
+	count   float64
+	updated time.Time
+}
+
+// WithRetryAfter — опция учета скорости освобождений для подсказок о повторе
+// Ошибки таймаута (ErrTimeoutInfo.RetryAfter) и переполнения очереди
+// (RetryAfterError) получают оценку времени, за которое при текущей
+// скорости освободится нужное количество разрешений. window — окно
+// сглаживания скорости и верхняя граница подсказки (по умолчанию 10 секунд)
+func WithRetryAfter(window time.Duration) Option {
+	if window <= 0 {
+		window = 10 * time.Second
+	}
+	return func(cs *CountingSemaphore) {
+		cs.releases = &releaseRate{window: window}
+	}
+}
+
+// decayLocked — метод затухания счетчика к моменту now
+func (r *releaseRate) decayLocked(now time.Time) {
+	if dt := now.Sub(r.updated); dt > 0 {
+		r.count *= math.Exp(-float64(dt) / float64(r.window))
+	}
+	r.updated = now
+}
+
+// event — метод учета одного освобождения
+func (r *releaseRate) event(now time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.decayLocked(now)
+	r.count++
+}
+
+// perSecond — метод получения сглаженной скорости освобождений в секунду
+func (r *releaseRate) perSecond(now time.Time) float64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.decayLocked(now)
+	return r.count / r.window.Seconds()
+}
+
+// hint — метод оценки времени до need освобождений
+// Без освобождений за последнее время (и для слишком долгих оценок)
+// возвращает window
+func (r *releaseRate) hint(now time.Time, need int) time.Duration {
+	if need < 1 {
+		need = 1
+	}
+	rate := r.perSecond(now)
+	if rate <= 0 {
+		return r.window
+	}
+	d := time.Duration(float64(need) / rate * float64(time.Second))
+	if d > r.window {
+		d = r.window
+	}
+	return d
+}
+
+// ReleaseRate — метод получения сглаженной скорости освобождений в секунду
+// Возвращает 0, если учет не включен опцией WithRetryAfter
+func (cs *CountingSemaphore) ReleaseRate() float64 {
+	if cs.releases == nil {
+		return 0
+	}
+	return cs.releases.perSecond(cs.clock.Now())
+}
+
+// retryHint — метод оценки времени до need освобождений (0 — учет выключен)
+func (cs *CountingSemaphore) retryHint(need int) time.Duration {
+	if cs.releases == nil {
+		return 0
+	}
+	return cs.releases.hint(cs.clock.Now(), need)
+}
+
+// saturated — метод добавления подсказки о повторе к ошибке перегрузки
+// Для ErrBreakerOpen подсказка — оставшееся время остывания, для
+// ErrQueueFull — время, за которое пройдет очередь ожидающих
+func (cs *CountingSemaphore) saturated(err error) error {
+	var hint time.Duration
+	switch err {
+	case ErrBreakerOpen:
+		hint = cs.escalation.remaining(cs.clock.Now())
+	case ErrQueueFull:
+		hint = cs.retryHint(cs.Waiters() + 1)
+	}
+	if hint <= 0 {
+		return err
+	}
+	return &RetryAfterError{Err: err, RetryAfter: hint}
+}
@@ -1,9 +1,16 @@
 package semaphore
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"goroutines-example/clock"
+	"goroutines-example/flightrec"
+	"goroutines-example/inflight"
+	"goroutines-example/latency"
 )
 
 // CountingSemaphore — структура счетного семафора
@@ -11,40 +18,154 @@ import (
 // что позволяет контролировать доступ к нескольким одинаковым ресурсам
 type CountingSemaphore struct {
 	// Канал для хранения состояния семафора
+	// Количество элементов в канале — единственный источник истины
+	// о количестве доступных разрешений: отдельный счетчик мог бы
+	// расходиться с каналом между приемом из канала и своим изменением
 	sem chan struct{}
 	// Максимальное количество разрешений
 	maxPermits int
-	// Текущее количество доступных разрешений
-	currentPermits int
-	// Защита переменной currentPermits при многопоточном доступе
+	// Количество разрешений, которые нужно «погасить» при освобождении,
+	// а не возвращать в канал. Появляется, когда Resize уменьшает семафор
+	// ниже количества уже захваченных разрешений
+	debt atomic.Int64
+	// Закрывается и заменяется новым при каждом Resize, чтобы горутины,
+	// ожидающие на старом канале, переключились на новый
+	resized chan struct{}
+	// Защита полей sem, maxPermits и resized, которые меняет Resize.
+	// Операции с разрешениями берут блокировку на чтение и работают параллельно
 	mutex sync.RWMutex
 	// Время ожидания основных операций с семафором, чтобы не 
 	// блокировать операции с ним навечно
 	timeout time.Duration
+	// Распределение времени ожидания разрешений в Acquire
+	waits *latency.Recorder
+	// Имя семафора для диагностики
+	name string
+	// Бортовой самописец событий (может отсутствовать)
+	recorder *flightrec.Recorder
+	// Источник времени для таймаутов и измерений
+	clock clock.Clock
+	// Режим хаоса для тестов (nil — выключен)
+	chaos *chaos
+	// Журнал аудита захватов (nil — выключен)
+	audit *audit
+	// Сглаженная загрузка (nil — выключена)
+	util *utilization
+	// Количество захваченных разрешений и его пик
+	inflight inflight.Gauge
+	// Профилировщик ожидания разрешений (nil — выключен)
+	contention *contention
+	// Глобальный порядковый номер для захвата нескольких семафоров без взаимоблокировок
+	id uint64
+	// Политика эскалации таймаутов (nil — всегда timeout)
+	escalation *escalation
+	// Предельное количество ожидающих захватов (0 — без ограничения)
+	// и текущее количество ожидающих
+	maxWaiters int64
+	waiters    atomic.Int64
+	// Учет разрешений по владельцам (nil — выключен)
+	owners *owners
+	// Проверки AssertHeld/AssertNotHeld включены
+	assertions bool
+	// Сохранение конфигурации и аренд (nil — выключено)
+	persistence *persistence
+	// Скорость освобождений для подсказок о повторе (nil — выключена)
+	releases *releaseRate
+	// Право накапливать разрешения в AcquireAtLeast (буфер на одно значение)
+	gather chan struct{}
+	// Функция, вызываемая в точках переключения (nil — не вызывается)
+	yield func()
 }
 
 // Acquire — метод захвата одного разрешения у семафора
 // Уменьшает счетчик доступных разрешений на 1
 func (cs *CountingSemaphore) Acquire() error {
-	select {
-	case _ = <-cs.sem:
-		cs.mutex.Lock()
-		defer cs.mutex.Unlock()
-		cs.currentPermits--
-		return nil
-	case <-time.After(cs.timeout):
-		return fmt.Errorf("Не удалось захватить разрешение у семафора")
+	cs.yieldPoint()
+	if cs.chaos != nil {
+		cs.chaos.delay(cs.clock)
+		if cs.chaos.spuriousTimeout() {
+			cs.record(flightrec.Timeout, cs.AvailablePermits())
+			return cs.timeoutError(1, 0, 0)
+		}
+	}
+
+	if cs.contention != nil {
+		defer cs.contention.track(0)()
+	}
+
+	wait, err := cs.acquireTimeout(cs.AvailablePermits() == 0)
+	if err != nil {
+		return err
 	}
+
+	if cs.maxWaiters > 0 {
+		// Свободное разрешение забираем сразу, не вставая в очередь
+		if cs.takeAvailable(1) == 1 {
+			cs.waits.Record(0)
+			cs.acquireDone(false)
+			return nil
+		}
+		leave, err := cs.enqueue()
+		if err != nil {
+			return err
+		}
+		defer leave()
+	}
+
+	start := cs.clock.Now()
+	// Таймер заводится, только если свободного разрешения нет, и
+	// останавливается при выходе, чтобы быстрые захваты не оставляли
+	// живых таймеров (с clock.Fake они считались бы ожидающими)
+	var timeout <-chan time.Time
+	for {
+		sem, resized := cs.state()
+		if timeout == nil {
+			select {
+			case _ = <-sem:
+				cs.acquiredOne(start, len(sem))
+				return nil
+			default:
+			}
+			timer := cs.clock.NewTimer(wait)
+			defer timer.Stop()
+			timeout = timer.C()
+		}
+		select {
+		case _ = <-sem:
+			cs.acquiredOne(start, len(sem))
+			return nil
+		case <-resized:
+			// Семафор изменил размер — ждем уже на новом канале
+		case <-timeout:
+			cs.record(flightrec.Timeout, len(sem))
+			cs.acquireDone(true)
+			return cs.timeoutError(1, 0, cs.clock.Since(start))
+		}
+	}
+}
+
+// acquiredOne — метод учета разрешения, захваченного в Acquire
+// start — начало ожидания, available — количество свободных разрешений после захвата
+func (cs *CountingSemaphore) acquiredOne(start time.Time, available int) {
+	cs.waits.Record(cs.clock.Since(start))
+	cs.record(flightrec.Acquire, available)
+	cs.acquireDone(false)
 }
 
 // TryAcquire — метод попытки захвата разрешения без блокировки
 // Возвращает true, если удалось захватить разрешение, иначе false
 func (cs *CountingSemaphore) TryAcquire() bool {
+	cs.yieldPoint()
+	if cs.chaos != nil && cs.chaos.spuriousTimeout() {
+		return false
+	}
+
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+
 	select {
 	case _ = <-cs.sem:
-		cs.mutex.Lock()
-		defer cs.mutex.Unlock()
-		cs.currentPermits--
+		cs.record(flightrec.Acquire, len(cs.sem))
 		return true
 	default:
 		return false
@@ -53,36 +174,131 @@ func (cs *CountingSemaphore) TryAcquire() bool {
 
 // Release — метод освобождения одного разрешения у семафора
 // Увеличивает счетчик доступных разрешений на 1
+// Возвращает ошибку, если все разрешения уже свободны
 func (cs *CountingSemaphore) Release() error {
+	cs.yieldPoint()
+	if cs.chaos != nil {
+		cs.chaos.delay(cs.clock)
+	}
+
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+
+	if cs.payDebt() {
+		cs.record(flightrec.Release, len(cs.sem))
+		return nil
+	}
 	select {
 	case cs.sem <- struct{}{}:
-		cs.mutex.Lock()
-		defer cs.mutex.Unlock()
-		cs.currentPermits++
+		cs.record(flightrec.Release, len(cs.sem))
 		return nil
-	case <-time.After(cs.timeout):
+	default:
 		return fmt.Errorf("Не удалось освободить разрешение у семафора")
 	}
 }
 
+// payDebt — метод погашения одного разрешения из долга, оставшегося после Resize
+// Возвращает true, если освобождаемое разрешение ушло в счет долга
+func (cs *CountingSemaphore) payDebt() bool {
+	for {
+		d := cs.debt.Load()
+		if d <= 0 {
+			return false
+		}
+		if cs.debt.CompareAndSwap(d, d-1) {
+			return true
+		}
+	}
+}
+
+// yieldPoint — метод вызова функции переключения, если она задана
+func (cs *CountingSemaphore) yieldPoint() {
+	if cs.yield != nil {
+		cs.yield()
+	}
+}
+
+// state — метод получения текущего канала разрешений и сигнала изменения размера
+func (cs *CountingSemaphore) state() (sem, resized chan struct{}) {
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+	return cs.sem, cs.resized
+}
+
+// record — метод учета события в счетчике захваченных разрешений,
+// сглаженной загрузке и бортовом самописце, если они подключены
+// available — количество доступных разрешений после события
+func (cs *CountingSemaphore) record(kind flightrec.Kind, available int) {
+	switch kind {
+	case flightrec.Acquire:
+		cs.inflight.Add(1)
+	case flightrec.Release:
+		cs.inflight.Done()
+		if cs.releases != nil {
+			cs.releases.event(cs.clock.Now())
+		}
+	}
+	if cs.util != nil {
+		cs.util.event(kind)
+	}
+	if cs.recorder == nil {
+		return
+	}
+	cs.recorder.Record(flightrec.Event{
+		Kind:      kind,
+		Source:    cs.name,
+		Count:     1,
+		Available: available,
+	})
+}
+
 // AvailablePermits — метод получения количества доступных разрешений
+// Значение может устареть сразу после возврата, если с семафором
+// одновременно работают другие горутины
 func (cs *CountingSemaphore) AvailablePermits() int {
 	cs.mutex.RLock()
 	defer cs.mutex.RUnlock()
-	return cs.currentPermits
+	return len(cs.sem)
+}
+
+// MaxPermits — метод получения максимального количества разрешений
+func (cs *CountingSemaphore) MaxPermits() int {
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+	return cs.maxPermits
+}
+
+// heldPermits — метод получения количества захваченных разрешений
+// Вызывается при захваченной блокировке
+func (cs *CountingSemaphore) heldPermits() int {
+	return cs.maxPermits + int(cs.debt.Load()) - len(cs.sem)
+}
+
+// InFlight — метод получения счетчика захваченных разрешений с отметкой пика
+// HighWaterMark показывает наибольшее количество одновременно захваченных
+// разрешений с последнего Reset. Разрешения, погашенные в счет долга после
+// Resize, учитываются как освобожденные
+func (cs *CountingSemaphore) InFlight() *inflight.Gauge {
+	return &cs.inflight
+}
+
+// WaitStats — метод получения статистики времени ожидания разрешений
+// Учитываются только успешные вызовы Acquire (в том числе внутри AcquireN)
+func (cs *CountingSemaphore) WaitStats() *latency.Recorder {
+	return cs.waits
 }
 
 // AcquireN — метод захвата N разрешений у семафора
 // Важно: для корректной работы с несколькими разрешениями используйте
 // эту функцию вместо вызова Acquire несколько раз
 func (cs *CountingSemaphore) AcquireN(n int) error {
-	if n > cs.maxPermits {
-		return fmt.Errorf("запрошено больше разрешений (%d), чем максимально доступно (%d)", n, cs.maxPermits)
+	if maxPermits := cs.MaxPermits(); n > maxPermits {
+		return fmt.Errorf("запрошено больше разрешений (%d), чем максимально доступно (%d)", n, maxPermits)
 	}
 
 	// Проверяем, достаточно ли доступных разрешений
-	if cs.AvailablePermits() < n {
-		return fmt.Errorf("недостаточно разрешений: доступно %d, требуется %d", cs.AvailablePermits(), n)
+	if available := cs.AvailablePermits(); available < n {
+		return fmt.Errorf("недостаточно разрешений: доступно %d, требуется %d", available, n)
 	}
 
 	for i := 0; i < n; i++ {
@@ -99,7 +315,7 @@ func (cs *CountingSemaphore) AcquireN(n int) error {
 // ReleaseN — метод освобождения N разрешений у семафора
 func (cs *CountingSemaphore) ReleaseN(n int) error {
 	cs.mutex.RLock()
-	availableToRelease := cs.maxPermits - cs.currentPermits
+	availableToRelease := cs.heldPermits()
 	cs.mutex.RUnlock()
 
 	if n > availableToRelease {
@@ -115,9 +331,245 @@ func (cs *CountingSemaphore) ReleaseN(n int) error {
 	return nil
 }
 
+// TryAcquireN — метод попытки захвата N разрешений без блокировки
+// Захватывает либо все N разрешений, либо ни одного
+// Возвращает true, если удалось захватить все разрешения
+func (cs *CountingSemaphore) TryAcquireN(n int) bool {
+	if n <= 0 {
+		return n == 0
+	}
+	cs.yieldPoint()
+	if cs.chaos != nil && cs.chaos.spuriousTimeout() {
+		return false
+	}
+
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+
+	if n > cs.maxPermits {
+		return false
+	}
+	for i := 0; i < n; i++ {
+		select {
+		case _ = <-cs.sem:
+		default:
+			// Разрешений не хватило — возвращаем уже захваченные.
+			// Место в канале для них гарантированно есть, так как мы их только что забрали
+			for j := 0; j < i; j++ {
+				cs.sem <- struct{}{}
+			}
+			return false
+		}
+	}
+	for i := 0; i < n; i++ {
+		cs.record(flightrec.Acquire, len(cs.sem))
+	}
+	return true
+}
+
+// ReleaseUpTo — метод освобождения не более N разрешений без блокировки
+// Возвращает количество фактически освобожденных разрешений: оно меньше N,
+// если семафор заполнился (то есть захваченных разрешений было меньше N)
+func (cs *CountingSemaphore) ReleaseUpTo(n int) int {
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+
+	released := 0
+	for released < n {
+		if cs.payDebt() {
+			released++
+			cs.record(flightrec.Release, len(cs.sem))
+			continue
+		}
+		select {
+		case cs.sem <- struct{}{}:
+			released++
+			cs.record(flightrec.Release, len(cs.sem))
+		default:
+			return released
+		}
+	}
+	return released
+}
+
+// AcquireAtLeast — метод захвата от min до max разрешений за один вызов
+// Захват по принципу «все или ничего»: если min разрешений свободно, они
+// забираются сразу вместе со свободными сверх них (но не больше max).
+// Иначе вызов встает в очередь и ждет, пока не истечет таймаут семафора
+// или не будет отменен ctx. Недостающие разрешения накапливает только
+// один ожидающий за раз, поэтому вызовы, которым нужно несколько
+// разрешений, не держат части друг друга и не блокируют друг друга
+// навечно. Возвращает количество захваченных разрешений; при ошибке все
+// захваченные разрешения возвращаются семафору
+func (cs *CountingSemaphore) AcquireAtLeast(ctx context.Context, min, max int) (int, error) {
+	return cs.acquireAtLeast(ctx, min, max, true)
+}
+
+// AcquireNUntil — метод захвата n разрешений, ожидающий только отмены ctx
+// В отличие от AcquireNContext, таймаут семафора не прерывает ожидание:
+// вызов стоит в очереди один раз, пока разрешения не освободятся или ctx
+// не будет отменен (как Acquire у golang.org/x/sync/semaphore.Weighted)
+func (cs *CountingSemaphore) AcquireNUntil(ctx context.Context, n int) error {
+	_, err := cs.acquireAtLeast(ctx, n, n, false)
+	return err
+}
+
+// acquireAtLeast — метод захвата от min до max разрешений
+// bounded — ожидание ограничено таймаутом семафора (иначе только ctx)
+func (cs *CountingSemaphore) acquireAtLeast(ctx context.Context, min, max int, bounded bool) (int, error) {
+	if min < 0 || min > max {
+		return 0, fmt.Errorf("некорректный диапазон разрешений: от %d до %d", min, max)
+	}
+	if maxPermits := cs.MaxPermits(); min > maxPermits {
+		return 0, fmt.Errorf("запрошено больше разрешений (%d), чем максимально доступно (%d)", min, maxPermits)
+	}
+
+	if cs.escalation != nil && cs.escalation.open(cs.clock.Now()) {
+		return 0, cs.saturated(ErrBreakerOpen)
+	}
+	if cs.TryAcquireN(min) {
+		if cs.escalation != nil {
+			cs.escalation.calm()
+		}
+		return min + cs.takeAvailable(max-min), nil
+	}
+
+	wait, err := cs.acquireTimeout(true)
+	if err != nil {
+		return 0, err
+	}
+	leave, err := cs.enqueue()
+	if err != nil {
+		return 0, err
+	}
+	defer leave()
+
+	if cs.contention != nil {
+		defer cs.contention.track(0)()
+	}
+
+	start := cs.clock.Now()
+	// Без ограничения канал таймаута nil и никогда не срабатывает
+	var timeout <-chan time.Time
+	if bounded {
+		timer := cs.clock.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C()
+	}
+	got := 0
+	if min > 1 {
+		// Право накапливать разрешения: без него два вызова, каждому из
+		// которых не хватает, держали бы по части разрешений вечно
+		select {
+		case cs.gather <- struct{}{}:
+			defer func() { <-cs.gather }()
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-timeout:
+			cs.record(flightrec.Timeout, cs.AvailablePermits())
+			cs.acquireDone(true)
+			return 0, cs.timeoutError(min, 0, cs.clock.Since(start))
+		}
+		got = cs.takeAvailable(min)
+	}
+	for got < min {
+		sem, resized := cs.state()
+		select {
+		case _ = <-sem:
+			got++
+			cs.record(flightrec.Acquire, len(sem))
+		case <-resized:
+		case <-ctx.Done():
+			cs.ReleaseUpTo(got)
+			return 0, ctx.Err()
+		case <-timeout:
+			cs.ReleaseUpTo(got)
+			cs.record(flightrec.Timeout, len(sem))
+			cs.acquireDone(true)
+			return 0, cs.timeoutError(min, got, cs.clock.Since(start))
+		}
+	}
+	cs.waits.Record(cs.clock.Since(start))
+	cs.acquireDone(false)
+
+	// Пока ждали, могли освободиться еще разрешения
+	got += cs.takeAvailable(max - got)
+	return got, nil
+}
+
+// takeAvailable — метод захвата без ожидания не более n свободных разрешений
+// Возвращает количество захваченных разрешений
+func (cs *CountingSemaphore) takeAvailable(n int) int {
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+
+	got := 0
+	for got < n {
+		select {
+		case _ = <-cs.sem:
+			got++
+			cs.record(flightrec.Acquire, len(cs.sem))
+		default:
+			return got
+		}
+	}
+	return got
+}
+
+// Resize — метод изменения максимального количества разрешений
+// Уже захваченные разрешения остаются у владельцев. Если новый максимум
+// меньше количества захваченных разрешений, лишние разрешения «гасятся»
+// по мере их освобождения, и новые захваты возможны только после этого
+func (cs *CountingSemaphore) Resize(maxPermits int) error {
+	if maxPermits <= 0 {
+		return fmt.Errorf("максимальное количество разрешений должно быть положительным: %d", maxPermits)
+	}
+	if cs.persistence != nil {
+		// Выполнится после освобождения блокировки. Ошибка сохранения не
+		// отменяет изменение размера: новая конфигурация уже действует
+		defer cs.persist()
+	}
+
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	// Забираем все свободные разрешения из старого канала. Горутины, успевшие
+	// получить разрешение во время этого цикла, честно учитываются как владельцы
+	available := 0
+	for drained := false; !drained; {
+		select {
+		case _ = <-cs.sem:
+			available++
+		default:
+			drained = true
+		}
+	}
+	held := cs.maxPermits + int(cs.debt.Load()) - available
+
+	sem := make(chan struct{}, maxPermits)
+	if held <= maxPermits {
+		for i := 0; i < maxPermits-held; i++ {
+			sem <- struct{}{}
+		}
+		cs.debt.Store(0)
+	} else {
+		cs.debt.Store(int64(held - maxPermits))
+	}
+
+	cs.sem = sem
+	cs.maxPermits = maxPermits
+	if cs.util != nil {
+		cs.util.resize(maxPermits)
+	}
+	close(cs.resized)
+	cs.resized = make(chan struct{})
+	return nil
+}
+
 // NewCountingSemaphore — функция создания счетного семафора
 // initialPermits — начальное количество разрешений (должно быть <= maxPermits)
-func NewCountingSemaphore(maxPermits int, timeout time.Duration) *CountingSemaphore {
+// opts — дополнительные опции (имя, бортовой самописец и т.д.)
+func NewCountingSemaphore(maxPermits int, timeout time.Duration, opts ...Option) *CountingSemaphore {
 	sem := make(chan struct{}, maxPermits)
 	
 	// Заполняем канал начальными разрешениями
@@ -125,10 +577,21 @@ func NewCountingSemaphore(maxPermits int, timeout time.Duration) *CountingSemaph
 		sem <- struct{}{}
 	}
 
-	return &CountingSemaphore{
-		sem:            sem,
-		maxPermits:     maxPermits,
-		currentPermits: maxPermits,
-		timeout:        timeout,
+	cs := &CountingSemaphore{
+		sem:        sem,
+		maxPermits: maxPermits,
+		resized:    make(chan struct{}),
+		gather:     make(chan struct{}, 1),
+		timeout:    timeout,
+		waits:      latency.NewRecorder(),
+		clock:      clock.Real(),
+		id:         nextID.Add(1),
+	}
+	for _, opt := range opts {
+		opt(cs)
 	}
-}
\ No newline at end of file
+	if cs.util != nil {
+		cs.util.start(cs.clock, maxPermits)
+	}
+	return cs
+}
@@ -0,0 +1,164 @@
+package semaphore
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"goroutines-example/clock"
+)
+
+// TestAcquireNContextCompeting — два вызова по 3 разрешения из 4 не должны
+// держать части друг друга: разрешения освобождаются по одному, и без
+// защиты каждый ожидающий получил бы по 2 и ждал бы вечно
+func TestAcquireNContextCompeting(t *testing.T) {
+	cs := NewCountingSemaphore(4, 5*time.Second)
+	if err := cs.AcquireNContext(context.Background(), 4); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := cs.AcquireNContext(ctx, 3); err != nil {
+				errs <- err
+				return
+			}
+			time.Sleep(time.Millisecond)
+			if err := cs.ReleaseN(3); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	// Даем обоим встать в ожидание и освобождаем разрешения по одному
+	time.Sleep(20 * time.Millisecond)
+	for i := 0; i < 4; i++ {
+		if err := cs.Release(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("захват 3 разрешений: %v", err)
+	}
+	if got := cs.AvailablePermits(); got != 4 {
+		t.Fatalf("свободно %d разрешений, ожидалось 4", got)
+	}
+}
+
+// TestAcquireAtLeastCancelReturnsPermits — отмененный ожидающий вызов
+// возвращает все накопленные разрешения
+func TestAcquireAtLeastCancelReturnsPermits(t *testing.T) {
+	cs := NewCountingSemaphore(4, 5*time.Second)
+	if err := cs.AcquireNContext(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := cs.AcquireAtLeast(ctx, 3, 4)
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-done; err == nil {
+		t.Fatal("ожидалась ошибка отмены контекста")
+	}
+	if got := cs.AvailablePermits(); got != 2 {
+		t.Fatalf("свободно %d разрешений, ожидалось 2", got)
+	}
+	if err := cs.ReleaseN(2); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestYieldHookScheduler — под планировщиком с точками переключения
+// в семафоре чередование воспроизводится по seed, а разрешение
+// никогда не удерживается двумя задачами сразу
+func TestYieldHookScheduler(t *testing.T) {
+	run := func(seed int64) []string {
+		s := clock.NewScheduler(seed)
+		cs := NewCountingSemaphore(1, time.Second, WithYieldHook(s.Yield))
+		holders := 0
+		for _, name := range []string{"a", "b", "c"} {
+			s.Go(name, func() {
+				for i := 0; i < 2; {
+					if !cs.TryAcquire() {
+						s.Yield()
+						continue
+					}
+					holders++
+					if holders > 1 {
+						t.Errorf("разрешение удерживают %d задачи", holders)
+					}
+					s.Yield()
+					holders--
+					if err := cs.Release(); err != nil {
+						t.Error(err)
+					}
+					i++
+				}
+			})
+		}
+		return s.Run()
+	}
+
+	first := run(7)
+	if again := run(7); !reflect.DeepEqual(first, again) {
+		t.Fatalf("трасса изменилась при том же seed:\n%v\n%v", first, again)
+	}
+}
+
+// TestAcquireStopsTimers — захваты не оставляют живых таймеров, и
+// Waiters у виртуальных часов показывает только действительно ждущих
+func TestAcquireStopsTimers(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	cs := NewCountingSemaphore(1, time.Second, WithClock(fc))
+	for i := 0; i < 2; i++ {
+		if err := cs.Acquire(); err != nil {
+			t.Fatal(err)
+		}
+		if err := cs.Release(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := cs.AcquireAtLeast(context.Background(), 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if n := fc.Waiters(); n != 0 {
+		t.Fatalf("после захватов без ожидания осталось %d таймеров", n)
+	}
+
+	// Разрешение занято: ожидающий захват заводит таймер и снимает его,
+	// получив разрешение до таймаута
+	done := make(chan error, 1)
+	go func() { done <- cs.Acquire() }()
+	fc.BlockUntil(1)
+	if err := cs.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if n := fc.Waiters(); n != 0 {
+		t.Fatalf("после ожидания осталось %d таймеров", n)
+	}
+
+	// Таймаут по-прежнему срабатывает по виртуальному времени
+	go func() { done <- cs.Acquire() }()
+	fc.BlockUntil(1)
+	fc.Advance(time.Second)
+	if err := <-done; err == nil {
+		t.Fatal("ожидалась ошибка таймаута")
+	}
+}
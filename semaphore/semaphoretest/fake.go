@@ -0,0 +1,133 @@
+package semaphoretest
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"goroutines-example/semaphore"
+)
+
+// ErrNoPermits — ошибка захвата при отсутствии свободных разрешений
+// Fake не ждет освобождения, чтобы тесты не зависели от времени
+var ErrNoPermits = errors.New("нет свободных разрешений")
+
+// Calls — количество вызовов методов Fake
+type Calls struct {
+	Acquire    int
+	TryAcquire int
+	Release    int
+}
+
+// Fake — поддельный семафор со сценарием поведения для тестов
+// Захват без свободных разрешений сразу завершается ErrNoPermits.
+// Через Script можно заранее задать результаты следующих захватов,
+// например сымитировать таймаут на третьем вызове
+type Fake struct {
+	// Защита всех полей
+	mutex sync.Mutex
+	// Количество свободных и максимальное количество разрешений
+	available int
+	max       int
+	// Заданные результаты следующих вызовов Acquire/TryAcquire
+	script []error
+	// Счетчики вызовов
+	calls Calls
+}
+
+var _ semaphore.Semaphore = (*Fake)(nil)
+
+// NewFake — функция создания поддельного семафора с permits свободными разрешениями
+func NewFake(permits int) *Fake {
+	return &Fake{available: permits, max: permits}
+}
+
+// Script — метод задания результатов следующих захватов по порядку
+// nil означает обычное поведение, ненулевая ошибка — отказ с этой ошибкой
+// без изменения количества разрешений (для TryAcquire — false).
+// Когда сценарий исчерпан, Fake снова ведет себя обычно
+func (f *Fake) Script(results ...error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.script = append(f.script, results...)
+}
+
+// Calls — метод получения счетчиков вызовов
+func (f *Fake) Calls() Calls {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.calls
+}
+
+// Acquire — метод захвата одного разрешения
+func (f *Fake) Acquire() error {
+	return f.AcquireNContext(context.Background(), 1)
+}
+
+// AcquireContext — метод захвата одного разрешения с проверкой ctx
+func (f *Fake) AcquireContext(ctx context.Context) error {
+	return f.AcquireNContext(ctx, 1)
+}
+
+// AcquireNContext — метод захвата n разрешений с проверкой ctx
+// Отмененный ctx имеет приоритет над сценарием
+func (f *Fake) AcquireNContext(ctx context.Context, n int) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.calls.Acquire++
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := f.nextLocked(); err != nil {
+		return err
+	}
+	if f.available < n {
+		return ErrNoPermits
+	}
+	f.available -= n
+	return nil
+}
+
+// TryAcquire — метод попытки захвата одного разрешения
+func (f *Fake) TryAcquire() bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.calls.TryAcquire++
+	if f.nextLocked() != nil || f.available == 0 {
+		return false
+	}
+	f.available--
+	return true
+}
+
+// Release — метод освобождения одного разрешения
+func (f *Fake) Release() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.calls.Release++
+	if f.available == f.max {
+		return errors.New("все разрешения уже свободны")
+	}
+	f.available++
+	return nil
+}
+
+// AvailablePermits — метод получения количества свободных разрешений
+func (f *Fake) AvailablePermits() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.available
+}
+
+// nextLocked — метод извлечения следующего результата из сценария
+func (f *Fake) nextLocked() error {
+	if len(f.script) == 0 {
+		return nil
+	}
+	err := f.script[0]
+	f.script = f.script[1:]
+	return err
+}
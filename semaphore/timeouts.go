@@ -0,0 +1,70 @@
+package semaphore
+
+import (
+	"fmt"
+	"time"
+)
+
+// ErrTimeoutInfo — ошибка истечения таймаута захвата с данными об ожидании
+// Возвращается Acquire, AcquireContext, AcquireNContext и AcquireAtLeast;
+// извлекается через errors.As, чтобы записать в журнал, почему захват не
+// удался, и подобрать лимиты
+type ErrTimeoutInfo struct {
+	// Имя семафора (WithName)
+	Name string
+	// Запрошено разрешений (для AcquireAtLeast — минимум)
+	Requested int
+	// Захвачено к моменту таймаута (все они возвращены семафору)
+	Got int
+	// Сколько длилось ожидание
+	Waited time.Duration
+	// Свободных разрешений и их максимум в момент таймаута
+	Available  int
+	MaxPermits int
+	// Ожидающих захватов в момент таймаута (учитываются только
+	// с опцией WithMaxWaiters, иначе 0)
+	QueueLength int
+	// Оценка, через сколько повтор имеет шанс на успех, по недавней
+	// скорости освобождений (только с опцией WithRetryAfter, иначе 0)
+	RetryAfter time.Duration
+}
+
+// Error — метод формирования текста ошибки
+func (e *ErrTimeoutInfo) Error() string {
+	var msg string
+	if e.Requested == 1 {
+		msg = "Не удалось захватить разрешение у семафора"
+	} else {
+		msg = fmt.Sprintf("не удалось захватить %d разрешений у семафора: получено %d", e.Requested, e.Got)
+	}
+	details := fmt.Sprintf("ожидание %v, свободно %d из %d, ожидающих %d",
+		e.Waited, e.Available, e.MaxPermits, e.QueueLength)
+	if e.Name != "" {
+		details = fmt.Sprintf("семафор %q, %s", e.Name, details)
+	}
+	if e.RetryAfter > 0 {
+		details += fmt.Sprintf(", повторить через %v", e.RetryAfter)
+	}
+	return msg + " (" + details + ")"
+}
+
+// timeoutError — метод формирования ошибки таймаута с текущим состоянием семафора
+func (cs *CountingSemaphore) timeoutError(requested, got int, waited time.Duration) error {
+	info := &ErrTimeoutInfo{
+		Name:        cs.name,
+		Requested:   requested,
+		Got:         got,
+		Waited:      waited,
+		Available:   cs.AvailablePermits(),
+		MaxPermits:  cs.MaxPermits(),
+		QueueLength: cs.Waiters(),
+	}
+	// Нужно дождаться недостающих разрешений и ожидающих впереди
+	missing := requested - info.Available
+	ahead := info.QueueLength - 1
+	if ahead < 0 {
+		ahead = 0
+	}
+	info.RetryAfter = cs.retryHint(missing + ahead)
+	return info
+}
@@ -0,0 +1,116 @@
+package semaphore
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"goroutines-example/clock"
+	"goroutines-example/flightrec"
+)
+
+// WindowUtilization — сглаженная загрузка семафора за окно
+type WindowUtilization struct {
+	// Окно сглаживания (постоянная времени экспоненты)
+	Window time.Duration
+	// Доля захваченных разрешений (0–1, может превышать 1 после уменьшения Resize)
+	Value float64
+}
+
+// utilization — экспоненциальное скользящее среднее доли захваченных разрешений
+// Загрузка между событиями постоянна, поэтому среднее обновляется точно
+// в момент каждого захвата и освобождения, без фонового опроса:
+// v += (1 - e^(-dt/окно)) * (u - v), где u — загрузка до события
+type utilization struct {
+	// Защита всех полей
+	mutex   sync.Mutex
+	clock   clock.Clock
+	windows []time.Duration
+	values  []float64
+	// Момент последнего обновления и загрузка с этого момента
+	updated time.Time
+	held    int
+	max     int
+}
+
+// WithUtilization — опция включения учета сглаженной загрузки для Utilization
+// windows — окна сглаживания; по умолчанию 1, 10 и 60 секунд (как load average)
+func WithUtilization(windows ...time.Duration) Option {
+	if len(windows) == 0 {
+		windows = []time.Duration{time.Second, 10 * time.Second, time.Minute}
+	}
+	return func(cs *CountingSemaphore) {
+		cs.util = &utilization{
+			windows: windows,
+			values:  make([]float64, len(windows)),
+		}
+	}
+}
+
+// start — метод начала учета после применения всех опций
+func (u *utilization) start(c clock.Clock, max int) {
+	u.clock = c
+	u.updated = c.Now()
+	u.max = max
+}
+
+// advanceLocked — метод учета загрузки с момента последнего обновления
+func (u *utilization) advanceLocked(now time.Time) {
+	dt := now.Sub(u.updated)
+	if dt <= 0 {
+		return
+	}
+	current := float64(u.held) / float64(u.max)
+	for i, w := range u.windows {
+		alpha := 1 - math.Exp(-float64(dt)/float64(w))
+		u.values[i] += alpha * (current - u.values[i])
+	}
+	u.updated = now
+}
+
+// event — метод учета захвата или освобождения разрешения
+func (u *utilization) event(kind flightrec.Kind) {
+	var delta int
+	switch kind {
+	case flightrec.Acquire:
+		delta = 1
+	case flightrec.Release:
+		delta = -1
+	default:
+		return
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.advanceLocked(u.clock.Now())
+	u.held += delta
+}
+
+// resize — метод учета нового максимума разрешений
+func (u *utilization) resize(max int) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.advanceLocked(u.clock.Now())
+	u.max = max
+}
+
+// Utilization — метод получения сглаженной загрузки семафора по окнам
+// Нужен для решений автоскейлера и дашбордов: мгновенное количество
+// свободных разрешений слишком шумное. Возвращает nil, если учет
+// не включен опцией WithUtilization
+func (cs *CountingSemaphore) Utilization() []WindowUtilization {
+	u := cs.util
+	if u == nil {
+		return nil
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.advanceLocked(u.clock.Now())
+
+	out := make([]WindowUtilization, len(u.windows))
+	for i, w := range u.windows {
+		out[i] = WindowUtilization{Window: w, Value: u.values[i]}
+	}
+	return out
+}
@@ -0,0 +1,38 @@
+package semaphore
+
+import "errors"
+
+// ErrQueueFull — ошибка захвата при превышении количества ожидающих
+var ErrQueueFull = errors.New("очередь ожидающих семафора переполнена")
+
+// WithMaxWaiters — опция ограничения количества ожидающих захватов
+// Если разрешений нет, а ждут уже n вызовов Acquire/AcquireContext/
+// AcquireAtLeast, новый вызов сразу возвращает ErrQueueFull (с опцией
+// WithRetryAfter — обернутую в *RetryAfterError). Неограниченное
+// количество заблокированных горутин во время инцидента опасно для памяти.
+// n <= 0 — без ограничения
+func WithMaxWaiters(n int) Option {
+	return func(cs *CountingSemaphore) {
+		cs.maxWaiters = int64(n)
+	}
+}
+
+// enqueue — метод постановки вызова в число ожидающих
+// Возвращает ErrQueueFull, если ожидающих уже maxWaiters, иначе функцию
+// ухода из числа ожидающих
+func (cs *CountingSemaphore) enqueue() (leave func(), err error) {
+	if cs.maxWaiters <= 0 {
+		return func() {}, nil
+	}
+	if cs.waiters.Add(1) > cs.maxWaiters {
+		cs.waiters.Add(-1)
+		return nil, cs.saturated(ErrQueueFull)
+	}
+	return func() { cs.waiters.Add(-1) }, nil
+}
+
+// Waiters — метод получения количества ожидающих захватов
+// Учитываются только при включенной опции WithMaxWaiters
+func (cs *CountingSemaphore) Waiters() int {
+	return int(cs.waiters.Load())
+}
@@ -0,0 +1,62 @@
+package semaphore
+
+import (
+	"context"
+	"time"
+)
+
+// waitKey — ключ контекста для измеренного времени ожидания разрешения
+type waitKey struct{}
+
+// WaitFromContext — функция получения времени ожидания разрешения
+// Доступно внутри функции, запущенной через With или WithBudget, чтобы
+// вызывающий код мог скорректировать таймауты нижележащих вызовов
+func WaitFromContext(ctx context.Context) (time.Duration, bool) {
+	wait, ok := ctx.Value(waitKey{}).(time.Duration)
+	return wait, ok
+}
+
+// With — метод выполнения fn под разрешением семафора
+// Захватывает разрешение с отменой через ctx, выполняет fn и освобождает
+// разрешение даже при панике. Время ожидания разрешения доступно в fn
+// через WaitFromContext
+func (cs *CountingSemaphore) With(ctx context.Context, fn func(ctx context.Context) error) error {
+	start := cs.clock.Now()
+	if err := cs.AcquireContext(ctx); err != nil {
+		return err
+	}
+	defer cs.Release()
+
+	return fn(context.WithValue(ctx, waitKey{}, cs.clock.Since(start)))
+}
+
+// WithBudget — метод выполнения fn под разрешением с общим бюджетом времени
+// budget покрывает и ожидание разрешения, и выполнение fn: fn получает
+// контекст с таймаутом budget минус фактическое время ожидания (но не позже
+// дедлайна ctx). Так долгое ожидание в очереди не растягивает общий ответ
+// сверх бюджета. Если бюджет исчерпан еще в очереди, fn не вызывается
+// и возвращается context.DeadlineExceeded
+func (cs *CountingSemaphore) WithBudget(ctx context.Context, budget time.Duration, fn func(ctx context.Context) error) error {
+	start := cs.clock.Now()
+
+	acquireCtx, cancelAcquire := context.WithTimeout(ctx, budget)
+	err := cs.AcquireContext(acquireCtx)
+	cancelAcquire()
+	if err != nil {
+		if ctx.Err() == nil && acquireCtx.Err() != nil {
+			return context.DeadlineExceeded
+		}
+		return err
+	}
+	defer cs.Release()
+
+	wait := cs.clock.Since(start)
+	remaining := budget - wait
+	if remaining <= 0 {
+		return context.DeadlineExceeded
+	}
+
+	fnCtx, cancel := context.WithTimeout(context.WithValue(ctx, waitKey{}, wait), remaining)
+	defer cancel()
+	return fn(fnCtx)
+}
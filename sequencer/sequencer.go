@@ -0,0 +1,154 @@
+package sequencer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrDuplicate — ошибка повторной передачи уже принятого номера
+var ErrDuplicate = errors.New("результат с таким номером уже передан")
+
+// ErrClosed — ошибка работы с закрытым упорядочивателем
+var ErrClosed = errors.New("упорядочиватель закрыт")
+
+// Sequencer — буфер переупорядочивания
+// Принимает от конкурентных производителей результаты, помеченные
+// последовательными номерами (начиная с нуля), и выдает их строго по порядку.
+// Буфер ограничен: производитель, опередивший очередь больше чем на размер
+// буфера, блокируется (обратное давление), пока отставшие номера не будут выданы
+type Sequencer[T any] struct {
+	// Защита состояния
+	mutex sync.Mutex
+	// Кольцевой буфер ожидающих результатов, индексируемый номером по модулю размера
+	buffer []T
+	// Признаки заполненности ячеек буфера
+	filled []bool
+	// Номер следующего результата, который должен быть выдан
+	next uint64
+	// Закрыт ли упорядочиватель
+	closed bool
+	// Закрывается и заменяется новым при любом изменении состояния
+	changed chan struct{}
+}
+
+// NewSequencer — функция создания упорядочивателя
+// size — размер буфера, то есть насколько номера могут опережать очередь
+func NewSequencer[T any](size int) (*Sequencer[T], error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("размер буфера должен быть положительным: %d", size)
+	}
+	return &Sequencer[T]{
+		buffer:  make([]T, size),
+		filled:  make([]bool, size),
+		changed: make(chan struct{}),
+	}, nil
+}
+
+// Put — метод передачи результата с номером seq
+// Блокируется, пока seq не попадет в окно буфера или пока не отменен ctx
+func (s *Sequencer[T]) Put(ctx context.Context, seq uint64, v T) error {
+	size := uint64(len(s.buffer))
+	for {
+		s.mutex.Lock()
+		if s.closed {
+			s.mutex.Unlock()
+			return ErrClosed
+		}
+		if seq < s.next {
+			s.mutex.Unlock()
+			return ErrDuplicate
+		}
+		if seq < s.next+size {
+			i := seq % size
+			if s.filled[i] {
+				s.mutex.Unlock()
+				return ErrDuplicate
+			}
+			s.buffer[i] = v
+			s.filled[i] = true
+			s.notifyLocked()
+			s.mutex.Unlock()
+			return nil
+		}
+		changed := s.changed
+		s.mutex.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Next — метод получения очередного по порядку результата
+// Блокируется, пока результат с очередным номером не передан.
+// После Close возвращает оставшиеся по порядку результаты, затем ErrClosed
+func (s *Sequencer[T]) Next(ctx context.Context) (T, error) {
+	size := uint64(len(s.buffer))
+	for {
+		s.mutex.Lock()
+		i := s.next % size
+		if s.filled[i] {
+			v := s.buffer[i]
+			var zero T
+			s.buffer[i] = zero
+			s.filled[i] = false
+			s.next++
+			s.notifyLocked()
+			s.mutex.Unlock()
+			return v, nil
+		}
+		if s.closed {
+			s.mutex.Unlock()
+			var zero T
+			return zero, ErrClosed
+		}
+		changed := s.changed
+		s.mutex.Unlock()
+
+		select {
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Run — метод выдачи результатов по порядку в sink до закрытия или отмены ctx
+// Возвращает nil после выдачи всех результатов закрытого упорядочивателя
+func (s *Sequencer[T]) Run(ctx context.Context, sink func(T) error) error {
+	for {
+		v, err := s.Next(ctx)
+		if errors.Is(err, ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := sink(v); err != nil {
+			return err
+		}
+	}
+}
+
+// Close — метод закрытия упорядочивателя
+// Новые результаты больше не принимаются; ожидающие производители получают ErrClosed
+func (s *Sequencer[T]) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.closed {
+		s.closed = true
+		s.notifyLocked()
+	}
+}
+
+// notifyLocked — метод пробуждения всех ожидающих горутин
+// Вызывается при захваченном мьютексе
+func (s *Sequencer[T]) notifyLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
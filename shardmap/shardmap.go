@@ -0,0 +1,134 @@
+package shardmap
+
+import (
+	"fmt"
+	"hash/maphash"
+	"sync"
+)
+
+// shard — одна часть карты со своим мьютексом
+type shard[V any] struct {
+	mutex sync.RWMutex
+	items map[string]V
+}
+
+// Map — потокобезопасная карта, разделенная на части (шарды)
+// Ключи распределяются по шардам по хешу, и каждый шард защищен своим
+// мьютексом, поэтому горутины, работающие с разными ключами, почти
+// не мешают друг другу, в отличие от карты под одним общим мьютексом
+type Map[V any] struct {
+	seed   maphash.Seed
+	shards []shard[V]
+}
+
+// New — функция создания карты из shards частей
+func New[V any](shards int) (*Map[V], error) {
+	if shards <= 0 {
+		return nil, fmt.Errorf("количество шардов должно быть положительным: %d", shards)
+	}
+	m := &Map[V]{
+		seed:   maphash.MakeSeed(),
+		shards: make([]shard[V], shards),
+	}
+	for i := range m.shards {
+		m.shards[i].items = make(map[string]V)
+	}
+	return m, nil
+}
+
+// shardFor — метод выбора шарда для ключа
+func (m *Map[V]) shardFor(key string) *shard[V] {
+	h := maphash.String(m.seed, key)
+	return &m.shards[h%uint64(len(m.shards))]
+}
+
+// Get — метод получения значения по ключу
+func (m *Map[V]) Get(key string) (V, bool) {
+	s := m.shardFor(key)
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	v, ok := s.items[key]
+	return v, ok
+}
+
+// Set — метод записи значения по ключу
+func (m *Map[V]) Set(key string, v V) {
+	s := m.shardFor(key)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.items[key] = v
+}
+
+// Delete — метод удаления ключа
+func (m *Map[V]) Delete(key string) {
+	s := m.shardFor(key)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.items, key)
+}
+
+// Compute — метод атомарного изменения значения по ключу
+// fn получает текущее значение (ok=false, если ключа нет) и возвращает новое;
+// если keep=false, ключ удаляется. Пока выполняется fn, шард заблокирован,
+// поэтому fn должна быть быстрой и не обращаться к карте
+func (m *Map[V]) Compute(key string, fn func(old V, ok bool) (v V, keep bool)) V {
+	s := m.shardFor(key)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	old, ok := s.items[key]
+	v, keep := fn(old, ok)
+	if keep {
+		s.items[key] = v
+	} else {
+		delete(s.items, key)
+	}
+	return v
+}
+
+// Range — метод обхода всех элементов карты
+// Обход идет по шардам, каждый шард блокируется на время своего обхода.
+// Если fn возвращает false, обход прекращается
+func (m *Map[V]) Range(fn func(key string, v V) bool) {
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mutex.RLock()
+		for k, v := range s.items {
+			if !fn(k, v) {
+				s.mutex.RUnlock()
+				return
+			}
+		}
+		s.mutex.RUnlock()
+	}
+}
+
+// DeleteIf — метод удаления всех элементов, для которых fn возвращает true
+// Возвращает количество удаленных элементов
+func (m *Map[V]) DeleteIf(fn func(key string, v V) bool) int {
+	deleted := 0
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mutex.Lock()
+		for k, v := range s.items {
+			if fn(k, v) {
+				delete(s.items, k)
+				deleted++
+			}
+		}
+		s.mutex.Unlock()
+	}
+	return deleted
+}
+
+// Len — метод получения количества элементов
+func (m *Map[V]) Len() int {
+	n := 0
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mutex.RLock()
+		n += len(s.items)
+		s.mutex.RUnlock()
+	}
+	return n
+}
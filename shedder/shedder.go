@@ -0,0 +1,134 @@
+package shedder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"goroutines-example/semaphore"
+)
+
+// ErrOverloaded — ошибка отказа в обслуживании из-за перегрузки
+var ErrOverloaded = errors.New("работа отклонена: время ожидания в очереди превышает целевое")
+
+// Shedder — ограничитель нагрузки по времени ожидания (в духе алгоритма CoDel)
+// В отличие от ограничения по длине очереди, он начинает отклонять работу,
+// когда ожидание разрешения стабильно превышает целевую задержку
+// в течение интервала, а не когда очередь уже переполнена
+type Shedder struct {
+	// Семафор, ограничивающий количество одновременно выполняемых задач
+	sem *semaphore.CountingSemaphore
+	// Целевое (допустимое) время ожидания разрешения
+	target time.Duration
+	// Интервал, в течение которого ожидание должно превышать target,
+	// прежде чем начнется отклонение работы
+	interval time.Duration
+
+	// Защита состояния алгоритма
+	mutex sync.Mutex
+	// Момент, после которого начнется отклонение, если ожидание
+	// так и не опустится ниже target (нулевое значение — отсчет не идет)
+	firstAboveTime time.Time
+	// Находится ли ограничитель в режиме отклонения работы
+	dropping bool
+
+	// Количество принятых и отклоненных задач
+	admitted atomic.Uint64
+	rejected atomic.Uint64
+}
+
+// NewShedder — функция создания ограничителя нагрузки
+// target — целевое время ожидания разрешения
+// interval — время, в течение которого превышение target считается устойчивым
+func NewShedder(sem *semaphore.CountingSemaphore, target, interval time.Duration) (*Shedder, error) {
+	if sem == nil {
+		return nil, fmt.Errorf("семафор не задан")
+	}
+	if target <= 0 || interval <= 0 {
+		return nil, fmt.Errorf("целевая задержка и интервал должны быть положительными: %v, %v", target, interval)
+	}
+	return &Shedder{
+		sem:      sem,
+		target:   target,
+		interval: interval,
+	}, nil
+}
+
+// Do — метод выполнения fn под защитой ограничителя
+// Если свободное разрешение есть, fn выполняется сразу.
+// Если разрешений нет и ограничитель в режиме отклонения, возвращается ErrOverloaded
+// без ожидания. Иначе метод ждет разрешение как обычный Acquire семафора
+func (s *Shedder) Do(fn func() error) error {
+	release, err := s.Acquire(context.Background())
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn()
+}
+
+// Acquire — метод получения допуска с отменой через ctx (реализует limiter.Limiter)
+// Правила допуска те же, что у Do; ожидание дополнительно прерывается
+// отменой ctx. Возвращенную функцию нужно вызвать ровно один раз
+func (s *Shedder) Acquire(ctx context.Context) (release func(), err error) {
+	if s.sem.TryAcquire() {
+		s.observe(0)
+	} else {
+		if s.Dropping() {
+			s.rejected.Add(1)
+			return nil, ErrOverloaded
+		}
+
+		start := time.Now()
+		if err := s.sem.AcquireContext(ctx); err != nil {
+			// Таймаут ожидания — тоже признак перегрузки
+			s.observe(time.Since(start))
+			s.rejected.Add(1)
+			return nil, err
+		}
+		s.observe(time.Since(start))
+	}
+
+	s.admitted.Add(1)
+	return func() { s.sem.Release() }, nil
+}
+
+// observe — метод учета очередного измерения времени ожидания
+func (s *Shedder) observe(wait time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	if wait < s.target {
+		// Очередь рассосалась — выходим из режима отклонения
+		s.firstAboveTime = time.Time{}
+		s.dropping = false
+		return
+	}
+
+	if s.firstAboveTime.IsZero() {
+		s.firstAboveTime = now.Add(s.interval)
+	} else if !now.Before(s.firstAboveTime) {
+		s.dropping = true
+	}
+}
+
+// Dropping — метод проверки, отклоняет ли ограничитель новую работу
+func (s *Shedder) Dropping() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.dropping
+}
+
+// Admitted — метод получения количества принятых задач
+func (s *Shedder) Admitted() uint64 {
+	return s.admitted.Load()
+}
+
+// Rejected — метод получения количества отклоненных задач
+func (s *Shedder) Rejected() uint64 {
+	return s.rejected.Load()
+}
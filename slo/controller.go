@@ -0,0 +1,169 @@
+package slo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"goroutines-example/clock"
+	"goroutines-example/latency"
+	"goroutines-example/semaphore"
+)
+
+// Config — параметры регулятора размера семафора
+type Config struct {
+	// Целевой 99-й перцентиль задержки (SLO)
+	Target time.Duration
+	// Допустимая доля ошибок; при превышении размер уменьшается (0 — не учитывать)
+	MaxErrorRate float64
+	// Границы размера семафора
+	Min, Max int
+	// Период пересчета размера
+	Interval time.Duration
+	// Минимальное количество замеров за период для принятия решения
+	MinSamples int
+	// Режим рекомендаций: размер вычисляется, но Resize не вызывается
+	DryRun bool
+}
+
+// Recommendation — решение регулятора за один период
+type Recommendation struct {
+	// Размер до и рекомендованный размер после решения
+	Current, Target int
+	// Наблюдаемые за период p99 и доля ошибок
+	P99       time.Duration
+	ErrorRate float64
+	// Количество замеров за период
+	Samples uint64
+	// Рекомендация применена к семафору (false в режиме DryRun,
+	// при недостатке замеров или если размер не изменился)
+	Applied bool
+}
+
+// Option — функциональная опция регулятора
+type Option func(*Controller)
+
+// WithClock — опция источника времени для периодического пересчета
+func WithClock(c clock.Clock) Option {
+	return func(ctrl *Controller) { ctrl.clock = c }
+}
+
+// Controller — регулятор размера семафора по обратной связи от SLO
+// Принимает замеры задержки и успешности от пользователя и раз в период
+// меняет размер семафора по схеме AIMD: при нарушении SLO размер
+// уменьшается в полтора раза, а при запасе больше 20% увеличивается на один.
+// Так параллелизм сам подстраивается под возможности зависимости
+type Controller struct {
+	sem   *semaphore.CountingSemaphore
+	cfg   Config
+	clock clock.Clock
+	// Задержки за текущий период
+	latencies *latency.Recorder
+	// Количество ошибок за текущий период
+	failures atomic.Uint64
+
+	// Защита last
+	mutex sync.Mutex
+	last  Recommendation
+}
+
+// NewController — функция создания регулятора
+func NewController(sem *semaphore.CountingSemaphore, cfg Config, opts ...Option) (*Controller, error) {
+	if sem == nil {
+		return nil, fmt.Errorf("семафор не задан")
+	}
+	if cfg.Target <= 0 || cfg.Interval <= 0 {
+		return nil, fmt.Errorf("целевая задержка и период должны быть положительными: %v, %v", cfg.Target, cfg.Interval)
+	}
+	if cfg.Min <= 0 || cfg.Max < cfg.Min {
+		return nil, fmt.Errorf("некорректные границы размера: от %d до %d", cfg.Min, cfg.Max)
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = 1
+	}
+	c := &Controller{sem: sem, cfg: cfg, clock: clock.Real(), latencies: latency.NewRecorder()}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Observe — метод учета одного замера
+// d — задержка операции, ok — признак ее успешности
+func (c *Controller) Observe(d time.Duration, ok bool) {
+	c.latencies.Record(d)
+	if !ok {
+		c.failures.Add(1)
+	}
+}
+
+// Run — метод периодического пересчета размера до отмены ctx
+func (c *Controller) Run(ctx context.Context) error {
+	timer := c.clock.NewTimer(c.cfg.Interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C():
+			if _, err := c.Evaluate(); err != nil {
+				return err
+			}
+			timer.Reset(c.cfg.Interval)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Evaluate — метод принятия решения по замерам за прошедший период
+// Замеры после вызова сбрасываются. Обычно вызывается из Run,
+// но может вызываться и вручную (например, в тестах)
+func (c *Controller) Evaluate() (Recommendation, error) {
+	samples := c.latencies.Count()
+	p99 := c.latencies.Percentile(99)
+	failures := c.failures.Swap(0)
+	c.latencies.Reset()
+
+	current := c.sem.MaxPermits()
+	rec := Recommendation{Current: current, Target: current, P99: p99, Samples: samples}
+	if samples > 0 {
+		rec.ErrorRate = float64(failures) / float64(samples)
+	}
+
+	if samples >= uint64(c.cfg.MinSamples) {
+		violated := p99 > c.cfg.Target ||
+			(c.cfg.MaxErrorRate > 0 && rec.ErrorRate > c.cfg.MaxErrorRate)
+		switch {
+		case violated:
+			rec.Target = current * 2 / 3
+		case p99 < c.cfg.Target*8/10:
+			rec.Target = current + 1
+		}
+		if rec.Target < c.cfg.Min {
+			rec.Target = c.cfg.Min
+		}
+		if rec.Target > c.cfg.Max {
+			rec.Target = c.cfg.Max
+		}
+
+		if !c.cfg.DryRun && rec.Target != current {
+			if err := c.sem.Resize(rec.Target); err != nil {
+				return rec, err
+			}
+			rec.Applied = true
+		}
+	}
+
+	c.mutex.Lock()
+	c.last = rec
+	c.mutex.Unlock()
+	return rec, nil
+}
+
+// Last — метод получения последнего решения регулятора
+func (c *Controller) Last() Recommendation {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.last
+}
@@ -0,0 +1,171 @@
+package striped
+
+import (
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"goroutines-example/internal/cpu"
+)
+
+// cell — ячейка счетчика, занимающая собственную кеш-линию,
+// чтобы записи в соседние ячейки не мешали друг другу (false sharing)
+type cell struct {
+	value atomic.Int64
+	_     [cpu.CacheLineSize - 8]byte
+}
+
+// cells — набор ячеек и выбор ячейки для текущей горутины
+type cells struct {
+	cells []cell
+	// Пул номеров ячеек. sync.Pool хранит объекты локально для каждого P,
+	// поэтому горутины, работающие на одном процессоре, обычно получают
+	// одну и ту же ячейку, а на разных процессорах — разные
+	indexes sync.Pool
+	// Счетчик для раздачи номеров новым элементам пула
+	nextIndex atomic.Uint32
+}
+
+// newCells — функция создания набора ячеек по числу процессоров
+func newCells() *cells {
+	n := runtime.GOMAXPROCS(0)
+	c := &cells{cells: make([]cell, n)}
+	c.indexes.New = func() any {
+		i := int(c.nextIndex.Add(1)-1) % n
+		return &i
+	}
+	return c
+}
+
+// pick — метод выбора ячейки для текущей горутины
+func (c *cells) pick() *cell {
+	i := c.indexes.Get().(*int)
+	cl := &c.cells[*i]
+	c.indexes.Put(i)
+	return cl
+}
+
+// sum — метод суммирования значений всех ячеек
+func (c *cells) sum() int64 {
+	var s int64
+	for i := range c.cells {
+		s += c.cells[i].value.Load()
+	}
+	return s
+}
+
+// reset — метод обнуления всех ячеек
+func (c *cells) reset() {
+	for i := range c.cells {
+		c.cells[i].value.Store(0)
+	}
+}
+
+// Counter — счетчик, устойчивый к конкуренции (аналог LongAdder из Java)
+// Увеличения распределяются по ячейкам на разных кеш-линиях,
+// а при чтении значения ячеек суммируются. Запись дешевле, чем у atomic.Int64
+// под высокой конкуренцией; чтение дороже и не является атомарным снимком
+type Counter struct {
+	c *cells
+}
+
+// NewCounter — функция создания счетчика
+func NewCounter() *Counter {
+	return &Counter{c: newCells()}
+}
+
+// Add — метод увеличения счетчика на delta
+func (c *Counter) Add(delta int64) {
+	c.c.pick().value.Add(delta)
+}
+
+// Inc — метод увеличения счетчика на 1
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Value — метод получения текущего значения счетчика
+// При конкурентной записи результат учитывает часть одновременных увеличений
+func (c *Counter) Value() int64 {
+	return c.c.sum()
+}
+
+// Reset — метод обнуления счетчика
+func (c *Counter) Reset() {
+	c.c.reset()
+}
+
+// Gauge — показатель, который может как увеличиваться, так и уменьшаться
+// (например, количество выполняющихся задач)
+type Gauge struct {
+	c *cells
+}
+
+// NewGauge — функция создания показателя
+func NewGauge() *Gauge {
+	return &Gauge{c: newCells()}
+}
+
+// Add — метод изменения показателя на delta (может быть отрицательным)
+func (g *Gauge) Add(delta int64) {
+	g.c.pick().value.Add(delta)
+}
+
+// Inc — метод увеличения показателя на 1
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+// Dec — метод уменьшения показателя на 1
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+// Value — метод получения текущего значения показателя
+func (g *Gauge) Value() int64 {
+	return g.c.sum()
+}
+
+// Max — отслеживание максимального значения из множества горутин
+// Каждая ячейка хранит свой максимум, при чтении выбирается наибольший
+type Max struct {
+	c *cells
+}
+
+// NewMax — функция создания отслеживателя максимума
+func NewMax() *Max {
+	m := &Max{c: newCells()}
+	m.Reset()
+	return m
+}
+
+// Observe — метод учета очередного значения
+func (m *Max) Observe(v int64) {
+	cl := m.c.pick()
+	for {
+		cur := cl.value.Load()
+		if v <= cur || cl.value.CompareAndSwap(cur, v) {
+			return
+		}
+	}
+}
+
+// Value — метод получения максимального учтенного значения
+// Если значений еще не было, возвращает math.MinInt64
+func (m *Max) Value() int64 {
+	result := int64(math.MinInt64)
+	for i := range m.c.cells {
+		if v := m.c.cells[i].value.Load(); v > result {
+			result = v
+		}
+	}
+	return result
+}
+
+// Reset — метод сброса максимума
+func (m *Max) Reset() {
+	for i := range m.c.cells {
+		m.c.cells[i].value.Store(math.MinInt64)
+	}
+}
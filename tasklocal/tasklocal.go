@@ -0,0 +1,106 @@
+package tasklocal
+
+import "context"
+
+// Key — типизированный ключ значения задачи
+// Ключи сравниваются по указателю, поэтому два ключа с одним именем различны
+type Key[T any] struct {
+	// Имя ключа для диагностики
+	name string
+}
+
+// NewKey — функция создания ключа с именем name
+func NewKey[T any](name string) *Key[T] {
+	return &Key[T]{name: name}
+}
+
+// String — метод получения имени ключа
+func (k *Key[T]) String() string {
+	return k.name
+}
+
+// ctxKey — ключ набора значений задачи в контексте
+type ctxKey struct{}
+
+// values — набор значений задачи
+// Набор не меняется после создания: With копирует его, поэтому снимок,
+// переданный в задачу, не видит последующих изменений у отправителя
+type values map[any]any
+
+// from — функция получения набора значений из ctx (nil, если его нет)
+func from(ctx context.Context) values {
+	vals, _ := ctx.Value(ctxKey{}).(values)
+	return vals
+}
+
+// With — функция получения контекста, в котором ключ k имеет значение v
+// Значения наследуются дочерними контекстами и исчезают вместе с контекстом
+// задачи, поэтому не требуют явной очистки и не копятся в глобальных картах
+func With[T any](ctx context.Context, k *Key[T], v T) context.Context {
+	old := from(ctx)
+	vals := make(values, len(old)+1)
+	for key, val := range old {
+		vals[key] = val
+	}
+	vals[k] = v
+	return context.WithValue(ctx, ctxKey{}, vals)
+}
+
+// Get — функция получения значения ключа k из ctx
+// Возвращает false, если значение не задано
+func Get[T any](ctx context.Context, k *Key[T]) (T, bool) {
+	v, ok := from(ctx)[k]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	return v.(T), true
+}
+
+// Values — снимок значений задачи для передачи через границу Submit
+// Пул или актор захватывает снимок в момент отправки задачи (сообщения)
+// и привязывает его к контексту, в котором задача выполняется
+type Values struct {
+	vals values
+}
+
+// Capture — функция получения снимка значений ctx
+func Capture(ctx context.Context) Values {
+	return Values{vals: from(ctx)}
+}
+
+// Len — метод получения количества значений в снимке
+func (v Values) Len() int {
+	return len(v.vals)
+}
+
+// Bind — метод получения контекста ctx со значениями снимка
+// Собственные значения ctx сохраняются, а при совпадении ключей
+// побеждают значения снимка
+func (v Values) Bind(ctx context.Context) context.Context {
+	if len(v.vals) == 0 {
+		return ctx
+	}
+	own := from(ctx)
+	if len(own) == 0 {
+		return context.WithValue(ctx, ctxKey{}, v.vals)
+	}
+	vals := make(values, len(own)+len(v.vals))
+	for key, val := range own {
+		vals[key] = val
+	}
+	for key, val := range v.vals {
+		vals[key] = val
+	}
+	return context.WithValue(ctx, ctxKey{}, vals)
+}
+
+// Wrap — функция оборачивания задачи fn для отправки в пул
+// Значения ctx захватываются в момент вызова Wrap и видны fn в контексте,
+// с которым пул ее запускает; после возврата fn они нигде не остаются
+func Wrap(ctx context.Context, fn func(ctx context.Context)) func(ctx context.Context) {
+	vals := Capture(ctx)
+	return func(taskCtx context.Context) {
+		fn(vals.Bind(taskCtx))
+	}
+}
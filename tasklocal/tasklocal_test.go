@@ -0,0 +1,57 @@
+package tasklocal
+
+import (
+	"context"
+	"testing"
+)
+
+var (
+	traceID = NewKey[string]("trace_id")
+	attempt = NewKey[int]("attempt")
+)
+
+// TestWithGet — значения наследуются дочерними контекстами и перекрываются
+func TestWithGet(t *testing.T) {
+	ctx := With(context.Background(), traceID, "a")
+	child := With(ctx, attempt, 2)
+	shadow := With(child, traceID, "b")
+
+	if v, ok := Get(child, traceID); !ok || v != "a" {
+		t.Fatalf("trace_id = %q, %v, ожидалось \"a\"", v, ok)
+	}
+	if v, _ := Get(shadow, traceID); v != "b" {
+		t.Fatalf("перекрытое trace_id = %q, ожидалось \"b\"", v)
+	}
+	if v, _ := Get(child, traceID); v != "a" {
+		t.Fatalf("перекрытие изменило родителя: trace_id = %q", v)
+	}
+	if _, ok := Get(ctx, attempt); ok {
+		t.Fatal("значение дочернего контекста видно в родительском")
+	}
+	if v, ok := Get(context.Background(), attempt); ok || v != 0 {
+		t.Fatalf("значение без With: %d, %v", v, ok)
+	}
+}
+
+// TestWrap — задача видит значения, заданные до отправки, а не после
+func TestWrap(t *testing.T) {
+	ctx := With(context.Background(), traceID, "submit")
+	var got string
+	task := Wrap(ctx, func(ctx context.Context) {
+		got, _ = Get(ctx, traceID)
+		if v, _ := Get(ctx, attempt); v != 1 {
+			t.Errorf("собственное значение контекста пула потеряно: %d", v)
+		}
+	})
+	// Значение, заданное после отправки, в задачу не попадает
+	_ = With(ctx, traceID, "later")
+
+	poolCtx := With(context.Background(), attempt, 1)
+	task(poolCtx)
+	if got != "submit" {
+		t.Fatalf("задача увидела trace_id %q, ожидалось \"submit\"", got)
+	}
+	if _, ok := Get(poolCtx, traceID); ok {
+		t.Fatal("значение задачи осталось в контексте пула после ее завершения")
+	}
+}
@@ -0,0 +1,38 @@
+package testsync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"goroutines-example/filesem"
+)
+
+// EnvDir — переменная окружения с каталогом файлов блокировок Limit
+// По умолчанию используется временный каталог ОС. Пакеты тестируются
+// в отдельных процессах, поэтому каталог должен быть общим для них
+const EnvDir = "TESTSYNC_DIR"
+
+// Limit — функция ограничения количества одновременно выполняемых тестов
+// Тест занимает одно из k разрешений файлового семафора с именем name и
+// освобождает его при завершении (через t.Cleanup). Ограничение действует
+// между пакетами, которые go test запускает в разных процессах, и
+// снимается при аварийном завершении процесса. Для дорогих
+// интеграционных тестов, которым нельзя выполняться все сразу
+func Limit(t testing.TB, name string, k int) {
+	t.Helper()
+
+	dir := os.Getenv(EnvDir)
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	sem, err := filesem.New(filepath.Join(dir, "testsync-"+name), k, 0)
+	if err != nil {
+		t.Fatalf("не удалось открыть файловый семафор: %v", err)
+	}
+	if err := sem.AcquireContext(context.Background()); err != nil {
+		t.Fatalf("не удалось занять слот: %v", err)
+	}
+	t.Cleanup(func() { sem.Release() })
+}
@@ -0,0 +1,54 @@
+package testsync
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"goroutines-example/clock"
+)
+
+// Concurrently — функция одновременного запуска fn в n горутинах
+// Горутины стартуют по общему сигналу, чтобы максимально пересечься,
+// и Concurrently ждет завершения всех. Паника в fn не роняет тестовый
+// процесс, а отмечает тест как неудачный с номером горутины.
+// i — номер горутины от 0 до n-1
+func Concurrently(t testing.TB, n int, fn func(i int)) {
+	t.Helper()
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("горутина %d: паника: %v", i, r)
+				}
+			}()
+			<-start
+			fn(i)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+}
+
+// AwaitCondition — функция ожидания выполнения условия в тесте
+// Проверяет cond каждые несколько миллисекунд и завершает тест через
+// t.Fatalf, если условие не выполнилось за timeout
+func AwaitCondition(t testing.TB, cond func() bool, timeout time.Duration) {
+	t.Helper()
+
+	poll := timeout / 100
+	if poll < time.Millisecond {
+		poll = time.Millisecond
+	}
+	if poll > 50*time.Millisecond {
+		poll = 50 * time.Millisecond
+	}
+	if !clock.Eventually(clock.Real(), cond, timeout, poll) {
+		t.Fatalf("условие не выполнилось за %v", timeout)
+	}
+}
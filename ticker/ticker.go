@@ -0,0 +1,112 @@
+package ticker
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// JitterTicker — периодический тикер со случайным разбросом моментов срабатывания
+// Нужен для heartbeat-ов из множества горутин: без разброса они синхронизируются
+// и одновременно нагружают получателя (thundering herd)
+type JitterTicker struct {
+	// Канал, в который доставляются тики (как у time.Ticker)
+	C <-chan time.Time
+	// Внутренний канал для записи тиков
+	c chan time.Time
+	// Отмена внутреннего контекста тикера
+	cancel context.CancelFunc
+	// Закрывается, когда горутина тикера завершилась
+	done chan struct{}
+	// Гарантирует однократную остановку тикера
+	stopOnce sync.Once
+}
+
+// NewJitterTicker — функция создания тикера с разбросом
+// period — базовый период между тиками
+// jitter — доля периода (от 0 до 1), на которую может сдвигаться каждый тик
+// immediate — если true, первый тик приходит сразу после создания
+// Тикер останавливается при вызове Stop или при отмене ctx
+func NewJitterTicker(ctx context.Context, period time.Duration, jitter float64, immediate bool) (*JitterTicker, error) {
+	if period <= 0 {
+		return nil, fmt.Errorf("период тикера должен быть положительным: %v", period)
+	}
+	if jitter < 0 || jitter > 1 {
+		return nil, fmt.Errorf("доля разброса должна быть в диапазоне [0, 1]: %v", jitter)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	c := make(chan time.Time, 1)
+	t := &JitterTicker{
+		C:      c,
+		c:      c,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	// У каждого тикера свой источник случайных чисел, чтобы тикеры,
+	// созданные одновременно, не получили одинаковую последовательность сдвигов
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano() ^ int64(rand.Int63())))
+
+	go t.run(ctx, period, jitter, immediate, rnd)
+	return t, nil
+}
+
+// run — основной цикл тикера
+// Моменты срабатывания отсчитываются от времени старта (start + k*period + сдвиг),
+// а не от предыдущего тика, поэтому ошибка не накапливается (нет дрейфа)
+func (t *JitterTicker) run(ctx context.Context, period time.Duration, jitter float64, immediate bool, rnd *rand.Rand) {
+	defer close(t.done)
+
+	start := time.Now()
+	if immediate {
+		t.send(time.Now())
+	}
+
+	timer := time.NewTimer(0)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	defer timer.Stop()
+
+	for k := int64(1); ; k++ {
+		// Случайный сдвиг в пределах [-jitter*period/2, +jitter*period/2]
+		offset := time.Duration((rnd.Float64() - 0.5) * jitter * float64(period))
+		next := start.Add(time.Duration(k)*period + offset)
+
+		wait := time.Until(next)
+		if wait < 0 {
+			// Получатель или планировщик отстали — пропускаем просроченный тик,
+			// как это делает time.Ticker
+			continue
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-timer.C:
+			t.send(now)
+		}
+	}
+}
+
+// send — неблокирующая отправка тика
+// Если получатель не успел забрать предыдущий тик, новый отбрасывается
+func (t *JitterTicker) send(now time.Time) {
+	select {
+	case t.c <- now:
+	default:
+	}
+}
+
+// Stop — метод остановки тикера
+// После возврата из Stop новые тики в канал C не поступают
+func (t *JitterTicker) Stop() {
+	t.stopOnce.Do(func() {
+		t.cancel()
+		<-t.done
+	})
+}
@@ -0,0 +1,126 @@
+package timeout
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrTimeout — ошибка истечения времени выполнения функции
+var ErrTimeout = errors.New("время выполнения функции истекло")
+
+// Orphan — сведения о «брошенной» горутине
+// Горутину нельзя остановить принудительно: после таймаута она продолжает
+// работать, пока fn сама не заметит отмену контекста и не завершится
+type Orphan struct {
+	// Уникальный номер вызова WithTimeout
+	ID uint64
+	// Время запуска функции
+	Started time.Time
+	// Время, когда вызывающая сторона перестала ждать функцию
+	Abandoned time.Time
+}
+
+// Реестр брошенных горутин, которые еще не завершились
+var (
+	orphansMutex sync.Mutex
+	orphans      = make(map[uint64]Orphan)
+	nextID       atomic.Uint64
+)
+
+// WithTimeout — функция выполнения fn с ограничением времени d
+// Если fn не завершилась за d, возвращается ErrTimeout, а контекст,
+// переданный в fn, отменяется. Сама горутина с fn продолжает работать до
+// возврата из fn; на это время она учитывается в реестре брошенных горутин
+// (см. Orphans), что позволяет находить функции, игнорирующие отмену контекста.
+// При отмене родительского контекста возвращается его ошибка
+func WithTimeout(parent context.Context, d time.Duration, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(parent, d)
+	// Отменяем контекст в любом случае, чтобы брошенная fn узнала об этом
+	defer cancel()
+
+	id := nextID.Add(1)
+	started := time.Now()
+
+	// Защищает флаг abandoned от гонки между завершением fn и таймаутом
+	var mutex sync.Mutex
+	abandoned := false
+
+	// Буфер на одно значение: брошенная горутина не блокируется на отправке
+	done := make(chan error, 1)
+	go func() {
+		err := fn(ctx)
+
+		// Отправка под мьютексом: иначе таймаут мог бы вклиниться между
+		// проверкой abandoned и отправкой и зарегистрировать уже
+		// завершившуюся горутину. Буфер гарантирует, что отправка не блокирует
+		mutex.Lock()
+		done <- err
+		if abandoned {
+			unregisterOrphan(id)
+		}
+		mutex.Unlock()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	// fn могла успеть завершиться одновременно с таймаутом
+	select {
+	case err := <-done:
+		return err
+	default:
+	}
+
+	abandoned = true
+	registerOrphan(Orphan{ID: id, Started: started, Abandoned: time.Now()})
+
+	if err := parent.Err(); err != nil {
+		return err
+	}
+	return ErrTimeout
+}
+
+// Orphans — функция получения списка брошенных горутин, которые еще работают,
+// упорядоченного по времени запуска
+func Orphans() []Orphan {
+	orphansMutex.Lock()
+	defer orphansMutex.Unlock()
+
+	result := make([]Orphan, 0, len(orphans))
+	for _, o := range orphans {
+		result = append(result, o)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Started.Before(result[j].Started) })
+	return result
+}
+
+// OrphanCount — функция получения количества брошенных горутин, которые еще работают
+func OrphanCount() int {
+	orphansMutex.Lock()
+	defer orphansMutex.Unlock()
+	return len(orphans)
+}
+
+// registerOrphan — функция добавления горутины в реестр брошенных
+func registerOrphan(o Orphan) {
+	orphansMutex.Lock()
+	defer orphansMutex.Unlock()
+	orphans[o.ID] = o
+}
+
+// unregisterOrphan — функция удаления завершившейся горутины из реестра
+func unregisterOrphan(id uint64) {
+	orphansMutex.Lock()
+	defer orphansMutex.Unlock()
+	delete(orphans, id)
+}
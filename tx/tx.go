@@ -0,0 +1,171 @@
+package tx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"goroutines-example/semaphore"
+)
+
+// ErrFinished — ошибка использования уже завершенной единицы работы
+var ErrFinished = errors.New("единица работы уже завершена")
+
+// step — зарегистрированное действие завершения
+type step struct {
+	fn func() error
+	// Выполняется только при откате (компенсация)
+	rollbackOnly bool
+}
+
+// TxScope — единица работы, собирающая захваченные ресурсы
+// Разрешения семафоров, объекты из пулов и функции очистки регистрируются
+// по мере захвата и освобождаются все разом в обратном порядке при Commit,
+// Rollback или панике. Ручной учет нескольких освобождений — постоянный
+// источник утечек, особенно на путях с ранним возвратом ошибки
+type TxScope struct {
+	// Защита steps и finished
+	mutex    sync.Mutex
+	steps    []step
+	finished bool
+}
+
+// New — функция создания единицы работы
+func New() *TxScope {
+	return &TxScope{}
+}
+
+// Defer — метод регистрации функции очистки
+// Выполняется и при Commit, и при Rollback
+func (t *TxScope) Defer(fn func() error) error {
+	return t.push(step{fn: fn})
+}
+
+// OnRollback — метод регистрации компенсирующего действия
+// Выполняется только при Rollback или панике
+func (t *TxScope) OnRollback(fn func() error) error {
+	return t.push(step{fn: fn, rollbackOnly: true})
+}
+
+// push — метод добавления действия завершения
+func (t *TxScope) push(s step) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.finished {
+		return ErrFinished
+	}
+	t.steps = append(t.steps, s)
+	return nil
+}
+
+// Acquire — метод захвата разрешения семафора в рамках единицы работы
+// Разрешение освобождается при завершении единицы работы
+func (t *TxScope) Acquire(ctx context.Context, sem semaphore.Semaphore) error {
+	if err := sem.AcquireContext(ctx); err != nil {
+		return err
+	}
+	if err := t.Defer(sem.Release); err != nil {
+		sem.Release()
+		return err
+	}
+	return nil
+}
+
+// Get — метод получения объекта из sync.Pool с возвратом при завершении
+func (t *TxScope) Get(pool *sync.Pool) (any, error) {
+	obj := pool.Get()
+	err := t.Defer(func() error {
+		pool.Put(obj)
+		return nil
+	})
+	if err != nil {
+		pool.Put(obj)
+		return nil, err
+	}
+	return obj, nil
+}
+
+// AcquireResource — функция захвата ресурса пула в рамках единицы работы
+// Ресурс возвращается в пул при завершении единицы работы
+func AcquireResource[T any](ctx context.Context, t *TxScope, pool *semaphore.ResourcePool[T]) (*T, error) {
+	r, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Defer(func() error { return pool.Release(r) }); err != nil {
+		pool.Release(r)
+		return nil, err
+	}
+	return r, nil
+}
+
+// Commit — метод успешного завершения единицы работы
+// Выполняет функции очистки в обратном порядке; компенсации пропускаются.
+// Возвращает первую ошибку очистки (остальные действия все равно выполняются)
+func (t *TxScope) Commit() error {
+	return t.finish(false)
+}
+
+// Rollback — метод отката единицы работы
+// Выполняет функции очистки и компенсации в обратном порядке
+func (t *TxScope) Rollback() error {
+	return t.finish(true)
+}
+
+// Finish — метод завершения единицы работы по результату функции
+// Предназначен для вызова через defer: defer t.Finish(&err).
+// При панике выполняется откат, и паника продолжается; при *errp != nil —
+// откат, иначе — Commit. Ошибка завершения записывается в *errp, если
+// там еще нет ошибки
+func (t *TxScope) Finish(errp *error) {
+	if r := recover(); r != nil {
+		t.Rollback()
+		panic(r)
+	}
+	var err error
+	if *errp != nil {
+		err = t.Rollback()
+	} else {
+		err = t.Commit()
+	}
+	if err != nil && *errp == nil {
+		*errp = err
+	}
+}
+
+// finish — метод выполнения действий завершения в обратном порядке
+func (t *TxScope) finish(rollback bool) error {
+	t.mutex.Lock()
+	if t.finished {
+		t.mutex.Unlock()
+		return ErrFinished
+	}
+	t.finished = true
+	steps := t.steps
+	t.steps = nil
+	t.mutex.Unlock()
+
+	var firstErr error
+	for i := len(steps) - 1; i >= 0; i-- {
+		s := steps[i]
+		if s.rollbackOnly && !rollback {
+			continue
+		}
+		if err := runStep(s.fn); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// runStep — функция выполнения действия с перехватом паники,
+// чтобы паника одного действия не помешала освободить остальные ресурсы
+func runStep(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("паника при завершении единицы работы: %v", r)
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,127 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClosed — ошибка ожидания значения у закрытого издателя
+var ErrClosed = errors.New("издатель закрыт")
+
+// Publisher — издатель значения с версиями для раздачи многим читателям
+// Хранит только последнее значение: читатель, не успевший прочитать
+// промежуточные значения, сразу получает самое свежее (промежуточные
+// схлопываются), но последнее значение не пропустит никогда.
+// Подходит для раздачи конфигурации множеству горутин
+type Publisher[T any] struct {
+	// Защита состояния
+	mutex sync.RWMutex
+	// Текущее значение и его версия (растет с каждой публикацией)
+	value   T
+	version uint64
+	// Закрывается и заменяется новым при каждой публикации
+	changed chan struct{}
+	// Закрыт ли издатель
+	closed bool
+}
+
+// NewPublisher — функция создания издателя с начальным значением
+func NewPublisher[T any](initial T) *Publisher[T] {
+	return &Publisher[T]{
+		value:   initial,
+		changed: make(chan struct{}),
+	}
+}
+
+// Publish — метод публикации нового значения
+// Возвращает ErrClosed, если издатель закрыт
+func (p *Publisher[T]) Publish(v T) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+	p.value = v
+	p.version++
+	close(p.changed)
+	p.changed = make(chan struct{})
+	return nil
+}
+
+// Load — метод получения текущего значения и его версии
+func (p *Publisher[T]) Load() (T, uint64) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.value, p.version
+}
+
+// Subscribe — метод создания читателя
+// Текущее значение считается уже прочитанным: Next вернет следующее опубликованное
+func (p *Publisher[T]) Subscribe() *Receiver[T] {
+	_, version := p.Load()
+	return &Receiver[T]{publisher: p, seen: version}
+}
+
+// Close — метод закрытия издателя
+// Ожидающие читатели получают ErrClosed, если не прочитали последнее значение —
+// иначе сначала получат его
+func (p *Publisher[T]) Close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !p.closed {
+		p.closed = true
+		close(p.changed)
+	}
+}
+
+// Receiver — читатель значений издателя
+// Один читатель не предназначен для использования из нескольких горутин;
+// каждой горутине нужен свой читатель
+type Receiver[T any] struct {
+	publisher *Publisher[T]
+	// Версия последнего прочитанного значения
+	seen uint64
+}
+
+// Next — метод ожидания значения новее последнего прочитанного
+// Возвращает самое свежее значение; промежуточные значения пропускаются
+func (r *Receiver[T]) Next(ctx context.Context) (T, error) {
+	p := r.publisher
+	for {
+		p.mutex.RLock()
+		value, version, changed, closed := p.value, p.version, p.changed, p.closed
+		p.mutex.RUnlock()
+
+		if version != r.seen {
+			r.seen = version
+			return value, nil
+		}
+		if closed {
+			var zero T
+			return zero, ErrClosed
+		}
+
+		select {
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Current — метод получения текущего значения с отметкой его как прочитанного
+func (r *Receiver[T]) Current() T {
+	value, version := r.publisher.Load()
+	r.seen = version
+	return value
+}
+
+// Changed — метод проверки, есть ли непрочитанное значение
+func (r *Receiver[T]) Changed() bool {
+	_, version := r.publisher.Load()
+	return version != r.seen
+}
@@ -0,0 +1,276 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"goroutines-example/clock"
+	"goroutines-example/semaphore"
+)
+
+// Warning — предупреждение о зависании
+type Warning struct {
+	// Имя наблюдаемого примитива
+	Name string
+	// Как долго примитив находится в зависшем состоянии
+	Stalled time.Duration
+	// Порог, после которого выдается предупреждение
+	Threshold time.Duration
+	// Время проверки
+	Time time.Time
+}
+
+// String — метод получения текстового описания предупреждения
+func (w Warning) String() string {
+	return fmt.Sprintf("watchdog: %s не продвигается %v (порог %v)", w.Name, w.Stalled, w.Threshold)
+}
+
+// WarningHandler — обработчик предупреждений по умолчанию
+// Используется, если сторожу не передана опция WithHandler.
+// По умолчанию предупреждение выводится в stderr
+var WarningHandler = func(w Warning) {
+	fmt.Fprintln(os.Stderr, w)
+}
+
+// Probe — функция проверки примитива
+// Возвращает, как долго примитив находится в зависшем состоянии
+// (например, возраст самого старого ожидающего или время с момента,
+// когда очередь была пуста); 0 — примитив продвигается
+type Probe func() time.Duration
+
+// target — зарегистрированный примитив
+type target struct {
+	name      string
+	threshold time.Duration
+	probe     Probe
+	// Предупреждение о текущем зависании уже выдано
+	warned bool
+}
+
+// Option — функциональная опция сторожа
+type Option func(*Watchdog)
+
+// WithHandler — опция обработчика предупреждений (по умолчанию WarningHandler)
+func WithHandler(handler func(Warning)) Option {
+	return func(w *Watchdog) {
+		w.handler = handler
+	}
+}
+
+// WithStackDump — опция записи стеков всех горутин в out при каждом предупреждении
+func WithStackDump(out io.Writer) Option {
+	return func(w *Watchdog) {
+		w.dump = out
+	}
+}
+
+// WithClock — опция задания источника времени (по умолчанию реальное время)
+func WithClock(c clock.Clock) Option {
+	return func(w *Watchdog) {
+		w.clock = c
+	}
+}
+
+// Watchdog — сторож зависаний
+// Периодически опрашивает зарегистрированные примитивы и выдает
+// предупреждение, когда ожидающий заблокирован дольше порога или очередь
+// не опустошалась дольше порога. О каждом зависании сообщается один раз:
+// следующее предупреждение возможно, только когда примитив снова
+// продвинется. По желанию вместе с предупреждением записываются стеки
+// всех горутин
+type Watchdog struct {
+	interval time.Duration
+	handler  func(Warning)
+	dump     io.Writer
+	clock    clock.Clock
+
+	// Защита targets и next
+	mutex   sync.Mutex
+	targets map[int]*target
+	next    int
+}
+
+// New — функция создания сторожа
+// interval — период опроса примитивов
+func New(interval time.Duration, opts ...Option) (*Watchdog, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("период опроса должен быть положительным: %v", interval)
+	}
+	w := &Watchdog{
+		interval: interval,
+		clock:    clock.Real(),
+		targets:  make(map[int]*target),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w, nil
+}
+
+// Register — метод регистрации примитива под наблюдение
+// Возвращает функцию снятия с наблюдения; порог должен быть положительным,
+// а проба — заданной, иначе любой примитив сразу считался бы зависшим
+func (w *Watchdog) Register(name string, threshold time.Duration, probe Probe) (unregister func(), err error) {
+	if threshold <= 0 {
+		return nil, fmt.Errorf("порог зависания %q должен быть положительным: %v", name, threshold)
+	}
+	if probe == nil {
+		return nil, fmt.Errorf("проба %q не задана", name)
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	id := w.next
+	w.next++
+	w.targets[id] = &target{name: name, threshold: threshold, probe: probe}
+	return func() {
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+		delete(w.targets, id)
+	}, nil
+}
+
+// Run — метод периодического опроса до отмены ctx
+func (w *Watchdog) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-w.clock.After(w.interval):
+			w.Check()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Check — метод однократного опроса всех примитивов
+// Возвращает выданные предупреждения
+func (w *Watchdog) Check() []Warning {
+	w.mutex.Lock()
+	targets := make([]*target, 0, len(w.targets))
+	for _, t := range w.targets {
+		targets = append(targets, t)
+	}
+	w.mutex.Unlock()
+
+	var warnings []Warning
+	for _, t := range targets {
+		stalled := t.probe()
+
+		w.mutex.Lock()
+		if stalled < t.threshold {
+			t.warned = false
+			w.mutex.Unlock()
+			continue
+		}
+		if t.warned {
+			w.mutex.Unlock()
+			continue
+		}
+		t.warned = true
+		w.mutex.Unlock()
+
+		warnings = append(warnings, Warning{
+			Name:      t.name,
+			Stalled:   stalled,
+			Threshold: t.threshold,
+			Time:      w.clock.Now(),
+		})
+	}
+
+	handler := w.handler
+	if handler == nil {
+		handler = WarningHandler
+	}
+	for _, warning := range warnings {
+		handler(warning)
+	}
+	if len(warnings) > 0 && w.dump != nil {
+		pprof.Lookup("goroutine").WriteTo(w.dump, 2)
+	}
+	return warnings
+}
+
+// QueueProbe — функция создания проверки очереди по ее длине
+// Зависанием считается время с последнего опроса, на котором очередь
+// была пуста. length — функция получения текущей длины очереди
+func QueueProbe(length func() int, c clock.Clock) Probe {
+	var (
+		mutex sync.Mutex
+		empty = c.Now()
+	)
+	return func() time.Duration {
+		mutex.Lock()
+		defer mutex.Unlock()
+		now := c.Now()
+		if length() == 0 {
+			empty = now
+			return 0
+		}
+		return now.Sub(empty)
+	}
+}
+
+// Waiters — учет ожидающих горутин для сторожа
+// Семафоры пакета не хранят время начала ожидания, поэтому ожидание,
+// за которым нужно следить, оборачивается в Begin или Acquire
+type Waiters struct {
+	clock clock.Clock
+
+	// Защита started и next
+	mutex   sync.Mutex
+	started map[int]time.Time
+	next    int
+}
+
+// NewWaiters — функция создания учета ожидающих
+func NewWaiters(c clock.Clock) *Waiters {
+	return &Waiters{clock: c, started: make(map[int]time.Time)}
+}
+
+// Begin — метод учета начала ожидания
+// Возвращает функцию учета его окончания
+func (ws *Waiters) Begin() (end func()) {
+	ws.mutex.Lock()
+	id := ws.next
+	ws.next++
+	ws.started[id] = ws.clock.Now()
+	ws.mutex.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ws.mutex.Lock()
+			delete(ws.started, id)
+			ws.mutex.Unlock()
+		})
+	}
+}
+
+// Acquire — метод захвата разрешения семафора с учетом ожидания
+func (ws *Waiters) Acquire(ctx context.Context, sem semaphore.Semaphore) error {
+	if sem == nil {
+		return errors.New("семафор не задан")
+	}
+	defer ws.Begin()()
+	return sem.AcquireContext(ctx)
+}
+
+// Oldest — метод получения возраста самого старого ожидания (0 — никто не ждет)
+// Подходит в качестве Probe
+func (ws *Waiters) Oldest() time.Duration {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	now := ws.clock.Now()
+	var oldest time.Duration
+	for _, start := range ws.started {
+		if age := now.Sub(start); age > oldest {
+			oldest = age
+		}
+	}
+	return oldest
+}
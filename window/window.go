@@ -0,0 +1,316 @@
+package window
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Event — событие с временем наступления
+type Event[T any] struct {
+	Time  time.Time
+	Value T
+}
+
+// Window — закрытое окно с накопленным значением
+type Window[A any] struct {
+	// Границы окна: [Start, End)
+	Start time.Time
+	End   time.Time
+	// Свертка значений событий окна
+	Value A
+	// Количество событий окна
+	Count int
+}
+
+// LatePolicy — политика обработки опоздавших событий
+// Событие опоздало, если все окна, в которые оно попадает, уже закрыты
+type LatePolicy int
+
+const (
+	// LateDrop — опоздавшие события отбрасываются и учитываются в Stats
+	LateDrop LatePolicy = iota
+	// LateSideOutput — опоздавшие события отправляются в канал Late
+	// (если он заполнен, событие отбрасывается)
+	LateSideOutput
+)
+
+// Stats — счетчики агрегатора
+type Stats struct {
+	// Принято событий хотя бы в одно окно
+	Accepted uint64
+	// Опоздавших событий (отправленных в Late и отброшенных)
+	Late uint64
+	// Отброшенных опоздавших событий
+	Dropped uint64
+	// Закрытых окон
+	Windows uint64
+}
+
+// Option — функция настройки агрегатора
+type Option func(*options)
+
+type options struct {
+	outOfOrder time.Duration
+	policy     LatePolicy
+	lateBuffer int
+	buffer     int
+}
+
+// WithOutOfOrder — опция допустимой неупорядоченности событий
+// Водяной знак отстает от максимального увиденного времени события на d:
+// окно закрывается, только когда водяной знак дошел до его конца, поэтому
+// события, опоздавшие не более чем на d, попадают в свои окна
+func WithOutOfOrder(d time.Duration) Option {
+	return func(o *options) { o.outOfOrder = d }
+}
+
+// WithLatePolicy — опция политики опоздавших событий
+// buffer — емкость канала Late для LateSideOutput
+func WithLatePolicy(policy LatePolicy, buffer int) Option {
+	return func(o *options) {
+		o.policy = policy
+		o.lateBuffer = buffer
+	}
+}
+
+// WithBuffer — опция емкости канала закрытых окон (по умолчанию 0)
+// Пока потребитель не забрал окно, Add и Advance, закрывающие следующие
+// окна, ждут — так медленный потребитель сдерживает производителей
+func WithBuffer(n int) Option {
+	return func(o *options) { o.buffer = n }
+}
+
+// Aggregator — потокобезопасный агрегатор событий по временным окнам
+// Окна строятся по времени событий (а не по времени поступления):
+// скользящие окна длиной size с шагом slide, выровненные по началу эпохи
+// Unix; при slide == size окна неперекрывающиеся. Add можно вызывать из
+// любого количества горутин; закрытые окна выдаются в канал Windows
+// по возрастанию начала
+type Aggregator[T, A any] struct {
+	size  time.Duration
+	slide time.Duration
+	fold  func(acc A, v T) A
+	opts  options
+
+	// Защита состояния окон
+	mutex sync.Mutex
+	// Открытые окна по началу
+	open map[int64]*Window[A]
+	// Водяной знак: окна с концом не позже него закрыты
+	watermark time.Time
+	// Максимальное время события
+	maxTime time.Time
+	closed  bool
+	stats   Stats
+
+	// Упорядочивает выдачу окон: берется до освобождения mutex
+	emitMutex sync.Mutex
+	windows   chan Window[A]
+	late      chan Event[T]
+}
+
+// NewTumbling — функция создания агрегатора по неперекрывающимся окнам длиной size
+func NewTumbling[T, A any](size time.Duration, fold func(acc A, v T) A, opts ...Option) (*Aggregator[T, A], error) {
+	return NewSliding(size, size, fold, opts...)
+}
+
+// NewSliding — функция создания агрегатора по скользящим окнам
+// size — длина окна, slide — шаг между началами окон (не больше size);
+// fold сворачивает значение события в накопленное значение окна,
+// начиная с нулевого значения A
+func NewSliding[T, A any](size, slide time.Duration, fold func(acc A, v T) A, opts ...Option) (*Aggregator[T, A], error) {
+	if size <= 0 || slide <= 0 || slide > size {
+		return nil, fmt.Errorf("некорректные параметры окна: длина %v, шаг %v", size, slide)
+	}
+	if fold == nil {
+		return nil, fmt.Errorf("функция свертки не задана")
+	}
+	a := &Aggregator[T, A]{
+		size:  size,
+		slide: slide,
+		fold:  fold,
+		open:  make(map[int64]*Window[A]),
+	}
+	for _, opt := range opts {
+		opt(&a.opts)
+	}
+	if a.opts.outOfOrder < 0 || a.opts.buffer < 0 || a.opts.lateBuffer < 0 {
+		return nil, fmt.Errorf("параметры агрегатора не могут быть отрицательными")
+	}
+	a.windows = make(chan Window[A], a.opts.buffer)
+	if a.opts.policy == LateSideOutput {
+		a.late = make(chan Event[T], a.opts.lateBuffer)
+	}
+	return a, nil
+}
+
+// Windows — метод получения канала закрытых окон
+// Канал закрывается после Close
+func (a *Aggregator[T, A]) Windows() <-chan Window[A] {
+	return a.windows
+}
+
+// Late — метод получения канала опоздавших событий
+// Возвращает nil, если политика не LateSideOutput. Канал закрывается после Close
+func (a *Aggregator[T, A]) Late() <-chan Event[T] {
+	return a.late
+}
+
+// Add — метод добавления события
+// Событие попадает во все открытые окна, которые его содержат, и сдвигает
+// водяной знак; окна, которые он прошел, закрываются. Событие, все окна
+// которого уже закрыты, обрабатывается согласно LatePolicy.
+// Возвращает ошибку после Close
+func (a *Aggregator[T, A]) Add(ev Event[T]) error {
+	a.mutex.Lock()
+	if a.closed {
+		a.mutex.Unlock()
+		return fmt.Errorf("агрегатор закрыт")
+	}
+
+	accepted := false
+	for start := a.firstStart(ev.Time); !start.After(ev.Time); start = start.Add(a.slide) {
+		end := start.Add(a.size)
+		if !end.After(a.watermark) {
+			// Окно уже закрыто
+			continue
+		}
+		w, ok := a.open[start.UnixNano()]
+		if !ok {
+			w = &Window[A]{Start: start, End: end}
+			a.open[start.UnixNano()] = w
+		}
+		w.Value = a.fold(w.Value, ev.Value)
+		w.Count++
+		accepted = true
+	}
+	if accepted {
+		a.stats.Accepted++
+	} else {
+		a.lateLocked(ev)
+	}
+
+	if ev.Time.After(a.maxTime) {
+		a.maxTime = ev.Time
+	}
+	a.emitLocked(a.maxTime.Add(-a.opts.outOfOrder))
+	return nil
+}
+
+// Advance — метод явного сдвига водяного знака до t
+// Нужен, когда события перестали поступать (простаивающий источник), а
+// окна пора закрыть. Водяной знак только растет: меньшее t игнорируется
+func (a *Aggregator[T, A]) Advance(t time.Time) {
+	a.mutex.Lock()
+	if a.closed {
+		a.mutex.Unlock()
+		return
+	}
+	a.emitLocked(t)
+}
+
+// Watermark — метод получения текущего водяного знака
+func (a *Aggregator[T, A]) Watermark() time.Time {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.watermark
+}
+
+// Stats — метод получения счетчиков агрегатора
+func (a *Aggregator[T, A]) Stats() Stats {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.stats
+}
+
+// Close — метод закрытия агрегатора
+// Все открытые окна выдаются, после чего каналы Windows и Late закрываются.
+// Повторные вызовы ничего не делают
+func (a *Aggregator[T, A]) Close() {
+	a.mutex.Lock()
+	if a.closed {
+		a.mutex.Unlock()
+		return
+	}
+	a.closed = true
+	ready := a.collectLocked(func(*Window[A]) bool { return true })
+	a.emitMutex.Lock()
+	a.mutex.Unlock()
+	defer a.emitMutex.Unlock()
+
+	for _, w := range ready {
+		a.windows <- w
+	}
+	close(a.windows)
+	if a.late != nil {
+		close(a.late)
+	}
+}
+
+// firstStart — метод получения начала самого раннего окна, содержащего t
+func (a *Aggregator[T, A]) firstStart(t time.Time) time.Time {
+	nanos := t.UnixNano()
+	last := nanos - mod(nanos, int64(a.slide))
+	// Окна с началом в (t-size, last] с шагом slide содержат t
+	first := last - (int64(a.size)-1)/int64(a.slide)*int64(a.slide)
+	return time.Unix(0, first)
+}
+
+// mod — функция неотрицательного остатка (для времени до эпохи)
+func mod(a, b int64) int64 {
+	m := a % b
+	if m < 0 {
+		m += b
+	}
+	return m
+}
+
+// lateLocked — метод обработки опоздавшего события
+func (a *Aggregator[T, A]) lateLocked(ev Event[T]) {
+	a.stats.Late++
+	if a.late != nil {
+		select {
+		case a.late <- ev:
+			return
+		default:
+		}
+	}
+	a.stats.Dropped++
+}
+
+// emitLocked — метод сдвига водяного знака и выдачи закрывшихся окон
+// Вызывается с захваченным mutex и освобождает его. emitMutex берется до
+// освобождения mutex, поэтому окна разных вызовов выдаются по порядку
+func (a *Aggregator[T, A]) emitLocked(watermark time.Time) {
+	if watermark.After(a.watermark) {
+		a.watermark = watermark
+	}
+	ready := a.collectLocked(func(w *Window[A]) bool { return !w.End.After(a.watermark) })
+	if len(ready) == 0 {
+		a.mutex.Unlock()
+		return
+	}
+	a.emitMutex.Lock()
+	a.mutex.Unlock()
+	defer a.emitMutex.Unlock()
+
+	for _, w := range ready {
+		a.windows <- w
+	}
+}
+
+// collectLocked — метод извлечения окон, удовлетворяющих done, по возрастанию начала
+func (a *Aggregator[T, A]) collectLocked(done func(*Window[A]) bool) []Window[A] {
+	var ready []Window[A]
+	for key, w := range a.open {
+		if done(w) {
+			ready = append(ready, *w)
+			delete(a.open, key)
+		}
+	}
+	sort.Slice(ready, func(i, j int) bool { return ready[i].Start.Before(ready[j].Start) })
+	a.stats.Windows += uint64(len(ready))
+	return ready
+}
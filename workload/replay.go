@@ -0,0 +1,42 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"goroutines-example/clock"
+)
+
+// Replay — функция воспроизведения записи нагрузки в реальном времени
+// submit вызывается для каждого события в момент его поступления,
+// пересчитанный со скоростью speed (2 — вдвое быстрее записи). Время
+// удержания в событии submit должен масштабировать сам (см. Scale).
+// Первая ошибка submit прерывает воспроизведение. Возвращается после
+// передачи всех событий, не дожидаясь их выполнения
+func Replay(ctx context.Context, events []Event, speed float64, c clock.Clock, submit func(e Event) error) error {
+	if speed <= 0 {
+		return fmt.Errorf("скорость воспроизведения должна быть положительной: %v", speed)
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	start := c.Now()
+	origin := events[0].At
+	for _, e := range events {
+		due := start.Add(Scale(e.At-origin, speed))
+		if err := clock.Sleep(ctx, c, due.Sub(c.Now())); err != nil {
+			return err
+		}
+		if err := submit(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Scale — функция пересчета длительности для скорости воспроизведения speed
+func Scale(d time.Duration, speed float64) time.Duration {
+	return time.Duration(float64(d) / speed)
+}
@@ -0,0 +1,139 @@
+package workload
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"goroutines-example/latency"
+)
+
+// Policy — порядок выбора ожидающих заявок при освобождении разрешения
+type Policy string
+
+const (
+	// FIFO — первым обслуживается ждущий дольше всех
+	FIFO Policy = "fifo"
+	// LIFO — первым обслуживается пришедший последним
+	LIFO Policy = "lifo"
+	// Random — ожидающий выбирается случайно (как при конкуренции горутин)
+	Random Policy = "random"
+)
+
+// SimConfig — параметры моделируемого семафора
+type SimConfig struct {
+	// Количество разрешений
+	Permits int
+	// Порядок обслуживания ожидающих (по умолчанию FIFO)
+	Policy Policy
+	// Время ожидания, после которого заявка уходит с таймаутом (0 — без ограничения)
+	MaxWait time.Duration
+	// Зерно генератора для политики Random
+	Seed int64
+}
+
+// SimResult — итог моделирования
+type SimResult struct {
+	Config SimConfig
+	// Обслуженные заявки и ушедшие по таймауту
+	Served   int
+	TimedOut int
+	// Распределение времени ожидания обслуженных заявок
+	Wait latency.Snapshot
+	// Время от первой заявки до освобождения последнего разрешения
+	Makespan time.Duration
+	// Доля времени, в течение которого разрешения были заняты
+	Utilization float64
+}
+
+// endHeap — куча моментов освобождения разрешений
+type endHeap []time.Duration
+
+func (h endHeap) Len() int            { return len(h) }
+func (h endHeap) Less(i, j int) bool  { return h[i] < h[j] }
+func (h endHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *endHeap) Push(x interface{}) { *h = append(*h, x.(time.Duration)) }
+func (h *endHeap) Pop() interface{} {
+	old := *h
+	v := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return v
+}
+
+// Simulate — функция моделирования нагрузки на семафоре с заданными параметрами
+// Моделирование дискретно-событийное и не зависит от реального времени,
+// поэтому многочасовая запись обрабатывается за доли секунды. events
+// должны быть отсортированы по At (как их возвращает ReadTrace)
+func Simulate(events []Event, cfg SimConfig) (SimResult, error) {
+	if cfg.Permits <= 0 {
+		return SimResult{}, fmt.Errorf("количество разрешений должно быть положительным: %d", cfg.Permits)
+	}
+	if cfg.Policy == "" {
+		cfg.Policy = FIFO
+	}
+	rnd := rand.New(rand.NewSource(cfg.Seed))
+	var pick func(n int) int
+	switch cfg.Policy {
+	case FIFO:
+		pick = func(int) int { return 0 }
+	case LIFO:
+		pick = func(n int) int { return n - 1 }
+	case Random:
+		pick = func(n int) int { return rnd.Intn(n) }
+	default:
+		return SimResult{}, fmt.Errorf("неизвестная политика: %q", cfg.Policy)
+	}
+
+	var (
+		result  = SimResult{Config: cfg}
+		waits   = latency.NewRecorder()
+		running endHeap
+		queue   []Event
+		busy    time.Duration
+		last    time.Duration
+	)
+	// dispatch — запуск ожидающих заявок на свободных разрешениях в момент now
+	dispatch := func(now time.Duration) {
+		for running.Len() < cfg.Permits && len(queue) > 0 {
+			i := pick(len(queue))
+			e := queue[i]
+			queue = append(queue[:i], queue[i+1:]...)
+			wait := now - e.At
+			if cfg.MaxWait > 0 && wait > cfg.MaxWait {
+				result.TimedOut++
+				continue
+			}
+			waits.Record(wait)
+			result.Served++
+			busy += e.Hold
+			heap.Push(&running, now+e.Hold)
+		}
+	}
+	// advance — освобождение разрешений, занятых до момента to
+	advance := func(to time.Duration) {
+		for running.Len() > 0 && running[0] <= to {
+			end := heap.Pop(&running).(time.Duration)
+			if end > last {
+				last = end
+			}
+			dispatch(end)
+		}
+	}
+
+	for _, e := range events {
+		advance(e.At)
+		queue = append(queue, e)
+		dispatch(e.At)
+	}
+	advance(time.Duration(1<<63 - 1))
+
+	if len(events) > 0 {
+		result.Makespan = last - events[0].At
+	}
+	if result.Makespan > 0 {
+		result.Utilization = float64(busy) / (float64(result.Makespan) * float64(cfg.Permits))
+	}
+	result.Wait = waits.Snapshot()
+	return result, nil
+}
@@ -0,0 +1,98 @@
+package workload
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Event — одна заявка записанной нагрузки
+type Event struct {
+	// Момент поступления относительно начала записи
+	At time.Duration `json:"at_ns"`
+	// Время удержания разрешения (выполнения задачи)
+	Hold time.Duration `json:"hold_ns"`
+	// Арендатор или очередь, в которую поступила заявка (может быть пустым)
+	Tenant string `json:"tenant,omitempty"`
+	// Стоимость задачи в планировщике (0 — не задана)
+	Cost int `json:"cost,omitempty"`
+	// Метки заявки (могут отсутствовать)
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// ReadTrace — функция чтения записи нагрузки в формате JSON Lines
+// Пустые строки пропускаются. События сортируются по моменту поступления
+func ReadTrace(r io.Reader) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("строка %d: %w", line, err)
+		}
+		if e.At < 0 || e.Hold < 0 {
+			return nil, fmt.Errorf("строка %d: отрицательное время", line)
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At < events[j].At })
+	return events, nil
+}
+
+// TraceWriter — запись нагрузки в формате JSON Lines
+// Безопасен для конкурентного использования
+type TraceWriter struct {
+	// Защита out и err
+	mutex sync.Mutex
+	out   *bufio.Writer
+	// Первая ошибка записи
+	err error
+}
+
+// NewTraceWriter — функция создания записи нагрузки в w
+func NewTraceWriter(w io.Writer) *TraceWriter {
+	return &TraceWriter{out: bufio.NewWriter(w)}
+}
+
+// Write — метод записи события
+// После первой ошибки события не записываются; ошибка доступна через Flush
+func (t *TraceWriter) Write(e Event) {
+	data, err := json.Marshal(e)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.err != nil {
+		return
+	}
+	if err != nil {
+		t.err = err
+		return
+	}
+	data = append(data, '\n')
+	if _, err := t.out.Write(data); err != nil {
+		t.err = err
+	}
+}
+
+// Flush — метод сброса буфера; возвращает первую ошибку записи
+func (t *TraceWriter) Flush() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.err != nil {
+		return t.err
+	}
+	return t.out.Flush()
+}
@@ -0,0 +1,37 @@
+package xsync
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+	xsemaphore "golang.org/x/sync/semaphore"
+
+	"goroutines-example/scope"
+	"goroutines-example/semaphore"
+)
+
+// Go — функция запуска fn в errgroup.Group под разрешением семафора
+// В отличие от Group.SetLimit, один семафор может ограничивать сразу
+// несколько групп. Ошибка захвата разрешения становится ошибкой группы
+func Go(g *errgroup.Group, cs *semaphore.CountingSemaphore, fn func() error) {
+	g.Go(func() error {
+		if err := cs.Acquire(); err != nil {
+			return err
+		}
+		defer cs.Release()
+		return fn()
+	})
+}
+
+// GoWeighted — функция запуска fn в области под разрешением Weighted
+// Позволяет переводить код с errgroup на scope, сохраняя уже существующий
+// общий Weighted. Ожидание разрешения прерывается отменой контекста области
+func GoWeighted(s *scope.Scope, w *xsemaphore.Weighted, fn func(ctx context.Context) error) {
+	s.Go(func(ctx context.Context) error {
+		if err := w.Acquire(ctx, 1); err != nil {
+			return err
+		}
+		defer w.Release(1)
+		return fn(ctx)
+	})
+}
@@ -0,0 +1,150 @@
+package xsync
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	xsemaphore "golang.org/x/sync/semaphore"
+
+	"goroutines-example/semaphore"
+)
+
+// Weighted — представление CountingSemaphore с API golang.org/x/sync/semaphore.Weighted
+// Позволяет передать наш семафор в код, написанный под *semaphore.Weighted,
+// меняя только тип зависимости, а не логику вызовов
+type Weighted struct {
+	cs *semaphore.CountingSemaphore
+}
+
+// ToWeighted — функция получения представления семафора с API Weighted
+func ToWeighted(cs *semaphore.CountingSemaphore) *Weighted {
+	return &Weighted{cs: cs}
+}
+
+// Acquire — метод захвата n разрешений с ожиданием до отмены ctx
+// Как и у Weighted, таймаут семафора не прерывает ожидание: запрос один
+// раз встает в очередь и ждет, пока не будет отменен ctx. Запрос больше
+// максимума ждет отмены ctx, так как никогда не может быть удовлетворен
+func (w *Weighted) Acquire(ctx context.Context, n int64) error {
+	if n > int64(w.cs.MaxPermits()) {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return w.cs.AcquireNUntil(ctx, int(n))
+}
+
+// TryAcquire — метод захвата n разрешений без ожидания
+func (w *Weighted) TryAcquire(n int64) bool {
+	return w.cs.TryAcquireN(int(n))
+}
+
+// Release — метод освобождения n разрешений
+// Как и у Weighted, освобождение больше захваченного приводит к панике
+func (w *Weighted) Release(n int64) {
+	if err := w.cs.ReleaseN(int(n)); err != nil {
+		panic(err)
+	}
+}
+
+// Counting — представление golang.org/x/sync/semaphore.Weighted с API CountingSemaphore
+// Weighted не сообщает количество свободных разрешений, поэтому адаптер
+// сам учитывает захваченные через него разрешения. Захваты в обход адаптера
+// не видны в AvailablePermits
+type Counting struct {
+	w *xsemaphore.Weighted
+	// Размер, с которым был создан Weighted
+	size int64
+	// Таймаут захвата
+	timeout time.Duration
+	// Количество разрешений, захваченных через адаптер
+	held atomic.Int64
+}
+
+var _ semaphore.Semaphore = (*Counting)(nil)
+
+// FromWeighted — функция получения представления Weighted с API CountingSemaphore
+// size — размер, с которым был создан w (Weighted его не сообщает)
+func FromWeighted(w *xsemaphore.Weighted, size int64, timeout time.Duration) *Counting {
+	return &Counting{w: w, size: size, timeout: timeout}
+}
+
+// Acquire — метод захвата одного разрешения с таймаутом
+func (c *Counting) Acquire() error {
+	return c.AcquireN(1)
+}
+
+// AcquireContext — метод захвата одного разрешения с отменой через ctx
+func (c *Counting) AcquireContext(ctx context.Context) error {
+	return c.AcquireNContext(ctx, 1)
+}
+
+// TryAcquire — метод попытки захвата одного разрешения без блокировки
+func (c *Counting) TryAcquire() bool {
+	return c.TryAcquireN(1)
+}
+
+// Release — метод освобождения одного разрешения
+func (c *Counting) Release() error {
+	return c.ReleaseN(1)
+}
+
+// AcquireN — метод захвата n разрешений с таймаутом
+func (c *Counting) AcquireN(n int) error {
+	return c.AcquireNContext(context.Background(), n)
+}
+
+// AcquireNContext — метод захвата n разрешений с таймаутом и отменой через ctx
+func (c *Counting) AcquireNContext(ctx context.Context, n int) error {
+	if int64(n) > c.size {
+		return fmt.Errorf("запрошено больше разрешений (%d), чем максимально доступно (%d)", n, c.size)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	if err := c.w.Acquire(timeoutCtx, int64(n)); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return fmt.Errorf("не удалось захватить %d разрешений у семафора за %v", n, c.timeout)
+	}
+	c.held.Add(int64(n))
+	return nil
+}
+
+// TryAcquireN — метод попытки захвата n разрешений без блокировки
+func (c *Counting) TryAcquireN(n int) bool {
+	if !c.w.TryAcquire(int64(n)) {
+		return false
+	}
+	c.held.Add(int64(n))
+	return true
+}
+
+// ReleaseN — метод освобождения n разрешений
+// В отличие от Weighted, освобождение больше захваченного через адаптер
+// возвращает ошибку, а не паникует
+func (c *Counting) ReleaseN(n int) error {
+	for {
+		held := c.held.Load()
+		if int64(n) > held {
+			return fmt.Errorf("попытка освободить больше разрешений (%d), чем захвачено (%d)", n, held)
+		}
+		if c.held.CompareAndSwap(held, held-int64(n)) {
+			break
+		}
+	}
+	c.w.Release(int64(n))
+	return nil
+}
+
+// AvailablePermits — метод получения количества свободных разрешений
+func (c *Counting) AvailablePermits() int {
+	return int(c.size - c.held.Load())
+}
+
+// MaxPermits — метод получения максимального количества разрешений
+func (c *Counting) MaxPermits() int {
+	return int(c.size)
+}